@@ -0,0 +1,16 @@
+package adminApiStructs
+
+import (
+	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
+	"time"
+)
+
+type AuditLogGetListReq struct {
+	commonApiStructs.RequestPage
+	UserId     uint       `json:"userId"`
+	Action     string     `json:"action"`
+	TargetType string     `json:"targetType"`
+	TargetId   string     `json:"targetId"`
+	StartTime  *time.Time `json:"startTime"`
+	EndTime    *time.Time `json:"endTime"`
+}
@@ -0,0 +1,9 @@
+package adminApiStructs
+
+type NetworkZoneEditReq struct {
+	Id       uint   `json:"id"` // 为0时新建
+	Name     string `json:"name" validate:"required,max=50"`
+	Cidrs    string `json:"cidrs" validate:"required,max=2000"` // IP或CIDR，逗号分隔
+	Internal bool   `json:"internal"`
+	Sort     int    `json:"sort"`
+}
@@ -0,0 +1,16 @@
+package adminApiStructs
+
+import "sun-panel/models"
+
+type RoleInfo struct {
+	models.Role
+	Permissions []string `json:"permissions"`
+	UserCount   int64    `json:"userCount"` // 使用该角色的用户数
+}
+
+type RoleEditReq struct {
+	Id          uint     `json:"id"` // 为0时新建
+	Name        string   `json:"name" validate:"required,max=50"`
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions"`
+}
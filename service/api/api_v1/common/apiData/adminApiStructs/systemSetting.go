@@ -0,0 +1,8 @@
+package adminApiStructs
+
+type SystemSettingEmailReq struct {
+	Host     string `json:"host" validate:"required"`
+	Port     int    `json:"port" validate:"required,min=1,max=65535"`
+	Mail     string `json:"mail" validate:"required,email"`
+	Password string `json:"password"` // 为空时保留原密码
+}
@@ -0,0 +1,44 @@
+package adminApiStructs
+
+import "sun-panel/models"
+
+type TeamInfo struct {
+	models.Team
+	MemberCount int64 `json:"memberCount"`
+}
+
+type TeamEditReq struct {
+	Id          uint   `json:"id"` // 为0时新建
+	Name        string `json:"name" validate:"required,max=50"`
+	Description string `json:"description" validate:"max=255"`
+}
+
+type TeamMemberInfo struct {
+	UserId   uint   `json:"userId"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Role     int    `json:"role"`
+}
+
+type TeamGetMemberListReq struct {
+	TeamId uint `json:"teamId" validate:"required"`
+}
+
+// 添加成员或修改成员角色
+type TeamSetMemberReq struct {
+	TeamId  uint   `json:"teamId" validate:"required"`
+	UserIds []uint `json:"userIds" validate:"required"`
+	Role    int    `json:"role"` // 1.查看者 2.编辑者
+}
+
+type TeamRemoveMembersReq struct {
+	TeamId  uint   `json:"teamId" validate:"required"`
+	UserIds []uint `json:"userIds" validate:"required"`
+}
+
+// 当前用户加入的团队
+type TeamMyInfo struct {
+	Id   uint   `json:"id"`
+	Name string `json:"name"`
+	Role int    `json:"role"`
+}
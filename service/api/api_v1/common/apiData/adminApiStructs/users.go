@@ -0,0 +1,23 @@
+package adminApiStructs
+
+import "time"
+
+type UsersSetStatusReq struct {
+	UserIds []uint `json:"userIds" validate:"required"`
+	Status  int    `json:"status" validate:"oneof=1 2"` // 1.启用 2.停用
+}
+
+type UsersSetExpireAtReq struct {
+	UserIds  []uint     `json:"userIds" validate:"required"`
+	ExpireAt *time.Time `json:"expireAt"` // 为空永不过期
+}
+
+type UsersSetMustChangePasswordReq struct {
+	UserIds            []uint `json:"userIds" validate:"required"`
+	MustChangePassword bool   `json:"mustChangePassword"`
+}
+
+type UsersImpersonateReq struct {
+	UserId  uint `json:"userId" validate:"required"`
+	Minutes int  `json:"minutes" validate:"min=0,max=120"` // 有效时长(分钟)，为0时使用默认值
+}
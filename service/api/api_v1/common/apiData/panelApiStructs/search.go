@@ -0,0 +1,6 @@
+package panelApiStructs
+
+type SearchQueryReq struct {
+	Keyword string `json:"keyword" validate:"required,max=100"`
+	Limit   int    `json:"limit" validate:"min=0,max=100"` // 0为默认数量
+}
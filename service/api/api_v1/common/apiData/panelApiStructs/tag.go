@@ -0,0 +1,21 @@
+package panelApiStructs
+
+type TagEditReq struct {
+	Id    uint   `json:"id"` // 为0时新建
+	Name  string `json:"name" validate:"required,max=50"`
+	Color string `json:"color" validate:"omitempty,hexcolor"`
+	Sort  int    `json:"sort"`
+}
+
+type TagViewEditReq struct {
+	Id       uint   `json:"id"` // 为0时新建
+	Icon     string `json:"icon" validate:"max=255"`
+	Title    string `json:"title" validate:"required,max=50"`
+	TagIds   []uint `json:"tagIds" validate:"required,min=1,max=20"`
+	MatchAll bool   `json:"matchAll"`
+	Sort     int    `json:"sort"`
+}
+
+type TagViewGetItemsReq struct {
+	Id uint `json:"id" validate:"required"`
+}
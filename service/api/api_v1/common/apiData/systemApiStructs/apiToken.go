@@ -0,0 +1,25 @@
+package systemApiStructs
+
+import "time"
+
+type ApiTokenCreateReq struct {
+	Name      string     `json:"name" validate:"required,max=50"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expiresAt"` // 过期时间，为空永不过期
+}
+
+type ApiTokenInfo struct {
+	Id          uint       `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"tokenPrefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	LastUsedAt  *time.Time `json:"lastUsedAt"`
+	LastUsedIp  string     `json:"lastUsedIp"`
+	CreateTime  time.Time  `json:"createTime"`
+}
+
+type ApiTokenCreateResp struct {
+	ApiTokenInfo
+	Token string `json:"token"` // 明文令牌，只在创建时返回一次
+}
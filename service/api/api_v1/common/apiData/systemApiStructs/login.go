@@ -0,0 +1,23 @@
+package systemApiStructs
+
+import "sun-panel/api/api_v1/common/apiData/commonApiStructs"
+
+type LoginSendResetPasswordVCodeReq struct {
+	Email        string                               `json:"email" validate:"required,max=50"` // 账号或邮箱
+	Verification commonApiStructs.VerificationRequest `json:"verification"`                     // 图形验证码
+}
+
+type LoginResetPasswordByVCodeReq struct {
+	Email      string `json:"email" validate:"required,max=50"` // 账号或邮箱
+	Password   string `json:"password" validate:"required,min=6,max=50"`
+	EmailVCode string `json:"emailVCode" validate:"required,max=10"`
+}
+
+type LoginSendMagicLinkReq struct {
+	Email        string                               `json:"email" validate:"required,email,max=50"`
+	Verification commonApiStructs.VerificationRequest `json:"verification"` // 图形验证码
+}
+
+type LoginMagicLinkReq struct {
+	Token string `json:"token" validate:"required,max=200"`
+}
@@ -3,3 +3,13 @@ package systemApiStructs
 type NoticeGetListByDisplayTypeReq struct {
 	DisplayType []int `json:"displayType"`
 }
+
+type NoticeEditReq struct {
+	Id          uint   `json:"id"` // 为0时新建
+	Title       string `json:"title" validate:"required,max=255"`
+	Content     string `json:"content" validate:"max=2000"`
+	DisplayType int    `json:"displayType" validate:"oneof=1 2"`
+	OneRead     int    `json:"oneRead" validate:"oneof=0 1"`
+	Url         string `json:"url" validate:"max=255"`
+	IsLogin     uint   `json:"isLogin" validate:"oneof=0 1"`
+}
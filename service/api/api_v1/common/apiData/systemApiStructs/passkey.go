@@ -0,0 +1,42 @@
+package systemApiStructs
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type PasskeyInfo struct {
+	Id             uint       `json:"id"`
+	Name           string     `json:"name"`
+	BackupEligible bool       `json:"backupEligible"` // 是否可以同步到其他设备
+	BackupState    bool       `json:"backupState"`    // 是否已同步
+	LastUsedAt     *time.Time `json:"lastUsedAt"`
+	CreateTime     time.Time  `json:"createTime"`
+}
+
+// 开始注册或登录，options 传给 navigator.credentials.create/get
+type PasskeyBeginResp struct {
+	Challenge string      `json:"challenge"`
+	Options   interface{} `json:"options"`
+}
+
+type PasskeyRegisterFinishReq struct {
+	Challenge  string          `json:"challenge" validate:"required"`
+	Name       string          `json:"name" validate:"max=50"`
+	Credential json.RawMessage `json:"credential" validate:"required"` // navigator.credentials.create 的结果
+}
+
+type PasskeyRenameReq struct {
+	Id   uint   `json:"id" validate:"required"`
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+// 账号为空时使用可发现凭据登录(无需输入账号)
+type PasskeyLoginBeginReq struct {
+	Username string `json:"username" validate:"max=50"`
+}
+
+type PasskeyLoginFinishReq struct {
+	Challenge  string          `json:"challenge" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required"` // navigator.credentials.get 的结果
+}
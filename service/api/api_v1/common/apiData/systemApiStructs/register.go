@@ -0,0 +1,20 @@
+package systemApiStructs
+
+import "sun-panel/api/api_v1/common/apiData/commonApiStructs"
+
+type RegisterSendVcodeReq struct {
+	Email        string                               `json:"email" validate:"required,email,max=50"`
+	Verification commonApiStructs.VerificationRequest `json:"verification"` // 图形验证码
+}
+
+type RegisterCommitReq struct {
+	Email      string `json:"email" validate:"required,email,max=50"`
+	Username   string `json:"username" validate:"required,min=5,max=50"`
+	Password   string `json:"password" validate:"required,min=6,max=50"`
+	EmailVCode string `json:"emailVCode" validate:"required,max=10"`
+}
+
+type RegisterCommitResp struct {
+	UserId          uint `json:"userId"`
+	RequireApproval bool `json:"requireApproval"` // 需要等待管理员审核后才能登录
+}
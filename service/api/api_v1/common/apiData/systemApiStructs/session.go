@@ -0,0 +1,21 @@
+package systemApiStructs
+
+import "time"
+
+type SessionInfo struct {
+	Id         uint      `json:"id"`
+	Device     string    `json:"device"`
+	Ip         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	CreateTime time.Time `json:"createTime"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current"` // 是否为当前会话
+
+	ImpersonatedBy uint       `json:"impersonatedBy"` // 模拟登录的管理员ID，0为正常登录
+	ExpiresAt      *time.Time `json:"expiresAt"`
+}
+
+type SessionAdminRevokeReq struct {
+	Ids     []uint `json:"ids"`     // 会话ID
+	UserIds []uint `json:"userIds"` // 用户ID，吊销其全部会话
+}
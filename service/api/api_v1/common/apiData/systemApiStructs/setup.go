@@ -0,0 +1,13 @@
+package systemApiStructs
+
+import "sun-panel/lib/cmn/systemSetting"
+
+type SetupCommitReq struct {
+	SetupToken string               `json:"setupToken" validate:"required"` // 启动日志中输出的设置令牌
+	Username   string               `json:"username" validate:"required,min=5,max=50"`
+	Password   string               `json:"password" validate:"required,min=6,max=50"`
+	Name       string               `json:"name" validate:"max=20"`
+	Language   string               `json:"language"`
+	WebSiteUrl string               `json:"webSiteUrl" validate:"omitempty,url"`
+	Email      *systemSetting.Email `json:"email"` // 可选，为空不配置邮箱
+}
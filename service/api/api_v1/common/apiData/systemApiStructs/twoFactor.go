@@ -0,0 +1,22 @@
+package systemApiStructs
+
+type TwoFactorSetupResp struct {
+	Secret string `json:"secret"` // 密钥，用于手动输入
+	Uri    string `json:"uri"`    // otpauth:// 地址
+	QrCode string `json:"qrCode"` // 二维码图片 base64
+}
+
+type TwoFactorCodeReq struct {
+	Code string `json:"code" validate:"required,max=20"`
+}
+
+type TwoFactorDisableReq struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,max=20"`
+}
+
+type TwoFactorStatusResp struct {
+	Enabled            bool `json:"enabled"`
+	RecoveryCodesCount int  `json:"recoveryCodesCount"` // 剩余恢复码数量
+	Required           bool `json:"required"`           // 当前账号是否被要求启用
+}
@@ -5,11 +5,71 @@ var ErrorCodeMap = map[int]string{
 	// 100: "operation failed",
 
 	1000: "Not logged in yet",                   // 还未登录
+	1001: "Login has expired",                   // 登录已过期或token无效
 	1003: "Incorrect username or password",      // 用户名或密码错误
 	1004: "Account disabled or not activated",   // 账号已停用或未激活
 	1005: "No current permission for operation", // 当前无权限操作
 	1006: "Account does not exist",              // 账号不存在
 	1007: "Old password error",                  // 旧密码不正确
+	1008: "Account has expired",                 // 账号已过期
+	1009: "Password change required",            // 需要先修改密码
+
+	// 二次验证
+	1010: "Two-factor authentication required",                         // 需要二次验证
+	1011: "Two-factor authentication must be enabled for this account", // 该账号必须启用二次验证
+	1012: "Invalid two-factor authentication code",                     // 二次验证码错误
+	1013: "Login has expired, please log in again",                     // 登录挑战已过期
+	1014: "Two-factor authentication is already enabled",               // 已启用二次验证
+	1015: "Two-factor authentication is not enabled",                   // 未启用二次验证
+
+	// 登录限制
+	1020: "Too many login attempts, please try again later",    // 尝试过于频繁，需要等待
+	1021: "Too many failed login attempts, temporarily locked", // 失败次数过多，已临时锁定
+
+	// 注册和邮箱验证码
+	1030: "Registration is not open",                                           // 未开放注册
+	1031: "This email domain is not allowed to register",                       // 邮箱后缀不在允许范围内
+	1032: "The email has already been registered",                              // 邮箱已被注册
+	1033: "The username has already been registered",                           // 账号已被注册
+	1034: "Invalid or expired email verification code",                         // 邮箱验证码错误或已过期
+	1035: "Verification code requested too frequently, please try again later", // 验证码发送过于频繁
+	1036: "Email service is not configured",                                    // 未配置系统邮箱
+	1037: "Failed to send email",                                               // 邮件发送失败
+
+	// 邮件登录
+	1040: "Email login is not enabled",    // 未开启邮件登录或未设置站点地址
+	1041: "Invalid or expired login link", // 登录链接无效、已使用或已过期
+
+	// 角色
+	1050: "Role does not exist",                          // 角色不存在
+	1051: "The administrator role cannot be modified",    // 管理员角色不能修改
+	1052: "Built-in roles cannot be deleted",             // 内置角色不能删除
+	1053: "The role is in use and cannot be deleted",     // 角色使用中
+	1054: "You cannot grant permissions you do not have", // 不能授予自己没有的权限
+
+	// 团队
+	1060: "Team does not exist",      // 团队不存在
+	1061: "Invalid team member role", // 团队成员角色错误
+
+	// 首次运行设置
+	1070: "Initial setup required",           // 需要完成首次设置
+	1071: "Invalid setup token",              // 设置令牌错误
+	1072: "Setup has already been completed", // 已完成首次设置
+
+	// 通行密钥
+	1080: "Passkey is not available, please set the website URL first", // 未设置站点地址
+	1081: "Passkey verification failed",                                // 通行密钥验证失败
+	1082: "No passkey is available for this account",                   // 账号没有可用的通行密钥
+	1083: "The number of passkeys has reached the limit",               // 通行密钥数量已达上限
+	1084: "The passkey has already been registered",                    // 通行密钥已注册
+
+	// 模拟登录
+	1090: "Read-only while viewing as another user", // 模拟登录时只读
+	1091: "You cannot view as yourself",             // 不能模拟登录自己
+
+	// 验证器类
+	1101: "Verification required",                         // 需要图形验证码
+	1102: "Incorrect verification code, please try again", // 图形验证码错误
 
 	// 数据类
 	1200: "Database error",           // 数据库错误
@@ -1,6 +1,7 @@
 package apiReturn
 
 import (
+	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
 	"sun-panel/global"
 
 	"github.com/gin-gonic/gin"
@@ -58,23 +59,17 @@ func ListData(ctx *gin.Context, list interface{}, count int64) {
 	ApiReturn(ctx, 0, "OK", data)
 }
 
-// 返回错误 验证码相关错误错误
-// func ErrorVerification(ctx *gin.Context, errCode int, codeID string) {
-// 	msg := ""
-// 	switch errCode {
-// 	case ERROR_CODE_VERIFICATION_FAIL:
-// 		msg = "验证失败，请重新验证"
-// 	case ERROR_CODE_VERIFICATION_MUST:
-// 		msg = "需要进一步验证"
-// 	}
-// 	ApiReturn(ctx, errCode, msg, gin.H{
-// 		"verification": commonApiStructs.VerificationResponse{
-// 			CodeID:  codeID,
-// 			Result:  false,
-// 			Message: msg,
-// 		},
-// 	})
-// }
+// 返回错误 验证码相关错误，codeID不为空时前端使用该ID获取验证码并提交
+func ErrorVerification(ctx *gin.Context, errCode int, codeID string) {
+	msg, _ := GetErrorMsgByCode(errCode)
+	ApiReturn(ctx, errCode, msg, gin.H{
+		"verification": commonApiStructs.VerificationResponse{
+			CodeID:  codeID,
+			Result:  false,
+			Message: msg,
+		},
+	})
+}
 
 // 返回错误 需要个性化定义的错误|带返回数据的错误
 func ErrorCode(ctx *gin.Context, code int, errMsg string, data interface{}) {
@@ -7,6 +7,7 @@ import (
 	"sun-panel/api/api_v1/common/apiReturn"
 	"sun-panel/lib/captcha"
 	"sun-panel/lib/cmn"
+	"sun-panel/lib/permission"
 	"sun-panel/models"
 
 	"github.com/gin-gonic/gin"
@@ -27,7 +28,9 @@ const (
 )
 
 const (
-	GIN_GET_VISIT_MODE = "VISIT_MODE"
+	GIN_GET_VISIT_MODE      = "VISIT_MODE"
+	GIN_GET_SESSION         = "SESSION"
+	GIN_GET_IMPERSONATED_BY = "IMPERSONATED_BY" // 模拟登录的管理员ID
 )
 
 // 验证输入是否有效并返回错误
@@ -88,6 +91,16 @@ func GetCurrentUserInfo(c *gin.Context) (userInfo models.User, exist bool) {
 	return
 }
 
+// 获取当前登录会话，使用访问令牌或反向代理认证时不存在
+func GetCurrentSession(c *gin.Context) (session models.UserSession, exist bool) {
+	if value, exist := c.Get(GIN_GET_SESSION); exist {
+		if v, ok := value.(models.UserSession); ok {
+			return v, exist
+		}
+	}
+	return
+}
+
 // 获取当前访问模式
 func GetCurrentVisitMode(c *gin.Context) (visitMode int) {
 	if value, exist := c.Get(GIN_GET_VISIT_MODE); exist {
@@ -98,6 +111,40 @@ func GetCurrentVisitMode(c *gin.Context) (visitMode int) {
 	return
 }
 
+// 获取发起模拟登录的管理员ID，非模拟登录时返回0
+func GetImpersonatedBy(c *gin.Context) uint {
+	if value, exist := c.Get(GIN_GET_IMPERSONATED_BY); exist {
+		if v, ok := value.(uint); ok {
+			return v
+		}
+	}
+	return 0
+}
+
+// 账号不可用时的错误码，可用时返回0
+func UserStatusErrorCode(userInfo models.User) int {
+	if userInfo.Status != models.USER_STATUS_ENABLE {
+		return 1004 // 停用或未激活
+	}
+	if userInfo.IsExpired() {
+		return 1008
+	}
+	return 0
+}
+
+// 验证当前用户可以管理这些用户，任一用户的角色超过自己的权限时拒绝整个请求
+func CheckCanManageUsers(c *gin.Context, userIds []uint) bool {
+	currentUser, _ := GetCurrentUserInfo(c)
+	if ok, err := permission.CanManageUsers(currentUser.Role, userIds); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return false
+	} else if !ok {
+		apiReturn.ErrorByCode(c, 1054)
+		return false
+	}
+	return true
+}
+
 // 验证器验证
 func VerificationCheck(verificationId, vCode string) (errCode int, verificationIdRes string) {
 
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"strings"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 个人访问令牌可以访问的接口及需要的权限范围，未列出的接口(修改密码、管理令牌等)令牌无法访问
+var apiTokenRouteScopes = map[string]string{
+	"user/getInfo":                      models.API_TOKEN_SCOPE_READ_PANEL,
+	"user/getAuthInfo":                  models.API_TOKEN_SCOPE_READ_PANEL,
+	"panel/itemIconGroup/getList":       models.API_TOKEN_SCOPE_READ_PANEL,
+	"panel/itemIcon/getListByGroupId":   models.API_TOKEN_SCOPE_READ_PANEL,
+	"panel/userConfig/get":              models.API_TOKEN_SCOPE_READ_PANEL,
+	"system/moduleConfig/getByName":     models.API_TOKEN_SCOPE_READ_PANEL,
+	"system/monitor/getAll":             models.API_TOKEN_SCOPE_READ_PANEL,
+	"system/monitor/getCpuState":        models.API_TOKEN_SCOPE_READ_PANEL,
+	"system/monitor/getDiskStateByPath": models.API_TOKEN_SCOPE_READ_PANEL,
+	"system/monitor/getMemonyState":     models.API_TOKEN_SCOPE_READ_PANEL,
+	"system/monitor/getDiskMountpoints": models.API_TOKEN_SCOPE_READ_PANEL,
+	"panel/team/getMyList":              models.API_TOKEN_SCOPE_READ_PANEL,
+	"panel/networkZone/getList":         models.API_TOKEN_SCOPE_READ_PANEL,
+	"panel/networkZone/getCurrent":      models.API_TOKEN_SCOPE_READ_PANEL,
+	"panel/search/query":                models.API_TOKEN_SCOPE_READ_PANEL,
+	"panel/tag/getList":                 models.API_TOKEN_SCOPE_READ_PANEL,
+	"panel/tagView/getList":             models.API_TOKEN_SCOPE_READ_PANEL,
+	"panel/tagView/getItems":            models.API_TOKEN_SCOPE_READ_PANEL,
+
+	"panel/itemIcon/edit":           models.API_TOKEN_SCOPE_WRITE_ITEMS,
+	"panel/itemIcon/deletes":        models.API_TOKEN_SCOPE_WRITE_ITEMS,
+	"panel/itemIcon/saveSort":       models.API_TOKEN_SCOPE_WRITE_ITEMS,
+	"panel/itemIcon/addMultiple":    models.API_TOKEN_SCOPE_WRITE_ITEMS,
+	"panel/itemIcon/getSiteFavicon": models.API_TOKEN_SCOPE_WRITE_ITEMS,
+	"panel/itemIconGroup/edit":      models.API_TOKEN_SCOPE_WRITE_ITEMS,
+	"panel/itemIconGroup/deletes":   models.API_TOKEN_SCOPE_WRITE_ITEMS,
+	"panel/itemIconGroup/saveSort":  models.API_TOKEN_SCOPE_WRITE_ITEMS,
+	"panel/tag/edit":                models.API_TOKEN_SCOPE_WRITE_ITEMS,
+	"panel/tag/deletes":             models.API_TOKEN_SCOPE_WRITE_ITEMS,
+	"panel/tagView/edit":            models.API_TOKEN_SCOPE_WRITE_ITEMS,
+	"panel/tagView/deletes":         models.API_TOKEN_SCOPE_WRITE_ITEMS,
+
+	"file/uploadImg":   models.API_TOKEN_SCOPE_UPLOAD_FILES,
+	"file/uploadFiles": models.API_TOKEN_SCOPE_UPLOAD_FILES,
+	"file/getList":     models.API_TOKEN_SCOPE_UPLOAD_FILES,
+	"file/deletes":     models.API_TOKEN_SCOPE_UPLOAD_FILES,
+	"file/rename":      models.API_TOKEN_SCOPE_UPLOAD_FILES,
+	"file/refresh":     models.API_TOKEN_SCOPE_UPLOAD_FILES,
+
+	"panel/users/create":                models.API_TOKEN_SCOPE_ADMIN,
+	"panel/users/update":                models.API_TOKEN_SCOPE_ADMIN,
+	"panel/users/getList":               models.API_TOKEN_SCOPE_ADMIN,
+	"panel/users/deletes":               models.API_TOKEN_SCOPE_ADMIN,
+	"panel/users/getPublicVisitUser":    models.API_TOKEN_SCOPE_ADMIN,
+	"panel/users/setPublicVisitUser":    models.API_TOKEN_SCOPE_ADMIN,
+	"panel/users/resetTwoFactor":        models.API_TOKEN_SCOPE_ADMIN,
+	"panel/users/getSessionList":        models.API_TOKEN_SCOPE_ADMIN,
+	"panel/users/revokeSessions":        models.API_TOKEN_SCOPE_ADMIN,
+	"panel/users/approve":               models.API_TOKEN_SCOPE_ADMIN,
+	"panel/users/setStatus":             models.API_TOKEN_SCOPE_ADMIN,
+	"panel/users/setExpireAt":           models.API_TOKEN_SCOPE_ADMIN,
+	"panel/users/setMustChangePassword": models.API_TOKEN_SCOPE_ADMIN,
+
+	"panel/loginLimit/getSetting":    models.API_TOKEN_SCOPE_ADMIN,
+	"panel/loginLimit/setSetting":    models.API_TOKEN_SCOPE_ADMIN,
+	"panel/loginLimit/getLockedList": models.API_TOKEN_SCOPE_ADMIN,
+	"panel/loginLimit/unlock":        models.API_TOKEN_SCOPE_ADMIN,
+
+	"panel/role/getPermissionList": models.API_TOKEN_SCOPE_ADMIN,
+	"panel/role/getList":           models.API_TOKEN_SCOPE_ADMIN,
+	"panel/role/edit":              models.API_TOKEN_SCOPE_ADMIN,
+	"panel/role/deletes":           models.API_TOKEN_SCOPE_ADMIN,
+
+	"panel/team/getList":       models.API_TOKEN_SCOPE_ADMIN,
+	"panel/team/edit":          models.API_TOKEN_SCOPE_ADMIN,
+	"panel/team/deletes":       models.API_TOKEN_SCOPE_ADMIN,
+	"panel/team/getMemberList": models.API_TOKEN_SCOPE_ADMIN,
+	"panel/team/setMember":     models.API_TOKEN_SCOPE_ADMIN,
+	"panel/team/removeMembers": models.API_TOKEN_SCOPE_ADMIN,
+
+	"panel/auditLog/getList":    models.API_TOKEN_SCOPE_ADMIN,
+	"panel/auditLog/getSetting": models.API_TOKEN_SCOPE_ADMIN,
+	"panel/auditLog/setSetting": models.API_TOKEN_SCOPE_ADMIN,
+
+	"panel/systemSetting/getEmail":              models.API_TOKEN_SCOPE_ADMIN,
+	"panel/systemSetting/setEmail":              models.API_TOKEN_SCOPE_ADMIN,
+	"panel/systemSetting/getApplicationSetting": models.API_TOKEN_SCOPE_ADMIN,
+	"panel/systemSetting/setApplicationSetting": models.API_TOKEN_SCOPE_ADMIN,
+
+	"panel/networkZone/edit":    models.API_TOKEN_SCOPE_ADMIN,
+	"panel/networkZone/deletes": models.API_TOKEN_SCOPE_ADMIN,
+}
+
+// 个人访问令牌认证：Authorization: Bearer <token>
+// ok:认证成功 errCode:令牌无效或权限不足时的错误码，均为零值表示请求没有携带令牌
+func apiTokenUser(c *gin.Context) (userInfo models.User, ok bool, errCode int) {
+	authorization := c.GetHeader("Authorization")
+	if len(authorization) < 7 || !strings.EqualFold(authorization[:7], "Bearer ") {
+		return
+	}
+	token := strings.TrimSpace(authorization[7:])
+
+	mApiToken := models.UserApiToken{}
+	apiToken, err := mApiToken.GetByToken(global.Db, token)
+	if err == models.ErrApiTokenInvalid || err == models.ErrApiTokenExpired {
+		return userInfo, false, 1001
+	} else if err != nil {
+		global.Logger.Errorln("api token:", err.Error())
+		return userInfo, false, 1200
+	}
+
+	scope, exist := apiTokenRouteScopes[strings.TrimPrefix(c.FullPath(), "/api/")]
+	if !exist || !apiToken.HasScope(scope) {
+		return userInfo, false, 1005
+	}
+
+	mUser := models.User{}
+	if userInfo, err = mUser.GetUserInfoByUid(apiToken.UserId); err != nil {
+		return userInfo, false, 1001
+	}
+	// 停用、未激活或已过期
+	if errCode := base.UserStatusErrorCode(userInfo); errCode != 0 {
+		return userInfo, false, errCode
+	}
+
+	if err := apiToken.UpdateLastUsed(global.Db, c.ClientIP()); err != nil {
+		global.Logger.Errorln("api token:", err.Error())
+	}
+
+	userInfo.Password = ""
+	return userInfo, true, 0
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"strings"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 模拟登录(管理员以其他用户身份查看)可以访问的只读接口，未列出的接口全部拒绝
+// 单独维护，不随个人访问令牌的权限范围变化
+var impersonationRoutes = map[string]bool{
+	"logout":                            true,
+	"user/getInfo":                      true,
+	"user/getAuthInfo":                  true,
+	"panel/itemIconGroup/getList":       true,
+	"panel/itemIcon/getListByGroupId":   true,
+	"panel/userConfig/get":              true,
+	"system/moduleConfig/getByName":     true,
+	"system/monitor/getAll":             true,
+	"system/monitor/getCpuState":        true,
+	"system/monitor/getDiskStateByPath": true,
+	"system/monitor/getMemonyState":     true,
+	"system/monitor/getDiskMountpoints": true,
+	"panel/team/getMyList":              true,
+	"panel/networkZone/getList":         true,
+	"panel/networkZone/getCurrent":      true,
+	"panel/search/query":                true,
+	"panel/tag/getList":                 true,
+	"panel/tagView/getList":             true,
+	"panel/tagView/getItems":            true,
+}
+
+func impersonationAllowed(path string) bool {
+	return impersonationRoutes[path]
+}
+
+// 模拟登录的会话访问非只读接口时终止请求，否则设置发起模拟登录的管理员ID
+func impersonationDenied(c *gin.Context, userSession models.UserSession) bool {
+	if !userSession.IsImpersonation() {
+		return false
+	}
+	if !impersonationAllowed(strings.TrimPrefix(c.FullPath(), "/api/")) {
+		apiReturn.ErrorByCode(c, 1090)
+		c.Abort()
+		return true
+	}
+	c.Set(base.GIN_GET_IMPERSONATED_BY, userSession.ImpersonatedBy)
+	return false
+}
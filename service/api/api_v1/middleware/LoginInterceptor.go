@@ -1,8 +1,12 @@
 package middleware
 
 import (
+	"strings"
 	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
 	"sun-panel/global"
+	"sun-panel/lib/cmn"
+	"sun-panel/lib/session"
 	"sun-panel/models"
 
 	"github.com/gin-gonic/gin"
@@ -13,6 +17,29 @@ func LoginInterceptor(c *gin.Context) {
 	// 继续执行后续的操作，再回来
 	// c.Next()
 
+	// 个人访问令牌
+	if userInfo, ok, errCode := apiTokenUser(c); ok {
+		if mustChangePassword(c, userInfo) {
+			return
+		}
+		c.Set("userInfo", userInfo)
+		return
+	} else if errCode != 0 {
+		apiReturn.ErrorByCode(c, errCode)
+		c.Abort()
+		return
+	}
+
+	// 可信反向代理已认证的用户，无需再次登录
+	if userInfo, ok, errCode := proxyAuthUser(c); ok {
+		c.Set("userInfo", userInfo)
+		return
+	} else if errCode != 0 {
+		apiReturn.ErrorByCode(c, errCode)
+		c.Abort()
+		return
+	}
+
 	// 获得token
 	cToken := c.GetHeader("token")
 
@@ -23,39 +50,45 @@ func LoginInterceptor(c *gin.Context) {
 		return
 	}
 
-	token := ""
-	{
-		var ok bool
-		token, ok = global.CUserToken.Get(cToken)
-		// 可能已经安全退出或者很久没有使用已过期
-		if !ok || token == "" {
-			apiReturn.ErrorByCode(c, 1001)
-			c.Abort() // 终止执行后续的操作，一般配合return使用
-			return
+	// 可能已经安全退出、被吊销或者很久没有使用已过期
+	userInfo, userSession, err := session.GetByToken(cToken, c.ClientIP())
+	if err != nil {
+		if err != models.ErrSessionInvalid {
+			global.Logger.Errorln("get session:", err.Error())
 		}
+		apiReturn.ErrorCode(c, 1001, global.Lang.Get("login.err_token_expire"), nil)
+		c.Abort()
+		return
 	}
-
-	// 直接返回缓存的用户信息
-	if userInfo, success := global.UserToken.Get(token); success {
-		c.Set("userInfo", userInfo)
+	if impersonationDenied(c, userSession) {
 		return
 	}
-
-	global.Logger.Debug("准备查询数据库的用户资料", token)
-
-	mUser := models.User{}
-	// 去库中查询是否存在该用户；否则返回错误
-	if info, err := mUser.GetUserInfoByToken(token); err != nil || info.Token == "" || info.ID == 0 {
-		apiReturn.ErrorCode(c, 1001, global.Lang.Get("login.err_token_expire"), nil)
-		c.Abort()
+	// 模拟登录时不要求修改密码
+	if !userSession.IsImpersonation() && mustChangePassword(c, userInfo) {
 		return
-	} else {
-		// 通过 设置当前用户信息
-		global.UserToken.SetDefault(info.Token, info)
-		global.CUserToken.SetDefault(cToken, token)
-		c.Set("userInfo", info)
 	}
 
+	// 通过 设置当前用户信息
+	c.Set("userInfo", userInfo)
+	c.Set(base.GIN_GET_SESSION, userSession)
+}
+
+// 被要求修改密码的账号只能访问以下接口
+var mustChangePasswordRoutes = []string{
+	"user/getInfo",
+	"user/getAuthInfo",
+	"user/updatePassword",
+	"logout",
+}
+
+// 账号需要先修改密码时终止请求
+func mustChangePassword(c *gin.Context, userInfo models.User) bool {
+	if !userInfo.MustChangePassword || cmn.InSlice(mustChangePasswordRoutes, strings.TrimPrefix(c.FullPath(), "/api/")) {
+		return false
+	}
+	apiReturn.ErrorByCode(c, 1009)
+	c.Abort()
+	return true
 }
 
 // 不验证缓存直接验证库省去没有缓存每次都要手动登录的问题
@@ -64,9 +97,14 @@ func LoginInterceptorDev(c *gin.Context) {
 	// 获得token
 	token := c.GetHeader("token")
 	mUser := models.User{}
+	mSession := models.UserSession{}
 
-	// 去库中查询是否存在该用户；否则返回错误
-	if info, err := mUser.GetUserInfoByToken(token); err != nil || info.ID == 0 {
+	// 去库中查询是否存在该会话和用户；否则返回错误
+	if userSession, err := mSession.GetByTokenHash(global.Db, models.HashToken(token)); err != nil {
+		apiReturn.ErrorCode(c, 1001, global.Lang.Get("login.err_token_expire"), nil)
+		c.Abort()
+		return
+	} else if info, err := mUser.GetUserInfoByUid(userSession.UserId); err != nil || info.ID == 0 {
 		apiReturn.ErrorCode(c, 1001, global.Lang.Get("login.err_token_expire"), nil)
 		c.Abort()
 		return
@@ -74,5 +112,6 @@ func LoginInterceptorDev(c *gin.Context) {
 		// 通过
 		// 设置当前用户信息
 		c.Set("userInfo", info)
+		c.Set(base.GIN_GET_SESSION, userSession)
 	}
 }
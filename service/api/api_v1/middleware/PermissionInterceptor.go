@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/lib/permission"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 权限拦截器，需在LoginInterceptor或PublicModeInterceptor之后使用
+// 例如：router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_MANAGE_USERS))
+func PermissionInterceptor(permissionName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser, _ := base.GetCurrentUserInfo(c)
+		if !permission.Has(currentUser.Role, permissionName) {
+			apiReturn.ErrorByCode(c, 1005)
+			c.Abort()
+			return
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/authenticator"
+	"sun-panel/lib/session"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 反向代理认证，可信代理传递了用户名请求头时视为已登录
+// ok:认证成功 errCode:请求头有效但无法登录时的错误码，均为零值表示没有使用代理认证
+func proxyAuthUser(c *gin.Context) (userInfo models.User, ok bool, errCode int) {
+	proxyAuth := authenticator.GetProxyAuth()
+	username := proxyAuth.Username(c.Request)
+	if username == "" {
+		return
+	}
+
+	// 短时间缓存，避免每次请求都查询数据库；用户组不同时重新映射角色
+	cacheKey := username
+	if proxyAuth.Config.GroupsHeader != "" {
+		cacheKey += "|" + c.GetHeader(proxyAuth.Config.GroupsHeader)
+	}
+	if info, success := global.ProxyAuthUser.Get(cacheKey); success {
+		// 账号状态以用户缓存为准，停用后立即生效
+		if current, err := session.GetUser(info.ID); err != nil {
+			return info, false, 1001
+		} else if errCode := base.UserStatusErrorCode(current); errCode != 0 {
+			return info, false, errCode
+		}
+		return info, true, 0
+	}
+
+	info, err := proxyAuth.Authenticate(c.Request, username)
+	if err != nil {
+		if err == authenticator.ErrUserNotFound || err == authenticator.ErrGroupDenied || err == authenticator.ErrUsernameInvalid {
+			global.Logger.Debug("proxy_auth: ", username, " ", err.Error())
+			return info, false, 1005
+		}
+		global.Logger.Errorln("proxy_auth:", err.Error())
+		return info, false, 1200
+	}
+
+	// 停用、未激活或已过期
+	if errCode := base.UserStatusErrorCode(info); errCode != 0 {
+		return info, false, errCode
+	}
+
+	info.Password = ""
+	global.ProxyAuthUser.SetDefault(cacheKey, info)
+	return info, true, 0
+}
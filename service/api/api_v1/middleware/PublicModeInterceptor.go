@@ -5,6 +5,7 @@ import (
 	"sun-panel/api/api_v1/common/base"
 	"sun-panel/global"
 	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/session"
 	"sun-panel/models"
 
 	"github.com/gin-gonic/gin"
@@ -12,39 +13,49 @@ import (
 
 // 公开访问模式（访客模式）
 // [有token将自动登录，无token/过期将使用公开账号，不可以与LoginInterceptor一起使用]
+// 使用公开账号时访问模式为VISIT_MODE_PUBLIC，列表按分组和图标的可见性过滤
 func PublicModeInterceptor(c *gin.Context) {
 
+	// 个人访问令牌，令牌无效时不降级为公开账号
+	if userInfo, ok, errCode := apiTokenUser(c); ok {
+		if mustChangePassword(c, userInfo) {
+			return
+		}
+		c.Set("userInfo", userInfo)
+		c.Set(base.GIN_GET_VISIT_MODE, base.VISIT_MODE_LOGIN)
+		return
+	} else if errCode != 0 {
+		apiReturn.ErrorByCode(c, errCode)
+		c.Abort()
+		return
+	}
+
+	// 可信反向代理已认证的用户，无法登录时继续使用token或公开账号
+	if userInfo, ok, _ := proxyAuthUser(c); ok {
+		c.Set("userInfo", userInfo)
+		c.Set(base.GIN_GET_VISIT_MODE, base.VISIT_MODE_LOGIN)
+		return
+	}
+
 	// 获得token
 	cToken := c.GetHeader("token")
-	token := ""
 
 	// 没有token信息视为未登录
 	if cToken != "" {
-		var ok bool
-		token, ok = global.CUserToken.Get(cToken)
-		if ok && token != "" {
-			// 直接返回缓存的用户信息
-			if userInfo, success := global.UserToken.Get(token); success {
-				global.Logger.Debug("缓存的用户TOKEN:", token)
-				c.Set("userInfo", userInfo)
+		if userInfo, userSession, err := session.GetByToken(cToken, c.ClientIP()); err == nil {
+			if impersonationDenied(c, userSession) {
 				return
-			} else {
-				global.Logger.Debug("数据库查询TOKEN:", token)
-				mUser := models.User{}
-				// 去库中查询是否存在该用户
-				if info, err := mUser.GetUserInfoByToken(token); err == nil && info.Token != "" && info.ID != 0 {
-					global.Logger.Debug("数据库查询用户:", info.ID)
-					// 通过 设置当前用户信息
-					global.UserToken.SetDefault(info.Token, info)
-					global.CUserToken.SetDefault(cToken, token)
-					c.Set("userInfo", info)
-					return
-				} else {
-					global.Logger.Debug("数据库查询用户失败", token)
-				}
 			}
+			if !userSession.IsImpersonation() && mustChangePassword(c, userInfo) {
+				return
+			}
+			// 通过 设置当前用户信息
+			c.Set("userInfo", userInfo)
+			c.Set(base.GIN_GET_SESSION, userSession)
+			c.Set(base.GIN_GET_VISIT_MODE, base.VISIT_MODE_LOGIN)
+			return
 		} else {
-			global.Logger.Debug("token为空或者不OK")
+			global.Logger.Debug("会话无效:", err.Error())
 		}
 	} else {
 		global.Logger.Debug("cToken不存在")
@@ -59,13 +70,12 @@ func PublicModeInterceptor(c *gin.Context) {
 			c.Abort()
 			return
 		}
-		global.Logger.Debug("访客用户TOKEN:", token)
 		global.Logger.Debug("访客用户ID:", userInfo.ID)
 		c.Set("userInfo", userInfo)
 		c.Set(base.GIN_GET_VISIT_MODE, base.VISIT_MODE_PUBLIC)
 		return
 	} else {
-		global.Logger.Debug("访客用户不存在:", userId, " ", token)
+		global.Logger.Debug("访客用户不存在:", userId)
 		apiReturn.ErrorCode(c, 1001, global.Lang.Get("login.err_token_expire"), nil)
 		c.Abort()
 		return
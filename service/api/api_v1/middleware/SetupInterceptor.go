@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"strings"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/lib/setup"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 首次运行设置：未完成设置前只能访问设置接口
+func SetupInterceptor(c *gin.Context) {
+	if !setup.Required() || strings.HasPrefix(c.FullPath(), "/api/setup/") {
+		return
+	}
+	apiReturn.ErrorByCode(c, 1070)
+	c.Abort()
+}
@@ -4,6 +4,10 @@ import (
 	"sun-panel/api/api_v1/common/apiReturn"
 	"sun-panel/global"
 	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/loginLimit"
+	"sun-panel/lib/magicLink"
+	"sun-panel/lib/passkey"
+	"sun-panel/structs"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,9 +21,17 @@ func (a *Openness) LoginConfig(c *gin.Context) {
 		apiReturn.Error(c, "配置查询失败："+err.Error())
 		return
 	}
+	oidcConfig := structs.IniConfigOidc{}
+	global.Config.GetSection("oidc", &oidcConfig)
 	apiReturn.SuccessData(c, gin.H{
-		"loginCaptcha": cfg.LoginCaptcha,
+		"loginCaptcha": loginLimit.CaptchaRequired(cfg.Login, c.ClientIP()),
 		"register":     cfg.Register,
+		"passkey":      passkey.Enabled(),
+		"magicLink":    magicLink.Enabled(cfg),
+		"oidc": gin.H{
+			"enable":     oidcConfig.Enable,
+			"buttonName": oidcConfig.ButtonName,
+		},
 	})
 }
 
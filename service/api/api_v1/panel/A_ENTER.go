@@ -1,8 +1,17 @@
 package panel
 
 type ApiPanel struct {
-	ItemIcon      ItemIcon
-	UserConfig    UserConfig
-	UsersApi      UsersApi
-	ItemIconGroup ItemIconGroup
+	ItemIcon         ItemIcon
+	UserConfig       UserConfig
+	UsersApi         UsersApi
+	ItemIconGroup    ItemIconGroup
+	LoginLimitApi    LoginLimitApi
+	SystemSettingApi SystemSettingApi
+	RoleApi          RoleApi
+	TeamApi          TeamApi
+	AuditLogApi      AuditLogApi
+	NetworkZoneApi   NetworkZoneApi
+	SearchApi        SearchApi
+	TagApi           TagApi
+	TagViewApi       TagViewApi
 }
@@ -1,11 +1,13 @@
 package panel
 
 import (
-	"math"
+	"fmt"
 	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
 	"sun-panel/api/api_v1/common/apiReturn"
 	"sun-panel/api/api_v1/common/base"
 	"sun-panel/global"
+	"sun-panel/lib/audit"
+	"sun-panel/lib/team"
 	"sun-panel/models"
 
 	"github.com/gin-gonic/gin"
@@ -25,20 +27,70 @@ func (a *ItemIconGroup) Edit(c *gin.Context) {
 		return
 	}
 
+	if err := checkVisibility(req.Visibility); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+
+	access, err := team.GetAccess(userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
 	req.UserId = userInfo.ID
 
 	if req.ID != 0 {
-		// 修改
-		updateField := []string{"IconJson", "Icon", "Title", "Url", "LanUrl", "Description", "OpenMethod", "GroupId", "UserId"}
+		// 修改，团队分组保留原创建者和所属团队
+		group, ok, err := access.GetGroup(req.ID)
+		if err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		} else if !ok {
+			apiReturn.ErrorDataNotFound(c)
+			return
+		} else if !access.CanEdit(group) {
+			apiReturn.ErrorByCode(c, 1005)
+			return
+		}
+		req.UserId = group.UserId
+		req.TeamId = group.TeamId
+		before := group
+
+		updateField := []string{"Icon", "Title", "Description", "UserId"}
 		if req.Sort != 0 {
 			updateField = append(updateField, "Sort")
 		}
-		global.Db.Model(&models.ItemIconGroup{}).
+		// 未提交可见性时不修改
+		if req.Visibility != "" {
+			updateField = append(updateField, "Visibility")
+		} else {
+			req.Visibility = before.Visibility
+		}
+		if err := global.Db.Model(&models.ItemIconGroup{}).
 			Select(updateField).
-			Where("id=?", req.ID).Updates(&req)
+			Where("id=?", req.ID).Updates(&req).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+
+		after := models.ItemIconGroup{}
+		global.Db.First(&after, "id=?", req.ID)
+		audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_ITEM_ICON_GROUP, req.ID, before, after)
 	} else {
-		// 创建
-		global.Db.Create(&req)
+		// 创建，在团队中创建需要是团队的编辑者
+		if req.TeamId != 0 && access.Roles[req.TeamId] != models.TEAM_MEMBER_ROLE_EDITOR {
+			apiReturn.ErrorByCode(c, 1005)
+			return
+		}
+		if req.Visibility == "" {
+			req.Visibility = models.VISIBILITY_PUBLIC
+		}
+		if err := global.Db.Create(&req).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		audit.Record(c, models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_ITEM_ICON_GROUP, req.ID, nil, req)
 	}
 
 	apiReturn.SuccessData(c, req)
@@ -50,7 +102,7 @@ func (a *ItemIconGroup) GetList(c *gin.Context) {
 	groups := []models.ItemIconGroup{}
 
 	err := global.Db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Order("sort ,created_at").Where("user_id=?", userInfo.ID).Find(&groups).Error; err != nil {
+		if err := tx.Order("sort ,created_at").Where("user_id=? AND team_id=0", userInfo.ID).Find(&groups).Error; err != nil {
 			apiReturn.ErrorDatabase(c, err.Error())
 			return err
 		}
@@ -83,9 +135,124 @@ func (a *ItemIconGroup) GetList(c *gin.Context) {
 	if err != nil {
 		apiReturn.ErrorDatabase(c, err.Error())
 		return
-	} else {
-		apiReturn.SuccessListData(c, groups, 0)
 	}
+
+	for k := range groups {
+		groups[k].Ownership = models.ITEM_ICON_GROUP_OWNERSHIP_SELF
+		groups[k].Editable = true
+	}
+
+	// 合并所在团队的分组，排在个人分组之后
+	teamGroups, err := getTeamGroups(userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	groups = append(groups, teamGroups...)
+
+	access, err := team.GetAccess(userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	public := isPublicVisit(c)
+
+	// 登录用户合并公开账号共享的分组，排在最后
+	if !public {
+		publicUserGroups, err := access.PublicUserGroups()
+		if err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		for k := range publicUserGroups {
+			publicUserGroups[k].Ownership = models.ITEM_ICON_GROUP_OWNERSHIP_PUBLIC
+		}
+		groups = append(groups, publicUserGroups...)
+	}
+
+	// 按可见性过滤，公开模式的访客只能看到公开的分组
+	visibleGroups := []models.ItemIconGroup{}
+	for _, v := range groups {
+		if access.CanSee(v, v.Visibility, public) {
+			visibleGroups = append(visibleGroups, v)
+		}
+	}
+
+	apiReturn.SuccessListData(c, visibleGroups, 0)
+}
+
+// 是否为公开模式的访客
+func isPublicVisit(c *gin.Context) bool {
+	return base.GetCurrentVisitMode(c) == base.VISIT_MODE_PUBLIC
+}
+
+// 检查可见性，可以为空
+func checkVisibility(visibility string) error {
+	switch visibility {
+	case "", models.VISIBILITY_PUBLIC, models.VISIBILITY_LOGIN, models.VISIBILITY_PRIVATE:
+		return nil
+	}
+	return fmt.Errorf("invalid visibility: %s", visibility)
+}
+
+// 当前访问者可以查看并且按可见性可见的分组，分组ID:分组
+func getVisibleGroups(c *gin.Context, access team.Access) (map[uint]models.ItemIconGroup, error) {
+	visibleGroups := map[uint]models.ItemIconGroup{}
+	ids, err := access.ViewableGroupIds()
+	if err != nil || len(ids) == 0 {
+		return visibleGroups, err
+	}
+	groups := []models.ItemIconGroup{}
+	if err := global.Db.Find(&groups, "id in ?", ids).Error; err != nil {
+		return nil, err
+	}
+	public := isPublicVisit(c)
+	for _, v := range groups {
+		if access.CanSee(v, v.Visibility, public) {
+			visibleGroups[v.ID] = v
+		}
+	}
+	return visibleGroups, nil
+}
+
+// 按可见性过滤图标，所在分组不在groups中的图标同样过滤
+func filterVisibleItemIcons(c *gin.Context, access team.Access, groups map[uint]models.ItemIconGroup, itemIcons []models.ItemIcon) []models.ItemIcon {
+	public := isPublicVisit(c)
+	list := []models.ItemIcon{}
+	for _, v := range itemIcons {
+		group, ok := groups[uint(v.ItemIconGroupId)]
+		if ok && access.CanSee(group, v.Visibility, public) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// 用户所在团队的分组
+func getTeamGroups(userId uint) ([]models.ItemIconGroup, error) {
+	groups := []models.ItemIconGroup{}
+	access, err := team.GetAccess(userId)
+	if err != nil || len(access.Roles) == 0 {
+		return groups, err
+	}
+
+	teams := []models.Team{}
+	if err := global.Db.Order("id").Find(&teams, "id in ?", access.TeamIds()).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range teams {
+		list := []models.ItemIconGroup{}
+		if err := global.Db.Order("sort ,created_at").Find(&list, "team_id=?", t.ID).Error; err != nil {
+			return nil, err
+		}
+		for k := range list {
+			list[k].Ownership = models.ITEM_ICON_GROUP_OWNERSHIP_TEAM
+			list[k].TeamName = t.Name
+			list[k].Editable = access.CanEdit(list[k])
+		}
+		groups = append(groups, list...)
+	}
+	return groups, nil
 }
 
 func (a *ItemIconGroup) Deletes(c *gin.Context) {
@@ -97,25 +264,45 @@ func (a *ItemIconGroup) Deletes(c *gin.Context) {
 	}
 	userInfo, _ := base.GetCurrentUserInfo(c)
 
-	var count int64
-	if err := global.Db.Model(&models.ItemIconGroup{}).Where(" user_id=?", userInfo.ID).Count(&count).Error; err != nil {
+	editable, err := getEditableGroupIds(userInfo.ID)
+	if err != nil {
 		apiReturn.ErrorDatabase(c, err.Error())
 		return
-	} else {
-		if math.Abs(float64(len(req.Ids))-float64(count)) < 1 {
-			apiReturn.ErrorCode(c, 1201, "At least one must be retained", nil)
-			return
+	}
+	deleteIds := []uint{}
+	for _, v := range req.Ids {
+		if editable[v] {
+			deleteIds = append(deleteIds, v)
 		}
+	}
+	if len(deleteIds) == 0 {
+		apiReturn.Success(c)
+		return
+	}
 
+	// 个人分组至少保留一个
+	var count int64
+	if err := global.Db.Model(&models.ItemIconGroup{}).Where("user_id=? AND team_id=0 AND id not in ?", userInfo.ID, deleteIds).Count(&count).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	} else if count == 0 {
+		apiReturn.ErrorCode(c, 1201, "At least one must be retained", nil)
+		return
+	}
+
+	deleteGroups := []models.ItemIconGroup{}
+	if err := global.Db.Find(&deleteGroups, "id in ?", deleteIds).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
 	}
 
 	txErr := global.Db.Transaction(func(tx *gorm.DB) error {
-		mitemIcon := models.ItemIcon{}
-		if err := tx.Delete(&models.ItemIconGroup{}, "id in ? AND user_id=?", req.Ids, userInfo.ID).Error; err != nil {
+		if err := tx.Delete(&models.ItemIconGroup{}, "id in ?", deleteIds).Error; err != nil {
 			return err
 		}
 
-		if err := mitemIcon.DeleteByItemIconGroupIds(tx, userInfo.ID, req.Ids); err != nil {
+		// 团队分组中的图标可能由多个成员创建，按分组删除
+		if err := tx.Delete(&models.ItemIcon{}, "item_icon_group_id in ?", deleteIds).Error; err != nil {
 			return err
 		}
 
@@ -126,6 +313,9 @@ func (a *ItemIconGroup) Deletes(c *gin.Context) {
 		apiReturn.ErrorDatabase(c, txErr.Error())
 		return
 	}
+	for _, v := range deleteGroups {
+		audit.Record(c, models.AUDIT_ACTION_DELETE, models.AUDIT_TARGET_ITEM_ICON_GROUP, v.ID, v, nil)
+	}
 
 	apiReturn.Success(c)
 }
@@ -144,7 +334,7 @@ func (a *ItemIconGroup) SaveSort(c *gin.Context) {
 	transactionErr := global.Db.Transaction(func(tx *gorm.DB) error {
 		// 在事务中执行一些 db 操作（从这里开始，您应该使用 'tx' 而不是 'db'）
 		for _, v := range req.SortItems {
-			if err := tx.Model(&models.ItemIconGroup{}).Where("user_id=? AND id=?", userInfo.ID, v.Id).Update("sort", v.Sort).Error; err != nil {
+			if err := tx.Model(&models.ItemIconGroup{}).Where("user_id=? AND team_id=0 AND id=?", userInfo.ID, v.Id).Update("sort", v.Sort).Error; err != nil {
 				// 返回任何错误都会回滚事务
 				return err
 			}
@@ -0,0 +1,97 @@
+package panel
+
+import (
+	"sun-panel/api/api_v1/common/apiData/adminApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/audit"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// 审计日志(管理员)
+type AuditLogApi struct{}
+
+func (a AuditLogApi) GetList(c *gin.Context) {
+	req := adminApiStructs.AuditLogGetListReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 || req.Limit > 100 {
+		req.Limit = 20
+	}
+
+	var (
+		list  []models.AuditLog
+		count int64
+	)
+	db := global.Db.Model(&models.AuditLog{})
+	if req.UserId != 0 {
+		db = db.Where("user_id=?", req.UserId)
+	}
+	if req.Action != "" {
+		db = db.Where("action=?", req.Action)
+	}
+	if req.TargetType != "" {
+		db = db.Where("target_type=?", req.TargetType)
+	}
+	if req.TargetId != "" {
+		db = db.Where("target_id=?", req.TargetId)
+	}
+	if req.StartTime != nil {
+		db = db.Where("created_at>=?", *req.StartTime)
+	}
+	if req.EndTime != nil {
+		db = db.Where("created_at<?", *req.EndTime)
+	}
+	if req.Keyword != "" {
+		db = db.Where("username LIKE ? OR ip LIKE ?", "%"+req.Keyword+"%", "%"+req.Keyword+"%")
+	}
+
+	if err := db.Count(&count).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if err := db.Order("id desc").Limit(req.Limit).Offset((req.Page - 1) * req.Limit).Find(&list).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.SuccessListData(c, list, count)
+}
+
+func (a AuditLogApi) GetSetting(c *gin.Context) {
+	apiReturn.SuccessData(c, audit.GetSetting())
+}
+
+// 修改保留天数，立即清理超过保留天数的日志
+func (a AuditLogApi) SetSetting(c *gin.Context) {
+	req := systemSetting.AuditLog{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	before := audit.GetSetting()
+	if err := global.SystemSetting.Set(systemSetting.SYSTEM_AUDIT_LOG, req); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_SYSTEM_SETTING, systemSetting.SYSTEM_AUDIT_LOG, before, req)
+	if err := audit.Cleanup(); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.Success(c)
+}
@@ -13,8 +13,13 @@ import (
 	"sun-panel/api/api_v1/common/apiReturn"
 	"sun-panel/api/api_v1/common/base"
 	"sun-panel/global"
+	"sun-panel/lib/audit"
 	"sun-panel/lib/cmn"
+	"sun-panel/lib/healthCheck"
+	"sun-panel/lib/networkZone"
+	"sun-panel/lib/permission"
 	"sun-panel/lib/siteFavicon"
+	"sun-panel/lib/team"
 	"sun-panel/models"
 	"time"
 
@@ -41,8 +46,71 @@ func (a *ItemIcon) Edit(c *gin.Context) {
 		return
 	}
 
+	if err := healthCheck.Normalize(&req); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if err := checkZoneUrls(req.ZoneUrls); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if err := checkVisibility(req.Visibility); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	tagIds, err := checkTagIds(userInfo.ID, req.TagIds)
+	if err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	req.TagIds = tagIds
+
 	req.UserId = userInfo.ID
 
+	// 目标分组以及修改前所在的分组都需要有编辑权限
+	editableIds, err := getEditableGroupIds(userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if !editableIds[uint(req.ItemIconGroupId)] {
+		apiReturn.ErrorByCode(c, 1005)
+		return
+	}
+	before := models.ItemIcon{}
+	if req.ID != 0 {
+		info := models.ItemIcon{}
+		if err := global.Db.First(&info, "id=?", req.ID).Error; err == gorm.ErrRecordNotFound {
+			apiReturn.ErrorDataNotFound(c)
+			return
+		} else if err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		if !editableIds[uint(info.ItemIconGroupId)] {
+			apiReturn.ErrorByCode(c, 1005)
+			return
+		}
+		// 保留原创建者
+		req.UserId = info.UserId
+		json.Unmarshal([]byte(info.IconJson), &info.Icon)
+		mZoneUrl := models.ItemIconZoneUrl{}
+		if info.ZoneUrls, err = mZoneUrl.GetByItemIconIds(global.Db, []uint{info.ID}); err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		beforeList := []models.ItemIcon{info}
+		if err := attachItemIconTags(userInfo.ID, beforeList); err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		before = beforeList[0]
+	}
+	if healthCheck.Changed(before, req) && !permission.Has(userInfo.Role, models.PERMISSION_HEALTH_CHECK) {
+		apiReturn.ErrorByCode(c, 1005)
+		return
+	}
+
 	// json转字符串
 	if j, err := json.Marshal(req.Icon); err == nil {
 		req.IconJson = string(j)
@@ -50,18 +118,56 @@ func (a *ItemIcon) Edit(c *gin.Context) {
 
 	if req.ID != 0 {
 		// 修改
-		updateField := []string{"IconJson", "Icon", "Title", "Url", "LanUrl", "Description", "OpenMethod", "GroupId", "UserId", "ItemIconGroupId"}
+		updateField := []string{"IconJson", "Title", "Url", "LanUrl", "Description", "OpenMethod", "UserId", "ItemIconGroupId",
+			"HealthCheckType", "HealthCheckInterval", "HealthCheckTimeout", "HealthCheckExpectedStatus", "HealthCheckKeyword"}
 		if req.Sort != 0 {
 			updateField = append(updateField, "Sort")
 		}
-		global.Db.Model(&models.ItemIcon{}).
+		// 未提交可见性时不修改
+		if req.Visibility != "" {
+			updateField = append(updateField, "Visibility")
+		} else {
+			req.Visibility = before.Visibility
+		}
+		if err := global.Db.Model(&models.ItemIcon{}).
 			Select(updateField).
-			Where("id=?", req.ID).Updates(&req)
+			Where("id=?", req.ID).Updates(&req).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+
+		if !saveZoneUrls(c, req) || !saveItemIconTags(c, userInfo.ID, req) {
+			return
+		}
+
+		after := models.ItemIcon{}
+		global.Db.First(&after, "id=?", req.ID)
+		json.Unmarshal([]byte(after.IconJson), &after.Icon)
+		after.ZoneUrls = req.ZoneUrls
+		if req.ZoneUrls == nil {
+			after.ZoneUrls = before.ZoneUrls
+		}
+		after.TagIds = req.TagIds
+		if req.TagIds == nil {
+			after.TagIds = before.TagIds
+		}
+		audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_ITEM_ICON, req.ID, before, after)
 	} else {
 		req.Sort = 9999
+		if req.Visibility == "" {
+			req.Visibility = models.VISIBILITY_PUBLIC
+		}
 		// 创建
-		global.Db.Create(&req)
+		if err := global.Db.Create(&req).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		if !saveZoneUrls(c, req) || !saveItemIconTags(c, userInfo.ID, req) {
+			return
+		}
+		audit.Record(c, models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_ITEM_ICON, req.ID, nil, req)
 	}
+	healthCheck.Reset(req.ID)
 
 	apiReturn.SuccessData(c, req)
 }
@@ -77,11 +183,43 @@ func (a *ItemIcon) AddMultiple(c *gin.Context) {
 		return
 	}
 
+	editableIds, err := getEditableGroupIds(userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
 	for i := 0; i < len(req); i++ {
 		if req[i].ItemIconGroupId == 0 {
 			apiReturn.ErrorParamFomat(c, "Group is mandatory")
 			return
 		}
+		if !editableIds[uint(req[i].ItemIconGroupId)] {
+			apiReturn.ErrorByCode(c, 1005)
+			return
+		}
+		if err := healthCheck.Normalize(&req[i]); err != nil {
+			apiReturn.ErrorParamFomat(c, err.Error())
+			return
+		}
+		if healthCheck.Changed(models.ItemIcon{}, req[i]) && !permission.Has(userInfo.Role, models.PERMISSION_HEALTH_CHECK) {
+			apiReturn.ErrorByCode(c, 1005)
+			return
+		}
+		if err := checkZoneUrls(req[i].ZoneUrls); err != nil {
+			apiReturn.ErrorParamFomat(c, err.Error())
+			return
+		}
+		if req[i].TagIds, err = checkTagIds(userInfo.ID, req[i].TagIds); err != nil {
+			apiReturn.ErrorParamFomat(c, err.Error())
+			return
+		}
+		if err := checkVisibility(req[i].Visibility); err != nil {
+			apiReturn.ErrorParamFomat(c, err.Error())
+			return
+		} else if req[i].Visibility == "" {
+			req[i].Visibility = models.VISIBILITY_PUBLIC
+		}
 		req[i].UserId = userInfo.ID
 		// json转字符串
 		if j, err := json.Marshal(req[i].Icon); err == nil {
@@ -90,6 +228,12 @@ func (a *ItemIcon) AddMultiple(c *gin.Context) {
 	}
 
 	global.Db.Create(&req)
+	for _, v := range req {
+		if !saveZoneUrls(c, v) || !saveItemIconTags(c, userInfo.ID, v) {
+			return
+		}
+		audit.Record(c, models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_ITEM_ICON, v.ID, nil, v)
+	}
 
 	apiReturn.SuccessData(c, req)
 }
@@ -135,16 +279,82 @@ func (a *ItemIcon) GetListByGroupId(c *gin.Context) {
 	userInfo, _ := base.GetCurrentUserInfo(c)
 	itemIcons := []models.ItemIcon{}
 
-	if err := global.Db.Order("sort ,created_at").Find(&itemIcons, "item_icon_group_id = ? AND user_id=?", req.ItemIconGroupId, userInfo.ID).Error; err != nil {
+	access, err := team.GetAccess(userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	group, ok, err := access.GetGroup(uint(req.ItemIconGroupId))
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	} else if !ok || !access.CanSee(group, group.Visibility, isPublicVisit(c)) {
+		apiReturn.SuccessListData(c, itemIcons, 0)
+		return
+	}
+
+	// 团队分组中的图标可能由多个成员创建，按分组查询
+	query := global.Db.Order("sort ,created_at").Where("item_icon_group_id = ?", req.ItemIconGroupId)
+
+	// 按标签筛选，包含任一标签即可
+	if len(req.TagIds) > 0 {
+		tagIds, err := checkTagIds(userInfo.ID, req.TagIds)
+		if err != nil {
+			apiReturn.ErrorParamFomat(c, err.Error())
+			return
+		}
+		mItemIconTag := models.ItemIconTag{}
+		ids, err := mItemIconTag.GetItemIconIds(global.Db, tagIds, false)
+		if err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		query = query.Where("id in ?", append(ids, 0))
+	}
+
+	if err := query.Find(&itemIcons).Error; err != nil {
 		apiReturn.ErrorDatabase(c, err.Error())
 		return
 	}
+	itemIcons = filterVisibleItemIcons(c, access, map[uint]models.ItemIconGroup{group.ID: group}, itemIcons)
+	if !fillItemIconList(c, userInfo.ID, itemIcons) {
+		return
+	}
 
+	apiReturn.SuccessListData(c, itemIcons, 0)
+}
+
+// 补充列表中图标的信息：图标、健康状态、标签和根据网络区域选择的地址
+func fillItemIconList(c *gin.Context, userId uint, itemIcons []models.ItemIcon) bool {
+	// 开启健康检测的图标返回最近的检测结果
+	healths, err := healthCheck.GetHealth(itemIcons)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return false
+	}
 	for k, v := range itemIcons {
 		json.Unmarshal([]byte(v.IconJson), &itemIcons[k].Icon)
+		if health, ok := healths[v.ID]; ok {
+			itemIcons[k].Health = &health
+		}
 	}
 
-	apiReturn.SuccessListData(c, itemIcons, 0)
+	if err := attachItemIconTags(userId, itemIcons); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return false
+	}
+
+	// 根据访问者所在的网络区域选择地址
+	zone, hideInternal, err := visitorZone(c)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return false
+	}
+	if err := networkZone.Apply(itemIcons, zone, hideInternal); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return false
+	}
+	return true
 }
 
 func (a *ItemIcon) Deletes(c *gin.Context) {
@@ -156,10 +366,47 @@ func (a *ItemIcon) Deletes(c *gin.Context) {
 	}
 
 	userInfo, _ := base.GetCurrentUserInfo(c)
-	if err := global.Db.Delete(&models.ItemIcon{}, "id in ? AND user_id=?", req.Ids, userInfo.ID).Error; err != nil {
+	editableIds, err := getEditableGroupIds(userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	groupIds := []uint{0}
+	for id := range editableIds {
+		groupIds = append(groupIds, id)
+	}
+	deleteItems := []models.ItemIcon{}
+	if err := global.Db.Find(&deleteItems, "id in ? AND item_icon_group_id in ?", req.Ids, groupIds).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if err := global.Db.Delete(&models.ItemIcon{}, "id in ? AND item_icon_group_id in ?", req.Ids, groupIds).Error; err != nil {
 		apiReturn.ErrorDatabase(c, err.Error())
 		return
 	}
+	deleteIds := []uint{}
+	for _, v := range deleteItems {
+		deleteIds = append(deleteIds, v.ID)
+	}
+	mHealthCheck := models.ItemIconHealthCheck{}
+	if err := mHealthCheck.DeleteByItemIconIds(global.Db, deleteIds); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	mZoneUrl := models.ItemIconZoneUrl{}
+	if err := mZoneUrl.DeleteByItemIconIds(global.Db, deleteIds); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	mItemIconTag := models.ItemIconTag{}
+	if err := mItemIconTag.DeleteByItemIconIds(global.Db, deleteIds); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	for _, v := range deleteItems {
+		json.Unmarshal([]byte(v.IconJson), &v.Icon)
+		audit.Record(c, models.AUDIT_ACTION_DELETE, models.AUDIT_TARGET_ITEM_ICON, v.ID, v, nil)
+	}
 
 	apiReturn.Success(c)
 }
@@ -174,11 +421,18 @@ func (a *ItemIcon) SaveSort(c *gin.Context) {
 	}
 
 	userInfo, _ := base.GetCurrentUserInfo(c)
+	if editableIds, err := getEditableGroupIds(userInfo.ID); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	} else if !editableIds[uint(req.ItemIconGroupId)] {
+		apiReturn.ErrorByCode(c, 1005)
+		return
+	}
 
 	transactionErr := global.Db.Transaction(func(tx *gorm.DB) error {
 		// 在事务中执行一些 db 操作（从这里开始，您应该使用 'tx' 而不是 'db'）
 		for _, v := range req.SortItems {
-			if err := tx.Model(&models.ItemIcon{}).Where("user_id=? AND id=? AND item_icon_group_id=?", userInfo.ID, v.Id, req.ItemIconGroupId).Update("sort", v.Sort).Error; err != nil {
+			if err := tx.Model(&models.ItemIcon{}).Where("id=? AND item_icon_group_id=?", v.Id, req.ItemIconGroupId).Update("sort", v.Sort).Error; err != nil {
 				// 返回任何错误都会回滚事务
 				return err
 			}
@@ -196,6 +450,63 @@ func (a *ItemIcon) SaveSort(c *gin.Context) {
 	apiReturn.Success(c)
 }
 
+// 检查图标的区域地址，区域需要存在且不能重复
+func checkZoneUrls(zoneUrls []models.ItemIconZoneUrl) error {
+	if len(zoneUrls) == 0 {
+		return nil
+	}
+	zones, err := networkZone.GetList()
+	if err != nil {
+		return err
+	}
+	exists := map[uint]bool{}
+	for _, v := range zones {
+		exists[v.ID] = true
+	}
+	used := map[uint]bool{}
+	for i, v := range zoneUrls {
+		if !exists[v.NetworkZoneId] || used[v.NetworkZoneId] {
+			return fmt.Errorf("invalid network zone: %d", v.NetworkZoneId)
+		}
+		used[v.NetworkZoneId] = true
+		zoneUrls[i].Url = strings.TrimSpace(v.Url)
+		if len(zoneUrls[i].Url) > 1000 {
+			return fmt.Errorf("url is too long")
+		}
+	}
+	return nil
+}
+
+// 保存图标的区域地址，未提交(nil)时不修改
+func saveZoneUrls(c *gin.Context, item models.ItemIcon) bool {
+	if item.ZoneUrls == nil {
+		return true
+	}
+	mZoneUrl := models.ItemIconZoneUrl{}
+	if err := mZoneUrl.Save(global.Db, item.ID, item.ZoneUrls); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return false
+	}
+	return true
+}
+
+// 用户可以编辑的分组：个人分组和作为编辑者的团队分组
+func getEditableGroupIds(userId uint) (map[uint]bool, error) {
+	access, err := team.GetAccess(userId)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := access.EditableGroupIds()
+	if err != nil {
+		return nil, err
+	}
+	editable := map[uint]bool{}
+	for _, v := range ids {
+		editable[v] = true
+	}
+	return editable, nil
+}
+
 // 支持获取并直接下载对方网站图标到服务器
 func (a *ItemIcon) GetSiteFavicon(c *gin.Context) {
 	userInfo, _ := base.GetCurrentUserInfo(c)
@@ -0,0 +1,65 @@
+package panel
+
+import (
+	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/loginLimit"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// 登录失败限制(管理员)
+type LoginLimitApi struct{}
+
+func (a LoginLimitApi) GetSetting(c *gin.Context) {
+	apiReturn.SuccessData(c, loginLimit.GetSetting())
+}
+
+func (a LoginLimitApi) SetSetting(c *gin.Context) {
+	req := systemSetting.LoginLimit{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	if err := global.SystemSetting.Set(systemSetting.SYSTEM_LOGIN_LIMIT, req); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.Success(c)
+}
+
+// 锁定中的账号和IP
+func (a LoginLimitApi) GetLockedList(c *gin.Context) {
+	mLockout := models.LoginLockout{}
+	list, err := mLockout.GetActiveList(global.Db)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.SuccessListData(c, list, int64(len(list)))
+}
+
+// 解除锁定
+func (a LoginLimitApi) Unlock(c *gin.Context) {
+	req := commonApiStructs.RequestDeleteIds[uint]{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+
+	if err := loginLimit.Unlock(req.Ids); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.Success(c)
+}
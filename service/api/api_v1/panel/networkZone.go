@@ -0,0 +1,138 @@
+package panel
+
+import (
+	"strings"
+	"sun-panel/api/api_v1/common/apiData/adminApiStructs"
+	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/audit"
+	"sun-panel/lib/networkZone"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// 网络区域，根据访问者IP选择图标的地址
+type NetworkZoneApi struct{}
+
+func (a NetworkZoneApi) GetList(c *gin.Context) {
+	list, err := networkZone.GetList()
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.SuccessListData(c, list, int64(len(list)))
+}
+
+// 访问者的IP和所在的网络区域
+func (a NetworkZoneApi) GetCurrent(c *gin.Context) {
+	zone, _, err := visitorZone(c)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	var zoneInfo gin.H
+	if zone != nil {
+		zoneInfo = gin.H{
+			"id":       zone.ID,
+			"name":     zone.Name,
+			"internal": zone.Internal,
+		}
+	}
+	apiReturn.SuccessData(c, gin.H{
+		"ip":   c.ClientIP(),
+		"zone": zoneInfo,
+	})
+}
+
+// 添加或修改网络区域(管理员)
+func (a NetworkZoneApi) Edit(c *gin.Context) {
+	req := adminApiStructs.NetworkZoneEditReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+	ipNets, err := networkZone.ParseCidrs(req.Cidrs)
+	if err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	cidrs := []string{}
+	for _, v := range ipNets {
+		cidrs = append(cidrs, v.String())
+	}
+
+	info := models.NetworkZone{
+		Name:     strings.TrimSpace(req.Name),
+		Cidrs:    strings.Join(cidrs, ","),
+		Internal: req.Internal,
+		Sort:     req.Sort,
+	}
+	if req.Id == 0 {
+		if err := global.Db.Create(&info).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		audit.Record(c, models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_NETWORK_ZONE, info.ID, nil, info)
+	} else {
+		before := models.NetworkZone{}
+		if err := global.Db.First(&before, "id=?", req.Id).Error; err == gorm.ErrRecordNotFound {
+			apiReturn.ErrorDataNotFound(c)
+			return
+		} else if err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		if err := global.Db.Model(&models.NetworkZone{}).Where("id=?", req.Id).Select("Name", "Cidrs", "Internal", "Sort").Updates(&info).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		info.ID = req.Id
+		audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_NETWORK_ZONE, info.ID, before, info)
+	}
+	networkZone.ClearCache()
+	apiReturn.SuccessData(c, info)
+}
+
+// 删除网络区域，同时删除图标在该区域的地址(管理员)
+func (a NetworkZoneApi) Deletes(c *gin.Context) {
+	req := commonApiStructs.RequestDeleteIds[uint]{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+
+	list := []models.NetworkZone{}
+	if err := global.Db.Find(&list, "id in ?", req.Ids).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	mZone := models.NetworkZone{}
+	if err := mZone.DeleteByIds(global.Db, req.Ids); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	networkZone.ClearCache()
+	for _, v := range list {
+		audit.Record(c, models.AUDIT_ACTION_DELETE, models.AUDIT_TARGET_NETWORK_ZONE, v.ID, v, nil)
+	}
+	apiReturn.Success(c)
+}
+
+// 访问者所在的网络区域，hideInternal表示是否需要隐藏内部地址(公开模式下不在内部区域的访问者)
+func visitorZone(c *gin.Context) (zone *models.NetworkZone, hideInternal bool, err error) {
+	zone, err = networkZone.Match(c.ClientIP())
+	if err != nil {
+		return nil, true, err
+	}
+	hideInternal = base.GetCurrentVisitMode(c) == base.VISIT_MODE_PUBLIC && (zone == nil || !zone.Internal)
+	return zone, hideInternal, nil
+}
@@ -0,0 +1,175 @@
+package panel
+
+import (
+	"strings"
+	"sun-panel/api/api_v1/common/apiData/adminApiStructs"
+	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/audit"
+	"sun-panel/lib/cmn"
+	"sun-panel/lib/permission"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// 角色和权限(管理员)
+type RoleApi struct{}
+
+// 全部可用的权限
+func (a RoleApi) GetPermissionList(c *gin.Context) {
+	apiReturn.SuccessListData(c, models.Permissions, int64(len(models.Permissions)))
+}
+
+func (a RoleApi) GetList(c *gin.Context) {
+	mRole := models.Role{}
+	list, err := mRole.GetList(global.Db)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	resList := []adminApiStructs.RoleInfo{}
+	for _, v := range list {
+		var count int64
+		if err := global.Db.Model(&models.User{}).Where("role=?", v.ID).Count(&count).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		resList = append(resList, adminApiStructs.RoleInfo{
+			Role:        v,
+			Permissions: permission.List(int(v.ID)),
+			UserCount:   count,
+		})
+	}
+	apiReturn.SuccessListData(c, resList, int64(len(resList)))
+}
+
+// 添加或修改角色，管理员角色拥有全部权限，不能修改
+func (a RoleApi) Edit(c *gin.Context) {
+	req := adminApiStructs.RoleEditReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+	if req.Id == models.ROLE_ADMIN {
+		apiReturn.ErrorByCode(c, 1051)
+		return
+	}
+
+	permissions := []string{}
+	for _, v := range req.Permissions {
+		if !cmn.InSlice(models.Permissions, v) {
+			apiReturn.ErrorParamFomat(c, "unknown permission: "+v)
+			return
+		}
+		if !cmn.InSlice(permissions, v) {
+			permissions = append(permissions, v)
+		}
+	}
+	// 只能授予自己拥有的权限
+	currentUser, _ := base.GetCurrentUserInfo(c)
+	if !permission.HasAll(currentUser.Role, permissions) {
+		apiReturn.ErrorByCode(c, 1054)
+		return
+	}
+
+	info := models.Role{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Permissions: strings.Join(permissions, ","),
+	}
+	if req.Id == 0 {
+		if err := global.Db.Create(&info).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		audit.Record(c, models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_ROLE, info.ID, nil, roleAuditInfo(info))
+	} else {
+		mRole := models.Role{}
+		before, err := mRole.GetById(global.Db, int(req.Id))
+		if err == gorm.ErrRecordNotFound {
+			apiReturn.ErrorByCode(c, 1050)
+			return
+		} else if err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		// 不能修改拥有自己没有的权限的角色
+		if !permission.CanAssign(currentUser.Role, int(req.Id)) {
+			apiReturn.ErrorByCode(c, 1054)
+			return
+		}
+		if err := global.Db.Model(&models.Role{}).Where("id=?", req.Id).Select("Name", "Description", "Permissions").Updates(&info).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		info.ID = req.Id
+		permission.ClearCache(int(req.Id))
+		audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_ROLE, info.ID, roleAuditInfo(before), roleAuditInfo(info))
+	}
+
+	apiReturn.SuccessData(c, adminApiStructs.RoleInfo{
+		Role:        info,
+		Permissions: permissions,
+	})
+}
+
+// 删除角色，内置角色和使用中的角色不能删除
+func (a RoleApi) Deletes(c *gin.Context) {
+	req := commonApiStructs.RequestDeleteIds[uint]{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+
+	var count int64
+	if err := global.Db.Model(&models.Role{}).Where("id in ? AND built_in=?", req.Ids, true).Count(&count).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	} else if count != 0 {
+		apiReturn.ErrorByCode(c, 1052)
+		return
+	}
+	if err := global.Db.Model(&models.User{}).Where("role in ?", req.Ids).Count(&count).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	} else if count != 0 {
+		apiReturn.ErrorByCode(c, 1053)
+		return
+	}
+
+	deleteRoles := []models.Role{}
+	if err := global.Db.Find(&deleteRoles, "id in ?", req.Ids).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if err := global.Db.Delete(&models.Role{}, "id in ?", req.Ids).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	for _, v := range req.Ids {
+		permission.ClearCache(int(v))
+	}
+	for _, v := range deleteRoles {
+		audit.Record(c, models.AUDIT_ACTION_DELETE, models.AUDIT_TARGET_ROLE, v.ID, roleAuditInfo(v), nil)
+	}
+	apiReturn.Success(c)
+}
+
+// 审计日志中记录的角色信息(权限字段不输出json)
+func roleAuditInfo(info models.Role) gin.H {
+	return gin.H{
+		"name":        info.Name,
+		"description": info.Description,
+		"permissions": info.Permissions,
+	}
+}
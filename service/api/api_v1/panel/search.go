@@ -0,0 +1,208 @@
+package panel
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sun-panel/api/api_v1/common/apiData/panelApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/networkZone"
+	"sun-panel/lib/search"
+	"sun-panel/lib/team"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	SEARCH_DEFAULT_LIMIT = 20
+
+	SEARCH_RESULT_TYPE_ITEM  = "item"
+	SEARCH_RESULT_TYPE_GROUP = "group"
+)
+
+// 搜索字段的权重
+const (
+	searchWeightTitle       = 10
+	searchWeightHost        = 6
+	searchWeightTag         = 5
+	searchWeightDescription = 3
+)
+
+// 服务端搜索图标和分组，范围与公开模式下可查看的分组一致
+type SearchApi struct{}
+
+type searchResult struct {
+	Type       string                `json:"type"` // item.图标 group.分组
+	Score      int                   `json:"score"`
+	Item       *models.ItemIcon      `json:"item,omitempty"`
+	Group      *models.ItemIconGroup `json:"group,omitempty"`
+	GroupId    uint                  `json:"groupId"`
+	GroupTitle string                `json:"groupTitle"`
+	Matches    []search.FieldMatch   `json:"matches"`
+}
+
+func (a SearchApi) Query(c *gin.Context) {
+	req := panelApiStructs.SearchQueryReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = SEARCH_DEFAULT_LIMIT
+	}
+
+	results := []searchResult{}
+	terms := search.Terms(req.Keyword)
+	if len(terms) == 0 {
+		apiReturn.SuccessListData(c, results, 0)
+		return
+	}
+
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	access, err := team.GetAccess(userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	visibleGroups, err := getVisibleGroups(c, access)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if len(visibleGroups) == 0 {
+		apiReturn.SuccessListData(c, results, 0)
+		return
+	}
+
+	groupIds := []uint{}
+	groups := []models.ItemIconGroup{}
+	for id, v := range visibleGroups {
+		groupIds = append(groupIds, id)
+		groups = append(groups, v)
+	}
+	items := []models.ItemIcon{}
+	if err := global.Db.Order("sort ,created_at").Find(&items, "item_icon_group_id in ?", groupIds).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	items = filterVisibleItemIcons(c, access, visibleGroups, items)
+
+	// 图标上当前用户的标签名称
+	if err := attachItemIconTags(userInfo.ID, items); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	mTag := models.Tag{}
+	tags, err := mTag.GetListByUserId(global.Db, userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	tagNames := map[uint]string{}
+	for _, v := range tags {
+		tagNames[v.ID] = v.Name
+	}
+
+	// 公开模式下外部访问者不能搜索内网地址
+	zone, hideInternal, err := visitorZone(c)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	groupTitles := map[uint]string{}
+	for k, v := range groups {
+		groupTitles[v.ID] = v.Title
+		score, matches, ok := search.Score([]search.Field{
+			{Name: "title", Text: v.Title, Weight: searchWeightTitle},
+			{Name: "description", Text: v.Description, Weight: searchWeightDescription},
+		}, terms)
+		if !ok {
+			continue
+		}
+		groups[k].Ownership = models.ITEM_ICON_GROUP_OWNERSHIP_SELF
+		if v.TeamId != 0 {
+			groups[k].Ownership = models.ITEM_ICON_GROUP_OWNERSHIP_TEAM
+		} else if access.IsPublicUserGroup(v) {
+			groups[k].Ownership = models.ITEM_ICON_GROUP_OWNERSHIP_PUBLIC
+		}
+		groups[k].Editable = access.CanEdit(v)
+		results = append(results, searchResult{
+			Type:       SEARCH_RESULT_TYPE_GROUP,
+			Score:      score,
+			Group:      &groups[k],
+			GroupId:    v.ID,
+			GroupTitle: v.Title,
+			Matches:    matches,
+		})
+	}
+
+	matchedItems := []models.ItemIcon{}
+	itemResults := []searchResult{}
+	for _, v := range items {
+		fields := []search.Field{
+			{Name: "title", Text: v.Title, Weight: searchWeightTitle},
+			{Name: "url", Text: search.UrlHost(v.Url), Weight: searchWeightHost},
+		}
+		if !hideInternal {
+			fields = append(fields, search.Field{Name: "lanUrl", Text: search.UrlHost(v.LanUrl), Weight: searchWeightHost})
+		}
+		fields = append(fields, search.Field{Name: "description", Text: v.Description, Weight: searchWeightDescription})
+		for _, tagId := range v.TagIds {
+			fields = append(fields, search.Field{Name: "tag", Text: tagNames[tagId], Weight: searchWeightTag})
+		}
+
+		score, matches, ok := search.Score(fields, terms)
+		if !ok {
+			continue
+		}
+		json.Unmarshal([]byte(v.IconJson), &v.Icon)
+		matchedItems = append(matchedItems, v)
+		itemResults = append(itemResults, searchResult{
+			Type:       SEARCH_RESULT_TYPE_ITEM,
+			Score:      score,
+			GroupId:    uint(v.ItemIconGroupId),
+			GroupTitle: groupTitles[uint(v.ItemIconGroupId)],
+			Matches:    matches,
+		})
+	}
+
+	// 根据访问者所在的网络区域选择地址
+	if err := networkZone.Apply(matchedItems, zone, hideInternal); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	for k := range itemResults {
+		itemResults[k].Item = &matchedItems[k]
+	}
+	results = append(results, itemResults...)
+
+	// 得分高的在前，得分相同时按标题排序
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return strings.ToLower(results[i].title()) < strings.ToLower(results[j].title())
+	})
+	count := int64(len(results))
+	if len(results) > req.Limit {
+		results = results[:req.Limit]
+	}
+
+	apiReturn.SuccessListData(c, results, count)
+}
+
+func (r searchResult) title() string {
+	if r.Item != nil {
+		return r.Item.Title
+	}
+	return r.Group.Title
+}
@@ -0,0 +1,75 @@
+package panel
+
+import (
+	"sun-panel/api/api_v1/common/apiData/adminApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/cmn/systemSetting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// 系统设置(管理员)
+type SystemSettingApi struct{}
+
+// 系统邮箱，不返回密码
+func (a SystemSettingApi) GetEmail(c *gin.Context) {
+	emailSetting := systemSetting.Email{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_EMAIL, &emailSetting)
+	emailSetting.Password = ""
+	apiReturn.SuccessData(c, emailSetting)
+}
+
+func (a SystemSettingApi) SetEmail(c *gin.Context) {
+	req := adminApiStructs.SystemSettingEmailReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	emailSetting := systemSetting.Email{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_EMAIL, &emailSetting)
+	emailSetting.Host = req.Host
+	emailSetting.Port = req.Port
+	emailSetting.Mail = req.Mail
+	if req.Password != "" {
+		emailSetting.Password = req.Password
+	}
+
+	if err := global.SystemSetting.Set(systemSetting.SYSTEM_EMAIL, emailSetting); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.Success(c)
+}
+
+// 应用设置(注册、登录、站点地址)
+func (a SystemSettingApi) GetApplicationSetting(c *gin.Context) {
+	setting := systemSetting.ApplicationSetting{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_APPLICATION, &setting)
+	apiReturn.SuccessData(c, setting)
+}
+
+func (a SystemSettingApi) SetApplicationSetting(c *gin.Context) {
+	req := systemSetting.ApplicationSetting{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	if err := global.SystemSetting.Set(systemSetting.SYSTEM_APPLICATION, req); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.Success(c)
+}
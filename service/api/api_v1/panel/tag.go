@@ -0,0 +1,163 @@
+package panel
+
+import (
+	"fmt"
+	"strings"
+	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
+	"sun-panel/api/api_v1/common/apiData/panelApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/audit"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// 标签，每个用户单独管理
+type TagApi struct{}
+
+func (a TagApi) GetList(c *gin.Context) {
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	mTag := models.Tag{}
+	list, err := mTag.GetListByUserId(global.Db, userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.SuccessListData(c, list, int64(len(list)))
+}
+
+func (a TagApi) Edit(c *gin.Context) {
+	req := panelApiStructs.TagEditReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+	userInfo, _ := base.GetCurrentUserInfo(c)
+
+	info := models.Tag{
+		UserId: userInfo.ID,
+		Name:   strings.TrimSpace(req.Name),
+		Color:  strings.ToLower(req.Color),
+		Sort:   req.Sort,
+	}
+	if req.Id == 0 {
+		if err := global.Db.Create(&info).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		audit.Record(c, models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_TAG, info.ID, nil, info)
+		apiReturn.SuccessData(c, info)
+		return
+	}
+
+	before := models.Tag{}
+	if err := global.Db.First(&before, "id=? AND user_id=?", req.Id, userInfo.ID).Error; err == gorm.ErrRecordNotFound {
+		apiReturn.ErrorDataNotFound(c)
+		return
+	} else if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if err := global.Db.Model(&models.Tag{}).Where("id=?", before.ID).Select("Name", "Color", "Sort").Updates(&info).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	after := models.Tag{}
+	global.Db.First(&after, "id=?", req.Id)
+	audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_TAG, after.ID, before, after)
+	apiReturn.SuccessData(c, after)
+}
+
+func (a TagApi) Deletes(c *gin.Context) {
+	req := commonApiStructs.RequestDeleteIds[uint]{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	userInfo, _ := base.GetCurrentUserInfo(c)
+
+	deleteTags := []models.Tag{}
+	if err := global.Db.Find(&deleteTags, "id in ? AND user_id=?", req.Ids, userInfo.ID).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	mTag := models.Tag{}
+	if err := mTag.DeleteByIds(global.Db, userInfo.ID, req.Ids); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	for _, v := range deleteTags {
+		audit.Record(c, models.AUDIT_ACTION_DELETE, models.AUDIT_TARGET_TAG, v.ID, v, nil)
+	}
+	apiReturn.Success(c)
+}
+
+// 检查标签是否属于该用户，返回去重后的标签ID
+func checkTagIds(userId uint, tagIds []uint) ([]uint, error) {
+	if tagIds == nil {
+		return nil, nil
+	}
+	ids := []uint{}
+	used := map[uint]bool{}
+	for _, v := range tagIds {
+		if !used[v] {
+			used[v] = true
+			ids = append(ids, v)
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	var count int64
+	if err := global.Db.Model(&models.Tag{}).Where("id in ? AND user_id=?", ids, userId).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if int(count) != len(ids) {
+		return nil, fmt.Errorf("invalid tag")
+	}
+	return ids, nil
+}
+
+// 保存图标上当前用户的标签，未提交(nil)时不修改
+func saveItemIconTags(c *gin.Context, userId uint, item models.ItemIcon) bool {
+	if item.TagIds == nil {
+		return true
+	}
+	mItemIconTag := models.ItemIconTag{}
+	if err := mItemIconTag.Save(global.Db, userId, item.ID, item.TagIds); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return false
+	}
+	return true
+}
+
+// 填充图标上当前用户的标签
+func attachItemIconTags(userId uint, items []models.ItemIcon) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := []uint{}
+	for _, v := range items {
+		ids = append(ids, v.ID)
+	}
+	mItemIconTag := models.ItemIconTag{}
+	tagIds, err := mItemIconTag.GetTagIdsByItemIconIds(global.Db, userId, ids)
+	if err != nil {
+		return err
+	}
+	for k, v := range items {
+		items[k].TagIds = tagIds[v.ID]
+		if items[k].TagIds == nil {
+			items[k].TagIds = []uint{}
+		}
+	}
+	return nil
+}
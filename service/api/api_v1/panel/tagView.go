@@ -0,0 +1,207 @@
+package panel
+
+import (
+	"encoding/json"
+	"strings"
+	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
+	"sun-panel/api/api_v1/common/apiData/panelApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/audit"
+	"sun-panel/lib/team"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// 标签视图，按标签组合图标的虚拟分组
+type TagViewApi struct{}
+
+func (a TagViewApi) GetList(c *gin.Context) {
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	list := []models.TagView{}
+	if err := global.Db.Order("sort, id").Find(&list, "user_id=?", userInfo.ID).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if err := parseTagViewTagIds(userInfo.ID, list); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.SuccessListData(c, list, int64(len(list)))
+}
+
+func (a TagViewApi) Edit(c *gin.Context) {
+	req := panelApiStructs.TagViewEditReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+	userInfo, _ := base.GetCurrentUserInfo(c)
+
+	tagIds, err := checkTagIds(userInfo.ID, req.TagIds)
+	if err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	info := models.TagView{
+		UserId:   userInfo.ID,
+		Icon:     req.Icon,
+		Title:    strings.TrimSpace(req.Title),
+		TagIds:   tagIds,
+		MatchAll: req.MatchAll,
+		Sort:     req.Sort,
+	}
+	// json转字符串
+	if j, err := json.Marshal(info.TagIds); err == nil {
+		info.TagIdsJson = string(j)
+	}
+
+	if req.Id == 0 {
+		if err := global.Db.Create(&info).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		audit.Record(c, models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_TAG_VIEW, info.ID, nil, info)
+		apiReturn.SuccessData(c, info)
+		return
+	}
+
+	before := models.TagView{}
+	if err := global.Db.First(&before, "id=? AND user_id=?", req.Id, userInfo.ID).Error; err == gorm.ErrRecordNotFound {
+		apiReturn.ErrorDataNotFound(c)
+		return
+	} else if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	json.Unmarshal([]byte(before.TagIdsJson), &before.TagIds)
+	if err := global.Db.Model(&models.TagView{}).Where("id=?", before.ID).Select("Icon", "Title", "TagIdsJson", "MatchAll", "Sort").Updates(&info).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	after := models.TagView{}
+	global.Db.First(&after, "id=?", req.Id)
+	json.Unmarshal([]byte(after.TagIdsJson), &after.TagIds)
+	audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_TAG_VIEW, after.ID, before, after)
+	apiReturn.SuccessData(c, after)
+}
+
+func (a TagViewApi) Deletes(c *gin.Context) {
+	req := commonApiStructs.RequestDeleteIds[uint]{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	userInfo, _ := base.GetCurrentUserInfo(c)
+
+	deleteViews := []models.TagView{}
+	if err := global.Db.Find(&deleteViews, "id in ? AND user_id=?", req.Ids, userInfo.ID).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if err := global.Db.Delete(&models.TagView{}, "id in ? AND user_id=?", req.Ids, userInfo.ID).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	for _, v := range deleteViews {
+		json.Unmarshal([]byte(v.TagIdsJson), &v.TagIds)
+		audit.Record(c, models.AUDIT_ACTION_DELETE, models.AUDIT_TARGET_TAG_VIEW, v.ID, v, nil)
+	}
+	apiReturn.Success(c)
+}
+
+// 获取视图中的图标，只包含可以查看的分组中的图标
+func (a TagViewApi) GetItems(c *gin.Context) {
+	req := panelApiStructs.TagViewGetItemsReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+	userInfo, _ := base.GetCurrentUserInfo(c)
+
+	views := []models.TagView{}
+	if err := global.Db.Limit(1).Find(&views, "id=? AND user_id=?", req.Id, userInfo.ID).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	} else if len(views) == 0 {
+		apiReturn.ErrorDataNotFound(c)
+		return
+	}
+	if err := parseTagViewTagIds(userInfo.ID, views); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	itemIcons := []models.ItemIcon{}
+	mItemIconTag := models.ItemIconTag{}
+	itemIconIds, err := mItemIconTag.GetItemIconIds(global.Db, views[0].TagIds, views[0].MatchAll)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	access, err := team.GetAccess(userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	visibleGroups, err := getVisibleGroups(c, access)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if len(itemIconIds) == 0 || len(visibleGroups) == 0 {
+		apiReturn.SuccessListData(c, itemIcons, 0)
+		return
+	}
+
+	groupIds := []uint{}
+	for id := range visibleGroups {
+		groupIds = append(groupIds, id)
+	}
+	if err := global.Db.Order("item_icon_group_id, sort, created_at").Find(&itemIcons, "id in ? AND item_icon_group_id in ?", itemIconIds, groupIds).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	itemIcons = filterVisibleItemIcons(c, access, visibleGroups, itemIcons)
+	if !fillItemIconList(c, userInfo.ID, itemIcons) {
+		return
+	}
+
+	apiReturn.SuccessListData(c, itemIcons, 0)
+}
+
+// 解析视图的标签，已删除的标签不再返回
+func parseTagViewTagIds(userId uint, views []models.TagView) error {
+	mTag := models.Tag{}
+	tags, err := mTag.GetListByUserId(global.Db, userId)
+	if err != nil {
+		return err
+	}
+	exists := map[uint]bool{}
+	for _, v := range tags {
+		exists[v.ID] = true
+	}
+	for k, v := range views {
+		tagIds := []uint{}
+		json.Unmarshal([]byte(v.TagIdsJson), &tagIds)
+		views[k].TagIds = []uint{}
+		for _, id := range tagIds {
+			if exists[id] {
+				views[k].TagIds = append(views[k].TagIds, id)
+			}
+		}
+	}
+	return nil
+}
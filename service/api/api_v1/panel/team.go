@@ -0,0 +1,287 @@
+package panel
+
+import (
+	"strings"
+	"sun-panel/api/api_v1/common/apiData/adminApiStructs"
+	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/audit"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// 团队(管理员管理团队和成员，成员在自己的面板中查看或维护团队分组)
+type TeamApi struct{}
+
+func (a TeamApi) GetList(c *gin.Context) {
+	list := []models.Team{}
+	if err := global.Db.Order("id").Find(&list).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	resList := []adminApiStructs.TeamInfo{}
+	for _, v := range list {
+		var count int64
+		if err := global.Db.Model(&models.TeamMember{}).Where("team_id=?", v.ID).Count(&count).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		resList = append(resList, adminApiStructs.TeamInfo{
+			Team:        v,
+			MemberCount: count,
+		})
+	}
+	apiReturn.SuccessListData(c, resList, int64(len(resList)))
+}
+
+func (a TeamApi) Edit(c *gin.Context) {
+	req := adminApiStructs.TeamEditReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	info := models.Team{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+	}
+	if req.Id == 0 {
+		if err := global.Db.Create(&info).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		audit.Record(c, models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_TEAM, info.ID, nil, info)
+	} else {
+		if !checkTeamExist(c, req.Id) {
+			return
+		}
+		mTeam := models.Team{}
+		before, _ := mTeam.GetById(global.Db, req.Id)
+		if err := global.Db.Model(&models.Team{}).Where("id=?", req.Id).Select("Name", "Description").Updates(&info).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		info.ID = req.Id
+		audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_TEAM, info.ID, before, info)
+	}
+	apiReturn.SuccessData(c, info)
+}
+
+// 删除团队，同时删除团队的分组和图标
+func (a TeamApi) Deletes(c *gin.Context) {
+	req := commonApiStructs.RequestDeleteIds[uint]{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+
+	deleteTeams := []models.Team{}
+	if err := global.Db.Find(&deleteTeams, "id in ?", req.Ids).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	txErr := global.Db.Transaction(func(tx *gorm.DB) error {
+		mTeam := models.Team{}
+		return mTeam.DeleteByIds(tx, req.Ids)
+	})
+	if txErr != nil {
+		apiReturn.ErrorDatabase(c, txErr.Error())
+		return
+	}
+	for _, v := range deleteTeams {
+		audit.Record(c, models.AUDIT_ACTION_DELETE, models.AUDIT_TARGET_TEAM, v.ID, v, nil)
+	}
+	apiReturn.Success(c)
+}
+
+func (a TeamApi) GetMemberList(c *gin.Context) {
+	req := adminApiStructs.TeamGetMemberListReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	mMember := models.TeamMember{}
+	members, err := mMember.GetListByTeamId(global.Db, req.TeamId)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	resList := []adminApiStructs.TeamMemberInfo{}
+	for _, v := range members {
+		user := models.User{}
+		if err := global.Db.Omit("Password").Limit(1).Find(&user, "id=?", v.UserId).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		resList = append(resList, adminApiStructs.TeamMemberInfo{
+			UserId:   v.UserId,
+			Username: user.Username,
+			Name:     user.Name,
+			Role:     v.Role,
+		})
+	}
+	apiReturn.SuccessListData(c, resList, int64(len(resList)))
+}
+
+// 添加成员，已是成员的修改角色
+func (a TeamApi) SetMember(c *gin.Context) {
+	req := adminApiStructs.TeamSetMemberReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+	if req.Role != models.TEAM_MEMBER_ROLE_VIEWER && req.Role != models.TEAM_MEMBER_ROLE_EDITOR {
+		apiReturn.ErrorByCode(c, 1061)
+		return
+	}
+	if !checkTeamExist(c, req.TeamId) {
+		return
+	}
+
+	var count int64
+	if err := global.Db.Model(&models.User{}).Where("id in ?", req.UserIds).Count(&count).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	} else if count != int64(len(req.UserIds)) {
+		apiReturn.ErrorDataNotFound(c)
+		return
+	}
+
+	// 修改前后的成员，事务提交后记录审计日志
+	var changedBefore, changedAfter []models.TeamMember
+	txErr := global.Db.Transaction(func(tx *gorm.DB) error {
+		for _, userId := range req.UserIds {
+			member := models.TeamMember{}
+			if err := tx.Limit(1).Find(&member, "team_id=? AND user_id=?", req.TeamId, userId).Error; err != nil {
+				return err
+			}
+			if member.ID != 0 {
+				if member.Role == req.Role {
+					continue
+				}
+				if err := tx.Model(&models.TeamMember{}).Where("id=?", member.ID).Update("role", req.Role).Error; err != nil {
+					return err
+				}
+				changedBefore = append(changedBefore, member)
+				member.Role = req.Role
+				changedAfter = append(changedAfter, member)
+				continue
+			}
+			member = models.TeamMember{
+				TeamId: req.TeamId,
+				UserId: userId,
+				Role:   req.Role,
+			}
+			if err := tx.Create(&member).Error; err != nil {
+				return err
+			}
+			changedBefore = append(changedBefore, models.TeamMember{})
+			changedAfter = append(changedAfter, member)
+		}
+		return nil
+	})
+	if txErr != nil {
+		apiReturn.ErrorDatabase(c, txErr.Error())
+		return
+	}
+	for i, v := range changedAfter {
+		if changedBefore[i].ID == 0 {
+			audit.Record(c, models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_TEAM_MEMBER, v.ID, nil, v)
+		} else {
+			audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_TEAM_MEMBER, v.ID, changedBefore[i], v)
+		}
+	}
+	apiReturn.Success(c)
+}
+
+func (a TeamApi) RemoveMembers(c *gin.Context) {
+	req := adminApiStructs.TeamRemoveMembersReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	members := []models.TeamMember{}
+	if err := global.Db.Find(&members, "team_id=? AND user_id in ?", req.TeamId, req.UserIds).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if err := global.Db.Unscoped().Delete(&models.TeamMember{}, "team_id=? AND user_id in ?", req.TeamId, req.UserIds).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	for _, v := range members {
+		audit.Record(c, models.AUDIT_ACTION_DELETE, models.AUDIT_TARGET_TEAM_MEMBER, v.ID, v, nil)
+	}
+	apiReturn.Success(c)
+}
+
+// 当前用户加入的团队，用于在团队中创建分组
+func (a TeamApi) GetMyList(c *gin.Context) {
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	mMember := models.TeamMember{}
+	roles, err := mMember.GetRolesByUserId(global.Db, userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	resList := []adminApiStructs.TeamMyInfo{}
+	if len(roles) > 0 {
+		teamIds := []uint{}
+		for teamId := range roles {
+			teamIds = append(teamIds, teamId)
+		}
+		teams := []models.Team{}
+		if err := global.Db.Order("id").Find(&teams, "id in ?", teamIds).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+		for _, v := range teams {
+			resList = append(resList, adminApiStructs.TeamMyInfo{
+				Id:   v.ID,
+				Name: v.Name,
+				Role: roles[v.ID],
+			})
+		}
+	}
+	apiReturn.SuccessListData(c, resList, int64(len(resList)))
+}
+
+// 验证团队是否存在，不存在时返回错误
+func checkTeamExist(c *gin.Context, teamId uint) bool {
+	mTeam := models.Team{}
+	if _, err := mTeam.GetById(global.Db, teamId); err == gorm.ErrRecordNotFound {
+		apiReturn.ErrorByCode(c, 1060)
+		return false
+	} else if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return false
+	}
+	return true
+}
@@ -4,12 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sun-panel/api/api_v1/common/apiData/adminApiStructs"
 	"sun-panel/api/api_v1/common/apiReturn"
 	"sun-panel/api/api_v1/common/base"
 	"sun-panel/global"
+	"sun-panel/lib/audit"
 	"sun-panel/lib/cmn"
 	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/password"
+	"sun-panel/lib/permission"
+	"sun-panel/lib/session"
 	"sun-panel/models"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -24,6 +30,8 @@ var (
 	ErrUsersApiAtLeastKeepOne = errors.New("at least keep one")
 )
 
+const IMPERSONATE_DEFAULT_MINUTES = 30 // 模拟登录默认有效时长(分钟)
+
 func (a UsersApi) Create(c *gin.Context) {
 	param := models.User{}
 	if err := c.ShouldBindBodyWith(&param, binding.JSON); err != nil {
@@ -42,13 +50,31 @@ func (a UsersApi) Create(c *gin.Context) {
 		return
 	}
 
+	if !checkRoleExist(c, param.Role) {
+		return
+	}
+	currentUser, _ := base.GetCurrentUserInfo(c)
+	if !permission.CanAssign(currentUser.Role, param.Role) {
+		apiReturn.ErrorByCode(c, 1054)
+		return
+	}
+
+	passwordHash, err := password.Hash(param.Password)
+	if err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
+
 	mUser := models.User{
 		Username:  strings.TrimSpace(param.Username),
-		Password:  cmn.PasswordEncryption(param.Password),
+		Password:  passwordHash,
 		Name:      param.Name,
 		HeadImage: param.HeadImage,
 		Status:    1,
 		Role:      param.Role,
+
+		ExpireAt:           param.ExpireAt,
+		MustChangePassword: param.MustChangePassword,
 		// Mail:      param.Username, 不再保存邮箱账号字段
 	}
 
@@ -65,6 +91,7 @@ func (a UsersApi) Create(c *gin.Context) {
 		apiReturn.ErrorDatabase(c, err.Error())
 		return
 	}
+	audit.Record(c, models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_USER, userInfo.ID, nil, userInfo)
 
 	apiReturn.SuccessData(c, gin.H{"userId": userInfo.ID})
 }
@@ -80,18 +107,33 @@ func (a UsersApi) Deletes(c *gin.Context) {
 		return
 	}
 
+	if !base.CheckCanManageUsers(c, param.UserIds) {
+		return
+	}
+	deleteUsers := []models.User{}
+	if err := global.Db.Find(&deleteUsers, "id in ?", param.UserIds).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
 	txErr := global.Db.Transaction(func(tx *gorm.DB) error {
 		mitemIconGroup := models.ItemIconGroup{}
 
 		for _, v := range param.UserIds {
-			// 删除图标
-			if err := tx.Delete(&models.ItemIcon{}, "user_id=?", v).Error; err != nil {
+			// 删除图标，团队分组中的图标保留
+			teamGroupIds := tx.Model(&models.ItemIconGroup{}).Select("id").Where("team_id<>0")
+			if err := tx.Delete(&models.ItemIcon{}, "user_id=? AND item_icon_group_id not in (?)", v, teamGroupIds).Error; err != nil {
 				return err
 			}
 			// 删除分组
 			if err := mitemIconGroup.DeleteByUserId(tx, v); err != nil {
 				return err
 			}
+			// 删除标签和标签视图
+			mTag := models.Tag{}
+			if err := mTag.DeleteByUserId(tx, v); err != nil {
+				return err
+			}
 			// 删除模块配置
 			if err := tx.Delete(&models.ModuleConfig{}, "user_id=?", v).Error; err != nil {
 				return err
@@ -100,6 +142,28 @@ func (a UsersApi) Deletes(c *gin.Context) {
 			if err := tx.Delete(&models.ModuleConfig{}, "user_id=?", v).Error; err != nil {
 				return err
 			}
+			// 删除二次验证
+			if err := tx.Unscoped().Delete(&models.UserTwoFactor{}, "user_id=?", v).Error; err != nil {
+				return err
+			}
+			// 退出全部团队
+			mTeamMember := models.TeamMember{}
+			if err := mTeamMember.DeleteByUserId(tx, v); err != nil {
+				return err
+			}
+			// 删除个人访问令牌
+			if err := tx.Unscoped().Delete(&models.UserApiToken{}, "user_id=?", v).Error; err != nil {
+				return err
+			}
+			// 删除绑定的OIDC身份
+			mOidcIdentity := models.UserOidcIdentity{}
+			if err := mOidcIdentity.DeleteByUserId(tx, v); err != nil {
+				return err
+			}
+			// 删除通行密钥
+			if err := tx.Unscoped().Delete(&models.UserPasskey{}, "user_id=?", v).Error; err != nil {
+				return err
+			}
 			// // 删除文件记录（不删除资源文件）
 			// if err := tx.Delete(&models.File{}, "user_id=?", v).Error; err != nil {
 			// 	return err
@@ -113,7 +177,7 @@ func (a UsersApi) Deletes(c *gin.Context) {
 
 		// 验证是否还存在管理员
 		var count int64
-		if err := tx.Model(&models.User{}).Where("role=?", 1).Count(&count).Error; err != nil {
+		if err := tx.Model(&models.User{}).Where("role=?", models.ROLE_ADMIN).Count(&count).Error; err != nil {
 			return err
 		} else if count == 0 {
 			return ErrUsersApiAtLeastKeepOne
@@ -129,6 +193,18 @@ func (a UsersApi) Deletes(c *gin.Context) {
 		return
 	}
 
+	// 退出被删除用户的全部会话
+	if err := session.RevokeByUserIds(param.UserIds, 0); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	for _, v := range param.UserIds {
+		session.ClearUserCache(v)
+	}
+	for _, v := range deleteUsers {
+		audit.Record(c, models.AUDIT_ACTION_DELETE, models.AUDIT_TARGET_USER, v.ID, v, nil)
+	}
+
 	apiReturn.Success(c)
 }
 
@@ -157,40 +233,305 @@ func (a UsersApi) Update(c *gin.Context) {
 		return
 	}
 
-	allowField := []string{"Username", "Name", "Mail", "Token", "Role"}
+	if !checkRoleExist(c, param.Role) {
+		return
+	}
+
+	before := models.User{}
+	if err := global.Db.First(&before, "id=?", param.ID).Error; err == gorm.ErrRecordNotFound {
+		apiReturn.ErrorDataNotFound(c)
+		return
+	} else if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	// 不能分配超过自己权限的角色，也不能修改权限超过自己的用户(如修改管理员的密码)
+	currentUser, _ := base.GetCurrentUserInfo(c)
+	if !permission.CanAssign(currentUser.Role, param.Role) || !permission.CanAssign(currentUser.Role, before.Role) {
+		apiReturn.ErrorByCode(c, 1054)
+		return
+	}
+
+	// 取消管理员角色时，至少保留一个管理员
+	if param.Role != models.ROLE_ADMIN {
+		var count int64
+		if err := global.Db.Model(&models.User{}).Where("role=? AND id<>?", models.ROLE_ADMIN, param.ID).Count(&count).Error; err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		} else if count == 0 {
+			apiReturn.ErrorByCode(c, 1201)
+			return
+		}
+	}
+
+	allowField := []string{"Username", "Name", "Mail", "Role"}
 
 	// 密码不为默认“-”空，修改密码
 	if param.Password != "-" {
-		param.Password = cmn.PasswordEncryption(param.Password)
+		passwordHash, err := password.Hash(param.Password)
+		if err != nil {
+			apiReturn.Error(c, err.Error())
+			return
+		}
+		param.Password = passwordHash
 		allowField = append(allowField, "Password")
 	}
 
 	mUser := models.User{}
 
-	userInfo := models.User{}
 	// 验证账号是否存在
 	if user, err := mUser.CheckUsernameExist(param.Username); err != nil {
-		userInfo = user
 		if user.ID != param.ID {
 			apiReturn.ErrorByCode(c, 1006)
 			// apiReturn.Error(c, global.Lang.Get("register.mail_exist"))
 			return
 		}
-	} else {
-		userInfo = user
 	}
 
-	param.Token = "" // 修改资料就重置token
 	if err := global.Db.Select(allowField).Where("id=?", param.ID).Updates(&param).Error; err != nil {
 		apiReturn.ErrorDatabase(c, err.Error())
 		return
 	}
-	// global.Logger.Debug("修改资料清空token", userInfo.Token)
-	global.UserToken.Delete(userInfo.Token) // 更新用户信息
+	after := models.User{}
+	global.Db.First(&after, "id=?", param.ID)
+	audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_USER, param.ID, before, after)
+	// 修改密码后退出该用户的全部会话
+	if cmn.InSlice(allowField, "Password") {
+		if err := session.RevokeByUserIds([]uint{param.ID}, 0); err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+	}
+	session.ClearUserCache(param.ID) // 更新用户信息
 	// 返回token等基本信息
 	apiReturn.SuccessData(c, param)
 }
 
+// 重置用户的二次验证，用户下次登录时可重新绑定
+func (a UsersApi) ResetTwoFactor(c *gin.Context) {
+	type UserIds struct {
+		UserIds []uint `json:"userIds"`
+	}
+	param := UserIds{}
+	if err := c.ShouldBindBodyWith(&param, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if !base.CheckCanManageUsers(c, param.UserIds) {
+		return
+	}
+
+	// 已绑定二次验证的用户，用于记录审计日志
+	resetIds := []uint{}
+	if err := global.Db.Model(&models.UserTwoFactor{}).Where("user_id in ?", param.UserIds).Pluck("user_id", &resetIds).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	mTwoFactor := models.UserTwoFactor{}
+	if err := mTwoFactor.DeleteByUserIds(global.Db, param.UserIds); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	for _, v := range resetIds {
+		audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_USER, v, gin.H{"twoFactor": true}, gin.H{"twoFactor": false})
+	}
+	apiReturn.Success(c)
+}
+
+// 审核通过自助注册的账号(未激活 -> 启用)
+func (a UsersApi) Approve(c *gin.Context) {
+	type UserIds struct {
+		UserIds []uint `json:"userIds"`
+	}
+	param := UserIds{}
+	if err := c.ShouldBindBodyWith(&param, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if !base.CheckCanManageUsers(c, param.UserIds) {
+		return
+	}
+
+	approveIds := []uint{}
+	if err := global.Db.Model(&models.User{}).Where("id in ? AND status=?", param.UserIds, 3).Pluck("id", &approveIds).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if err := global.Db.Model(&models.User{}).Where("id in ? AND status=?", param.UserIds, 3).Update("status", 1).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	for _, v := range approveIds {
+		audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_USER, v, gin.H{"status": 3}, gin.H{"status": 1})
+	}
+	for _, v := range param.UserIds {
+		session.ClearUserCache(v)
+	}
+	apiReturn.Success(c)
+}
+
+// 批量启用或停用账号，停用后立即清除用户和会话缓存，使其无法继续访问
+func (a UsersApi) SetStatus(c *gin.Context) {
+	req := adminApiStructs.UsersSetStatusReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	// 停用时至少保留一个启用的管理员
+	a.bulkUpdate(c, req.UserIds, map[string]interface{}{"status": req.Status}, req.Status == models.USER_STATUS_DISABLE)
+}
+
+// 批量设置账号到期时间，到期后无法登录，已登录的会话失效
+func (a UsersApi) SetExpireAt(c *gin.Context) {
+	req := adminApiStructs.UsersSetExpireAtReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	// 到期时间已过时等同停用，至少保留一个可用的管理员
+	disable := req.ExpireAt != nil && !req.ExpireAt.After(time.Now())
+	a.bulkUpdate(c, req.UserIds, map[string]interface{}{"expire_at": req.ExpireAt}, disable)
+}
+
+// 批量要求下次登录后修改密码
+func (a UsersApi) SetMustChangePassword(c *gin.Context) {
+	req := adminApiStructs.UsersSetMustChangePasswordReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	a.bulkUpdate(c, req.UserIds, map[string]interface{}{"must_change_password": req.MustChangePassword}, false)
+}
+
+// 批量修改账号字段，清除缓存并记录审计日志
+// 任一账号的角色超过当前用户的权限时拒绝整个请求，disable为true时至少保留一个可用的管理员
+func (a UsersApi) bulkUpdate(c *gin.Context, userIds []uint, updates map[string]interface{}, disable bool) {
+	if !base.CheckCanManageUsers(c, userIds) {
+		return
+	}
+	if disable && !keepOneActiveAdmin(c, userIds) {
+		return
+	}
+	beforeList := []models.User{}
+	if err := global.Db.Find(&beforeList, "id in ?", userIds).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if err := global.Db.Model(&models.User{}).Where("id in ?", userIds).Updates(updates).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if err := session.ClearUserAndSessionCache(userIds); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	afterList := []models.User{}
+	if err := global.Db.Find(&afterList, "id in ?", userIds).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	for i := range afterList {
+		for _, before := range beforeList {
+			if before.ID == afterList[i].ID {
+				audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_USER, before.ID, before, afterList[i])
+			}
+		}
+	}
+	apiReturn.Success(c)
+}
+
+// 停用账号前验证除这些账号外至少还有一个可用的管理员
+func keepOneActiveAdmin(c *gin.Context, userIds []uint) bool {
+	mUser := models.User{}
+	if count, err := mUser.CountActiveAdmins(global.Db, userIds); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return false
+	} else if count == 0 {
+		apiReturn.ErrorByCode(c, 1201)
+		return false
+	}
+	return true
+}
+
+// 以其他用户身份查看(模拟登录)，生成有时效的只读会话，只能访问读取面板的接口
+func (a UsersApi) Impersonate(c *gin.Context) {
+	req := adminApiStructs.UsersImpersonateReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	// 只有管理员角色可以模拟登录
+	currentUser, _ := base.GetCurrentUserInfo(c)
+	if currentUser.Role != models.ROLE_ADMIN {
+		apiReturn.ErrorByCode(c, 1005)
+		return
+	}
+	if req.UserId == currentUser.ID {
+		apiReturn.ErrorByCode(c, 1091)
+		return
+	}
+	mUser := models.User{}
+	userInfo, err := mUser.GetUserInfoByUid(req.UserId)
+	if err != nil {
+		apiReturn.ErrorDataNotFound(c)
+		return
+	}
+	// 不能查看权限超过自己的用户
+	if !permission.HasAll(currentUser.Role, permission.List(userInfo.Role)) {
+		apiReturn.ErrorByCode(c, 1005)
+		return
+	}
+	if code := base.UserStatusErrorCode(userInfo); code != 0 {
+		apiReturn.ErrorByCode(c, code)
+		return
+	}
+
+	if req.Minutes == 0 {
+		req.Minutes = IMPERSONATE_DEFAULT_MINUTES
+	}
+	info, token, err := session.CreateImpersonation(userInfo.ID, currentUser.ID, time.Duration(req.Minutes)*time.Minute, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	audit.Record(c, models.AUDIT_ACTION_IMPERSONATE, models.AUDIT_TARGET_USER, userInfo.ID, nil, gin.H{
+		"sessionId": info.ID,
+		"username":  userInfo.Username,
+		"expiresAt": info.ExpiresAt,
+	})
+
+	apiReturn.SuccessData(c, gin.H{
+		"token":     token,
+		"expiresAt": info.ExpiresAt,
+		"user": gin.H{
+			"id":       userInfo.ID,
+			"username": userInfo.Username,
+			"name":     userInfo.Name,
+		},
+	})
+}
+
 func (a UsersApi) GetList(c *gin.Context) {
 
 	type ParamsStruct struct {
@@ -258,10 +599,14 @@ func (a UsersApi) SetPublicVisitUser(c *gin.Context) {
 		}
 	}
 
+	var beforeUserId *uint
+	global.SystemSetting.GetValueByInterface(systemSetting.PANEL_PUBLIC_USER_ID, &beforeUserId)
+
 	if err := global.SystemSetting.Set(systemSetting.PANEL_PUBLIC_USER_ID, req.UserId); err != nil {
 		apiReturn.Error(c, "set fail")
 		return
 	}
+	audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_PUBLIC_VISIT_USER, systemSetting.PANEL_PUBLIC_USER_ID, gin.H{"userId": beforeUserId}, gin.H{"userId": req.UserId})
 	apiReturn.Success(c)
 }
 
@@ -278,3 +623,15 @@ func (a UsersApi) GetPublicVisitUser(c *gin.Context) {
 	// 没有此配置
 	apiReturn.ErrorDataNotFound(c)
 }
+
+// 验证角色是否存在，不存在时返回错误
+func checkRoleExist(c *gin.Context, roleId int) bool {
+	if _, err := permission.GetRole(roleId); err == gorm.ErrRecordNotFound {
+		apiReturn.ErrorByCode(c, 1050)
+		return false
+	} else if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return false
+	}
+	return true
+}
@@ -8,4 +8,12 @@ type ApiSystem struct {
 	NoticeApi       NoticeApi
 	ModuleConfigApi ModuleConfigApi
 	MonitorApi      MonitorApi
+	TwoFactorApi    TwoFactorApi
+	OidcApi         OidcApi
+	ApiTokenApi     ApiTokenApi
+	SessionApi      SessionApi
+	RegisterApi     RegisterApi
+	CaptchaApi      CaptchaApi
+	SetupApi        SetupApi
+	PasskeyApi      PasskeyApi
 }
@@ -0,0 +1,111 @@
+package system
+
+import (
+	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
+	"sun-panel/api/api_v1/common/apiData/systemApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/cmn"
+	"sun-panel/lib/permission"
+	"sun-panel/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// 个人访问令牌
+type ApiTokenApi struct{}
+
+func (a *ApiTokenApi) GetList(c *gin.Context) {
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	mApiToken := models.UserApiToken{}
+	list, err := mApiToken.GetListByUserId(global.Db, userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	resp := []systemApiStructs.ApiTokenInfo{}
+	for _, v := range list {
+		resp = append(resp, buildApiTokenInfo(v))
+	}
+	apiReturn.SuccessListData(c, resp, int64(len(resp)))
+}
+
+// 创建令牌，明文令牌只返回这一次
+func (a *ApiTokenApi) Create(c *gin.Context) {
+	req := systemApiStructs.ApiTokenCreateReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	scopes := []string{}
+	for _, v := range req.Scopes {
+		if !cmn.InSlice(models.ApiTokenScopes, v) {
+			apiReturn.ErrorParamFomat(c, "unknown scope: "+v)
+			return
+		}
+		// 非管理员不能创建管理员权限的令牌
+		if v == models.API_TOKEN_SCOPE_ADMIN && !permission.IsAdmin(userInfo.Role) {
+			apiReturn.ErrorByCode(c, 1005)
+			return
+		}
+		if !cmn.InSlice(scopes, v) {
+			scopes = append(scopes, v)
+		}
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		apiReturn.ErrorParamFomat(c, "expiresAt must be in the future")
+		return
+	}
+
+	mApiToken := models.UserApiToken{}
+	info, token, err := mApiToken.Create(global.Db, userInfo.ID, req.Name, scopes, req.ExpiresAt)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	apiReturn.SuccessData(c, systemApiStructs.ApiTokenCreateResp{
+		ApiTokenInfo: buildApiTokenInfo(info),
+		Token:        token,
+	})
+}
+
+// 删除(吊销)令牌
+func (a *ApiTokenApi) Deletes(c *gin.Context) {
+	req := commonApiStructs.RequestDeleteIds[uint]{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	mApiToken := models.UserApiToken{}
+	if err := mApiToken.DeleteByIds(global.Db, userInfo.ID, req.Ids); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.Success(c)
+}
+
+func buildApiTokenInfo(info models.UserApiToken) systemApiStructs.ApiTokenInfo {
+	return systemApiStructs.ApiTokenInfo{
+		Id:          info.ID,
+		Name:        info.Name,
+		TokenPrefix: info.TokenPrefix,
+		Scopes:      info.ScopeList(),
+		ExpiresAt:   info.ExpiresAt,
+		LastUsedAt:  info.LastUsedAt,
+		LastUsedIp:  info.LastUsedIp,
+		CreateTime:  info.CreatedAt,
+	}
+}
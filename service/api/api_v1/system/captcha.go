@@ -0,0 +1,71 @@
+package system
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/lib/captcha"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 图形验证码
+type CaptchaApi struct{}
+
+const (
+	captchaDefaultWidth  = 200
+	captchaDefaultHeight = 60
+	captchaCookieMaxAge  = 600 // 验证码ID的cookie有效期(秒)
+)
+
+// 获取图形验证码，验证码ID来自cookie或header(CaptchaId)，都没有时生成新的ID并写入cookie
+func (a CaptchaApi) GetImage(c *gin.Context) {
+	captchaId, err := captcha.CaptchaGetIdByCookieHeader(c, captcha.CAPTCHA_ID_KEY)
+	if err != nil || len(captchaId) > 64 {
+		captchaId = uuid.NewString()
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(captcha.CAPTCHA_ID_KEY, captchaId, captchaCookieMaxAge, "/", "", false, true)
+	c.Header(captcha.CAPTCHA_ID_KEY, captchaId)
+	captchaImage(c, captchaId)
+}
+
+// 根据验证码ID获取图形验证码，用于需要进一步验证的接口(错误码1101返回的codeId)
+func (a CaptchaApi) GetImageByCaptchaId(c *gin.Context) {
+	captchaId := c.Param("captchaId")
+	if captchaId == "" || len(captchaId) > 64 {
+		apiReturn.ErrorParamFomat(c, "captchaId")
+		return
+	}
+	captchaImage(c, captchaId)
+}
+
+// 输出验证码图片，尺寸限制在合理范围内
+func captchaImage(c *gin.Context, captchaId string) {
+	width := captchaSize(c.Param("width"), captchaDefaultWidth, 60, 400)
+	height := captchaSize(c.Param("height"), captchaDefaultHeight, 20, 150)
+
+	buf := bytes.Buffer{}
+	if err := captcha.WriteCaptchaImage(&buf, captchaId, width, height); err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", buf.Bytes())
+}
+
+func captchaSize(value string, defaultValue, min, max int) int {
+	size, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	if size < min {
+		return min
+	}
+	if size > max {
+		return max
+	}
+	return size
+}
@@ -10,6 +10,7 @@ import (
 	"sun-panel/api/api_v1/common/apiReturn"
 	"sun-panel/api/api_v1/common/base"
 	"sun-panel/global"
+	"sun-panel/lib/audit"
 	"sun-panel/lib/cmn"
 	"sun-panel/models"
 	"time"
@@ -159,10 +160,9 @@ func (a *FileApi) Deletes(c *gin.Context) {
 
 	// 设置一个错误收集器
 	var deleteErrors []string
+	files := []models.File{}
 
 	err := global.Db.Transaction(func(tx *gorm.DB) error {
-		files := []models.File{}
-
 		if err := tx.Order("created_at desc").Find(&files, "user_id=? AND id in ?", userInfo.ID, req.Ids).Error; err != nil {
 			return err
 		}
@@ -199,6 +199,9 @@ func (a *FileApi) Deletes(c *gin.Context) {
 		apiReturn.ErrorDatabase(c, err.Error())
 		return
 	}
+	for _, v := range files {
+		audit.Record(c, models.AUDIT_ACTION_DELETE, models.AUDIT_TARGET_FILE, v.ID, v, nil)
+	}
 
 	// 如果有删除错误，返回警告信息，但仍标记为成功（因为数据库记录已删除）
 	if len(deleteErrors) > 0 {
@@ -1,18 +1,23 @@
 package system
 
 import (
+	"math"
 	"strconv"
 	"strings"
 	"sun-panel/api/api_v1/common/apiReturn"
 	"sun-panel/api/api_v1/common/base"
 	"sun-panel/global"
-	"sun-panel/lib/cmn"
+	"sun-panel/lib/authenticator"
+	"sun-panel/lib/captcha"
 	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/loginLimit"
+	"sun-panel/lib/permission"
+	"sun-panel/lib/session"
 	"sun-panel/models"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 type LoginApi struct {
@@ -47,16 +52,36 @@ func (l LoginApi) Login(c *gin.Context) {
 	settings := systemSetting.ApplicationSetting{}
 	global.SystemSetting.GetValueByInterface("system_application", &settings)
 
-	mUser := models.User{}
 	var (
 		err  error
 		info models.User
 	)
-	bToken := ""
 	param.Username = strings.TrimSpace(param.Username)
-	if info, err = mUser.GetUserInfoByUsernameAndPassword(param.Username, cmn.PasswordEncryption(param.Password)); err != nil {
-		// 未找到记录 账号或密码错误
-		if err == gorm.ErrRecordNotFound {
+
+	// 登录失败次数过多
+	clientIp := c.ClientIP()
+	if retryAfter, err := loginLimit.Check(param.Username, clientIp); err != nil {
+		loginLimitError(c, retryAfter, err)
+		return
+	}
+
+	// 图形验证码，验证码ID来自cookie或header
+	if loginLimit.CaptchaRequired(settings.Login, clientIp) {
+		captchaId, _ := captcha.CaptchaGetIdByCookieHeader(c, captcha.CAPTCHA_ID_KEY)
+		if param.VCode == "" || captchaId == "" {
+			apiReturn.ErrorByCode(c, apiReturn.ERROR_CODE_VERIFICATION_MUST)
+			return
+		}
+		if !captcha.CaptchaVerifyHandle(captchaId, param.VCode) {
+			apiReturn.ErrorByCode(c, apiReturn.ERROR_CODE_VERIFICATION_FAIL)
+			return
+		}
+	}
+
+	if info, err = authenticator.Authenticate(param.Username, param.Password); err != nil {
+		// 账号或密码错误
+		if err == authenticator.ErrInvalidCredentials || err == authenticator.ErrGroupDenied || err == authenticator.ErrUserNotFound {
+			loginLimit.Failed(param.Username, clientIp)
 			apiReturn.ErrorByCode(c, 1003)
 			return
 		} else {
@@ -67,40 +92,233 @@ func (l LoginApi) Login(c *gin.Context) {
 
 	}
 
-	// 停用或未激活
-	if info.Status != 1 {
-		apiReturn.ErrorByCode(c, 1004)
+	loginLimit.Succeeded(param.Username)
+
+	// 停用、未激活或已过期
+	if errCode := base.UserStatusErrorCode(info); errCode != 0 {
+		apiReturn.ErrorByCode(c, errCode)
+		return
+	}
+
+	loginTwoFactorOrSuccess(c, info, settings)
+}
+
+// 已启用二次验证或管理员被要求启用时，下发登录挑战而不是token
+func loginTwoFactorOrSuccess(c *gin.Context, info models.User, settings systemSetting.ApplicationSetting) {
+	mTwoFactor := models.UserTwoFactor{}
+	twoFactorEnabled, err := mTwoFactor.IsEnabled(global.Db, info.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if twoFactorEnabled || (settings.AdminTwoFactorRequired && permission.IsAdmin(info.Role)) {
+		challenge := uuid.NewString()
+		global.LoginChallenge.SetDefault(challenge, global.LoginChallengeInfo{
+			UserId: info.ID,
+			Enroll: !twoFactorEnabled,
+		})
+		code := 1010
+		if !twoFactorEnabled {
+			code = 1011
+		}
+		msg, _ := apiReturn.GetErrorMsgByCode(code)
+		apiReturn.ErrorCode(c, code, msg, gin.H{"challenge": challenge})
+		return
+	}
+
+	loginSuccessReturn(c, info)
+}
+
+// 二次验证登录输入验证
+type LoginTwoFactorVerify struct {
+	Challenge string `json:"challenge" validate:"required"`
+	Code      string `json:"code" validate:"max=20"`
+}
+
+// 完成二次验证，使用TOTP验证码或恢复码换取登录token
+func (l LoginApi) TwoFactorVerify(c *gin.Context) {
+	param := LoginTwoFactorVerify{}
+	if err := c.ShouldBindJSON(&param); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+
+	if errMsg, err := base.ValidateInputStruct(param); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	challenge, ok := global.LoginChallenge.Get(param.Challenge)
+	if !ok {
+		apiReturn.ErrorByCode(c, 1013)
+		return
+	}
+	if challenge.Enroll {
+		apiReturn.ErrorByCode(c, 1011)
+		return
+	}
+
+	mTwoFactor := models.UserTwoFactor{}
+	if err := mTwoFactor.Verify(global.Db, challenge.UserId, param.Code); err != nil {
+		if err == models.ErrTwoFactorCodeInvalid {
+			loginChallengeFailed(param.Challenge, challenge)
+			apiReturn.ErrorByCode(c, 1012)
+		} else if err == models.ErrTwoFactorNotEnabled {
+			global.LoginChallenge.Delete(param.Challenge)
+			apiReturn.ErrorByCode(c, 1013)
+		} else {
+			apiReturn.ErrorDatabase(c, err.Error())
+		}
+		return
+	}
+	global.LoginChallenge.Delete(param.Challenge)
+
+	info, ok := loginChallengeUser(c, challenge)
+	if !ok {
+		return
+	}
+	loginSuccessReturn(c, info)
+}
+
+// 强制绑定二次验证：生成密钥
+func (l LoginApi) TwoFactorGenerate(c *gin.Context) {
+	param := LoginTwoFactorVerify{}
+	if err := c.ShouldBindJSON(&param); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+
+	challenge, ok := global.LoginChallenge.Get(param.Challenge)
+	if !ok || !challenge.Enroll {
+		apiReturn.ErrorByCode(c, 1013)
+		return
+	}
+
+	info, ok := loginChallengeUser(c, challenge)
+	if !ok {
+		return
+	}
+
+	mTwoFactor := models.UserTwoFactor{}
+	twoFactor, err := mTwoFactor.GenerateSecret(global.Db, info.ID)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	resp, err := buildTwoFactorSetupResp(info, twoFactor.Secret)
+	if err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
+	apiReturn.SuccessData(c, resp)
+}
+
+// 强制绑定二次验证：确认绑定并完成登录
+func (l LoginApi) TwoFactorEnable(c *gin.Context) {
+	param := LoginTwoFactorVerify{}
+	if err := c.ShouldBindJSON(&param); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
 		return
 	}
 
-	bToken = info.Token
-	if info.Token == "" {
-		// 生成token
-		buildTokenOver := false
-		for !buildTokenOver {
-			bToken = cmn.BuildRandCode(32, cmn.RAND_CODE_MODE2)
-			if _, err := mUser.GetUserInfoByToken(bToken); err != nil {
-				// 保存token
-				mUser.UpdateUserInfoByUserId(info.ID, map[string]interface{}{
-					"token": bToken,
-				})
-				buildTokenOver = true
-			}
+	challenge, ok := global.LoginChallenge.Get(param.Challenge)
+	if !ok || !challenge.Enroll {
+		apiReturn.ErrorByCode(c, 1013)
+		return
+	}
+
+	mTwoFactor := models.UserTwoFactor{}
+	recoveryCodes, err := mTwoFactor.Enable(global.Db, challenge.UserId, param.Code)
+	if err != nil {
+		if err == models.ErrTwoFactorCodeInvalid {
+			loginChallengeFailed(param.Challenge, challenge)
 		}
-		info.Token = bToken
+		twoFactorError(c, err)
+		return
+	}
+	global.LoginChallenge.Delete(param.Challenge)
+
+	info, ok := loginChallengeUser(c, challenge)
+	if !ok {
+		return
+	}
+	info, err = loginSuccess(c, info)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.SuccessData(c, struct {
+		models.User
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}{
+		User:          info,
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+// 登录失败次数过多，返回需要等待的秒数
+func loginLimitError(c *gin.Context, retryAfter time.Duration, err error) {
+	code := 1020
+	if err == loginLimit.ErrLocked {
+		code = 1021
+	} else if err != loginLimit.ErrTooFrequent {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	msg, _ := apiReturn.GetErrorMsgByCode(code)
+	apiReturn.ErrorCode(c, code, msg, gin.H{"retryAfter": seconds})
+}
+
+// 登录挑战验证失败，超过次数作废
+func loginChallengeFailed(key string, challenge global.LoginChallengeInfo) {
+	challenge.Attempts++
+	if challenge.Attempts >= 5 {
+		global.LoginChallenge.Delete(key)
+	} else {
+		global.LoginChallenge.SetKeepExpiration(key, challenge)
+	}
+}
+
+// 获取登录挑战对应的用户，并重新验证账号状态
+func loginChallengeUser(c *gin.Context, challenge global.LoginChallengeInfo) (models.User, bool) {
+	mUser := models.User{}
+	info, err := mUser.GetUserInfoByUid(challenge.UserId)
+	if err != nil {
+		apiReturn.ErrorByCode(c, 1013)
+		return info, false
+	}
+	if errCode := base.UserStatusErrorCode(info); errCode != 0 {
+		apiReturn.ErrorByCode(c, errCode)
+		return info, false
+	}
+	return info, true
+}
+
+// 登录成功，创建会话，返回的用户信息中token为会话token
+func loginSuccess(c *gin.Context, info models.User) (models.User, error) {
+	_, token, err := session.Create(info.ID, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		return info, err
 	}
 	info.Password = ""
 	info.ReferralCode = ""
 
-	// global.UserToken.SetDefault(bToken, info)
-	cToken := uuid.NewString() + "-" + cmn.Md5(cmn.Md5("userId"+strconv.Itoa(int(info.ID))))
-	global.CUserToken.SetDefault(cToken, bToken)
-	global.Logger.Debug("token:", cToken, "|", bToken)
-	global.Logger.Debug(global.CUserToken.Get(cToken))
-
 	// 设置当前用户信息
 	c.Set("userInfo", info)
-	info.Token = cToken // 重要 采用cToken,隐藏真实token
+	info.Token = token
+	return info, nil
+}
+
+// 登录成功并返回用户信息
+func loginSuccessReturn(c *gin.Context, info models.User) {
+	info, err := loginSuccess(c, info)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
 	apiReturn.SuccessData(c, info)
 }
 
@@ -108,6 +326,9 @@ func (l LoginApi) Login(c *gin.Context) {
 func (l *LoginApi) Logout(c *gin.Context) {
 	// userInfo, _ := base.GetCurrentUserInfo(c)
 	cToken := c.GetHeader("token")
-	global.CUserToken.Delete(cToken)
+	if err := session.RevokeToken(cToken); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
 	apiReturn.Success(c)
 }
@@ -0,0 +1,114 @@
+package system
+
+import (
+	"net/url"
+	"strings"
+	"sun-panel/api/api_v1/common/apiData/systemApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/emailVCode"
+	"sun-panel/lib/magicLink"
+	"sun-panel/lib/mail"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// 邮件登录：发送一次性登录链接到账号绑定的邮箱
+// 无论邮箱是否存在都返回成功，避免泄露账号信息
+func (l LoginApi) SendMagicLink(c *gin.Context) {
+	req := systemApiStructs.LoginSendMagicLinkReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	settings := systemSetting.ApplicationSetting{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_APPLICATION, &settings)
+	if !magicLink.Enabled(settings) {
+		apiReturn.ErrorByCode(c, 1040)
+		return
+	}
+	emailer, err := emailVCode.GetEmailer()
+	if err != nil {
+		apiReturn.ErrorByCode(c, 1036)
+		return
+	}
+
+	if errCode, codeId := base.VerificationCheck(req.Verification.CodeID, req.Verification.VCode); errCode != apiReturn.ERROR_CODE_SUCCESS {
+		apiReturn.ErrorVerification(c, errCode, codeId)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if err := emailVCode.CheckAndCount(emailVCode.PURPOSE_MAGIC_LINK, email, c.ClientIP()); err != nil {
+		apiReturn.ErrorByCode(c, 1035)
+		return
+	}
+
+	mUser := models.User{}
+	userInfo, err := mUser.GetUserInfoByUsernameOrMail(email)
+	if err != nil || userInfo.Mail == "" || strings.ToLower(userInfo.Mail) != email || base.UserStatusErrorCode(userInfo) != 0 {
+		global.Logger.Infoln("magic link requested for unknown or unavailable account:", email, "ip:", c.ClientIP())
+		apiReturn.Success(c)
+		return
+	}
+
+	expire := magicLink.ExpireDuration(settings)
+	token, err := magicLink.Generate(userInfo, expire)
+	if err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
+	link := strings.TrimSuffix(settings.WebSiteUrl, "/") + "/#/login?magicLink=" + url.QueryEscape(token)
+	// 异步发送，响应时间不因账号是否存在而不同
+	go func() {
+		if err := mail.SendMagicLink(emailer, userInfo.Mail, link, int(expire.Minutes())); err != nil {
+			global.Logger.Errorln("send magic link:", err.Error())
+		}
+	}()
+	apiReturn.Success(c)
+}
+
+// 邮件登录：使用链接中的token登录，链接只能使用一次
+// 已启用二次验证的账号仍需完成二次验证
+func (l LoginApi) MagicLinkLogin(c *gin.Context) {
+	req := systemApiStructs.LoginMagicLinkReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	settings := systemSetting.ApplicationSetting{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_APPLICATION, &settings)
+	if !magicLink.Enabled(settings) {
+		apiReturn.ErrorByCode(c, 1040)
+		return
+	}
+
+	userInfo, err := magicLink.Verify(req.Token)
+	if err == magicLink.ErrInvalid {
+		apiReturn.ErrorByCode(c, 1041)
+		return
+	} else if err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
+	if errCode := base.UserStatusErrorCode(userInfo); errCode != 0 {
+		apiReturn.ErrorByCode(c, errCode)
+		return
+	}
+	global.Logger.Infoln("magic link login, username:", userInfo.Username, "ip:", c.ClientIP())
+	loginTwoFactorOrSuccess(c, userInfo, settings)
+}
@@ -1,8 +1,10 @@
 package system
 
 import (
+	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
 	"sun-panel/api/api_v1/common/apiData/systemApiStructs"
 	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
 	"sun-panel/global"
 	"sun-panel/models"
 
@@ -26,3 +28,84 @@ func (a *NoticeApi) GetListByDisplayType(c *gin.Context) {
 	}
 	apiReturn.SuccessListData(c, noticeList, 0)
 }
+
+// 公告列表(管理)
+func (a *NoticeApi) GetList(c *gin.Context) {
+	req := commonApiStructs.RequestPage{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+
+	var (
+		list  []models.Notice
+		count int64
+	)
+	db := global.Db.Model(&models.Notice{})
+	if req.Keyword != "" {
+		db = db.Where("title LIKE ?", "%"+req.Keyword+"%")
+	}
+	if req.Limit > 0 {
+		db = db.Limit(req.Limit).Offset((req.Page - 1) * req.Limit)
+	}
+	if err := db.Order("id desc").Find(&list).Limit(-1).Offset(-1).Count(&count).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.SuccessListData(c, list, count)
+}
+
+// 添加或修改公告
+func (a *NoticeApi) Edit(c *gin.Context) {
+	req := systemApiStructs.NoticeEditReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	info := models.Notice{
+		Title:       req.Title,
+		Content:     req.Content,
+		DisplayType: req.DisplayType,
+		OneRead:     req.OneRead,
+		Url:         req.Url,
+		IsLogin:     req.IsLogin,
+		UserId:      userInfo.ID,
+	}
+	var err error
+	if req.Id == 0 {
+		err = global.Db.Omit("User").Create(&info).Error
+	} else {
+		result := global.Db.Model(&models.Notice{}).Where("id=?", req.Id).
+			Select("Title", "Content", "DisplayType", "OneRead", "Url", "IsLogin", "UserId").Updates(&info)
+		if err = result.Error; err == nil && result.RowsAffected == 0 {
+			apiReturn.ErrorDataNotFound(c)
+			return
+		}
+		info.ID = req.Id
+	}
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.SuccessData(c, info)
+}
+
+// 删除公告
+func (a *NoticeApi) Deletes(c *gin.Context) {
+	req := commonApiStructs.RequestDeleteIds[uint]{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if err := global.Db.Delete(&models.Notice{}, "id in ?", req.Ids).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.Success(c)
+}
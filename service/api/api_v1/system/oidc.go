@@ -0,0 +1,251 @@
+package system
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/authenticator"
+	"sun-panel/lib/cmn"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/oidc"
+	"sun-panel/models"
+	"sun-panel/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcLoginCodePrefix   = "oidc_login:" // 回调后换取token的一次性code缓存前缀
+	oidcBindCodePrefix    = "oidc_bind:"  // 绑定身份时发起登录的一次性code缓存前缀
+	oidcStateCookie       = "oidc_state"  // 发起登录的浏览器保存的state
+	oidcStateCookiePath   = "/api/oidc/"
+	oidcStateCookieMaxAge = 600 // 与state缓存的有效期一致(秒)
+)
+
+// OpenID Connect 单点登录
+type OidcApi struct{}
+
+// 发起登录，跳转到签发者的授权页
+func (a *OidcApi) Login(c *gin.Context) {
+	provider, err := getOidcProvider(c)
+	if err != nil {
+		oidcRedirectError(c, err)
+		return
+	}
+
+	state, _ := oidc.RandomString(24)
+	nonce, _ := oidc.RandomString(24)
+	codeVerifier, err := oidc.RandomString(48)
+	if err != nil {
+		oidcRedirectError(c, err)
+		return
+	}
+
+	// 已登录的账号绑定身份，跳转请求无法携带token，使用一次性code
+	var bindUserId uint
+	if bindCode := c.Query("bind"); bindCode != "" {
+		userId, ok := global.VerifyCodeCachePool.GetDel(oidcBindCodePrefix + bindCode)
+		if !ok {
+			oidcRedirectError(c, errors.New("invalid bind code"))
+			return
+		}
+		bindUserId = cmn.StrToUint(userId)
+	}
+
+	redirectUrl := getOidcRedirectUrl(c, provider.Config)
+	global.OidcState.SetDefault(state, global.OidcStateInfo{
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		RedirectUrl:  redirectUrl,
+		BindUserId:   bindUserId,
+	})
+
+	// state同时保存到发起登录的浏览器，回调时校验，防止登录CSRF和会话固定
+	setOidcStateCookie(c, state, oidcStateCookieMaxAge, redirectUrl)
+
+	c.Redirect(302, provider.AuthCodeURL(redirectUrl, state, nonce, codeVerifier))
+}
+
+// 签发者回调，验证通过后携带一次性code跳转回前端登录页
+func (a *OidcApi) Callback(c *gin.Context) {
+	if errMsg := c.Query("error"); errMsg != "" {
+		oidcRedirectError(c, errors.New(errMsg+" "+c.Query("error_description")))
+		return
+	}
+
+	state := c.Query("state")
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	stateInfo, ok := global.OidcState.Get(state)
+	if state == "" || !ok || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		oidcRedirectError(c, errors.New("invalid state"))
+		return
+	}
+	global.OidcState.Delete(state)
+	setOidcStateCookie(c, "", -1, stateInfo.RedirectUrl)
+
+	provider, err := getOidcProvider(c)
+	if err != nil {
+		oidcRedirectError(c, err)
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), stateInfo.RedirectUrl, c.Query("code"), stateInfo.CodeVerifier, stateInfo.Nonce)
+	if err != nil {
+		oidcRedirectError(c, err)
+		return
+	}
+
+	if stateInfo.BindUserId != 0 {
+		if err := authenticator.LinkOidcIdentity(stateInfo.BindUserId, oidc.ClaimString(claims, "iss"), oidc.ClaimString(claims, "sub")); err != nil {
+			oidcRedirectError(c, err)
+			return
+		}
+		c.Redirect(302, "/#/?oidcBound=1")
+		return
+	}
+
+	userInfo, err := oidcFindOrCreateUser(provider.Config, claims)
+	if err != nil {
+		oidcRedirectError(c, err)
+		return
+	}
+
+	loginCode, err := oidc.RandomString(32)
+	if err != nil {
+		oidcRedirectError(c, err)
+		return
+	}
+	global.VerifyCodeCachePool.SetDefault(oidcLoginCodePrefix+loginCode, cmn.UintToStr(userInfo.ID))
+	c.Redirect(302, "/#/login?oidcCode="+url.QueryEscape(loginCode))
+}
+
+// 已登录的账号发起绑定OIDC身份，返回跳转地址
+// 已有的本地账号不会按用户名自动关联，需要登录后在此绑定
+func (a *OidcApi) BindStart(c *gin.Context) {
+	if !getOidcConfig().Enable {
+		apiReturn.ErrorByCode(c, 1005)
+		return
+	}
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	bindCode, err := oidc.RandomString(32)
+	if err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
+	global.VerifyCodeCachePool.SetDefault(oidcBindCodePrefix+bindCode, cmn.UintToStr(userInfo.ID))
+	apiReturn.SuccessData(c, gin.H{"url": "/api/oidc/login?bind=" + url.QueryEscape(bindCode)})
+}
+
+// 前端使用一次性code换取登录token
+func (a *OidcApi) Exchange(c *gin.Context) {
+	type Req struct {
+		Code string `json:"code" validate:"required"`
+	}
+	req := Req{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	// 取值同时删除，同一个code只能使用一次
+	userId, ok := global.VerifyCodeCachePool.GetDel(oidcLoginCodePrefix + req.Code)
+	if !ok {
+		apiReturn.ErrorByCode(c, 1013)
+		return
+	}
+
+	mUser := models.User{}
+	info, err := mUser.GetUserInfoByUid(cmn.StrToUint(userId))
+	if err != nil {
+		apiReturn.ErrorByCode(c, 1013)
+		return
+	}
+	if errCode := base.UserStatusErrorCode(info); errCode != 0 {
+		apiReturn.ErrorByCode(c, errCode)
+		return
+	}
+
+	// 与密码登录相同，已启用二次验证时需要完成验证
+	settings := systemSetting.ApplicationSetting{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_APPLICATION, &settings)
+	loginTwoFactorOrSuccess(c, info, settings)
+}
+
+// 获取OIDC配置
+func getOidcConfig() structs.IniConfigOidc {
+	cfg := structs.IniConfigOidc{}
+	if err := global.Config.GetSection("oidc", &cfg); err != nil {
+		cfg.Enable = false
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "preferred_username"
+	}
+	return cfg
+}
+
+func getOidcProvider(c *gin.Context) (*oidc.Provider, error) {
+	return oidc.GetProvider(c.Request.Context(), getOidcConfig())
+}
+
+// 回调地址 优先级：配置文件 > 站点地址 > 当前请求地址
+func getOidcRedirectUrl(c *gin.Context, cfg structs.IniConfigOidc) string {
+	if cfg.RedirectUrl != "" {
+		return cfg.RedirectUrl
+	}
+
+	settings := systemSetting.ApplicationSetting{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_APPLICATION, &settings)
+	if settings.WebSiteUrl != "" {
+		return strings.TrimSuffix(settings.WebSiteUrl, "/") + "/api/oidc/callback"
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/api/oidc/callback"
+}
+
+// 回调由签发者跳转(跨站的顶层GET请求)，SameSite需要为Lax才会携带
+func setOidcStateCookie(c *gin.Context, state string, maxAge int, redirectUrl string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, maxAge, oidcStateCookiePath, "", strings.HasPrefix(redirectUrl, "https://"), true)
+}
+
+func oidcRedirectError(c *gin.Context, err error) {
+	global.Logger.Errorln("OIDC login failed:", err.Error())
+	c.Redirect(302, "/#/login?oidcError="+url.QueryEscape(err.Error()))
+}
+
+// 根据claims的签发者和sub匹配用户，不存在时按配置自动创建，并根据用户组同步角色
+func oidcFindOrCreateUser(cfg structs.IniConfigOidc, claims map[string]interface{}) (models.User, error) {
+	extUser := authenticator.ExternalUser{
+		Username: oidc.ClaimString(claims, cfg.UsernameClaim),
+		Name:     oidc.ClaimString(claims, "name"),
+		Mail:     oidc.ClaimString(claims, "email"),
+	}
+
+	// 未配置用户组claim时不修改角色
+	if cfg.GroupsClaim != "" {
+		role, err := authenticator.MapGroupsToRole(
+			oidc.ClaimStrings(claims, cfg.GroupsClaim),
+			cmn.SplitAndTrim(cfg.AdminGroups, ","),
+			cmn.SplitAndTrim(cfg.UserGroups, ","),
+		)
+		if err != nil {
+			return models.User{}, err
+		}
+		extUser.Role = role
+	}
+
+	return authenticator.ProvisionOidcUser(extUser, oidc.ClaimString(claims, "iss"), oidc.ClaimString(claims, "sub"), cfg.AutoCreate)
+}
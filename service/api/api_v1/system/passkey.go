@@ -0,0 +1,374 @@
+package system
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
+	"sun-panel/api/api_v1/common/apiData/systemApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/audit"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/passkey"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 通行密钥(WebAuthn)
+type PasskeyApi struct{}
+
+func (a *PasskeyApi) GetList(c *gin.Context) {
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	mPasskey := models.UserPasskey{}
+	list, err := mPasskey.GetListByUserId(global.Db, userInfo.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	resp := []systemApiStructs.PasskeyInfo{}
+	for _, v := range list {
+		resp = append(resp, buildPasskeyInfo(v))
+	}
+	apiReturn.SuccessListData(c, resp, int64(len(resp)))
+}
+
+// 开始注册，返回传给浏览器的参数
+func (a *PasskeyApi) RegisterBegin(c *gin.Context) {
+	wa, ok := getWebAuthn(c)
+	if !ok {
+		return
+	}
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	user, err := passkey.LoadUser(userInfo)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if len(user.Passkeys) >= passkey.MAX_PASSKEY_COUNT {
+		apiReturn.ErrorByCode(c, 1083)
+		return
+	}
+
+	options, sessionData, err := wa.BeginRegistration(user, webauthn.WithExclusions(user.Exclusions()))
+	if err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
+	passkeyBeginReturn(c, userInfo.ID, sessionData, options)
+}
+
+// 完成注册，验证浏览器返回的凭据并保存
+func (a *PasskeyApi) RegisterFinish(c *gin.Context) {
+	req := systemApiStructs.PasskeyRegisterFinishReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	challenge, ok := getPasskeyChallenge(c, req.Challenge)
+	if !ok || challenge.UserId != userInfo.ID {
+		apiReturn.ErrorByCode(c, 1013)
+		return
+	}
+	wa, ok := getWebAuthn(c)
+	if !ok {
+		return
+	}
+	user, err := passkey.LoadUser(userInfo)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		passkeyVerifyFailed(c, err)
+		return
+	}
+	cred, err := wa.CreateCredential(user, challenge.Session, parsed)
+	if err != nil {
+		passkeyVerifyFailed(c, err)
+		return
+	}
+
+	mPasskey := models.UserPasskey{}
+	if _, err := mPasskey.GetByCredentialId(global.Db, passkey.EncodeId(cred.ID)); err == nil {
+		apiReturn.ErrorByCode(c, 1084)
+		return
+	} else if err != gorm.ErrRecordNotFound {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = fmt.Sprintf("Passkey %d", len(user.Passkeys)+1)
+	}
+	info := passkey.NewPasskey(userInfo.ID, name, cred)
+	if err := global.Db.Create(&info).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	audit.Record(c, models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_PASSKEY, info.ID, nil, info)
+
+	apiReturn.SuccessData(c, buildPasskeyInfo(info))
+}
+
+func (a *PasskeyApi) Rename(c *gin.Context) {
+	req := systemApiStructs.PasskeyRenameReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	mPasskey := models.UserPasskey{}
+	if count, err := mPasskey.Rename(global.Db, userInfo.ID, req.Id, strings.TrimSpace(req.Name)); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	} else if count == 0 {
+		apiReturn.ErrorDataNotFound(c)
+		return
+	}
+	apiReturn.Success(c)
+}
+
+func (a *PasskeyApi) Deletes(c *gin.Context) {
+	req := commonApiStructs.RequestDeleteIds[uint]{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	deletePasskeys := []models.UserPasskey{}
+	if err := global.Db.Find(&deletePasskeys, "user_id=? AND id in ?", userInfo.ID, req.Ids).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	mPasskey := models.UserPasskey{}
+	if err := mPasskey.DeleteByIds(global.Db, userInfo.ID, req.Ids); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	for _, v := range deletePasskeys {
+		audit.Record(c, models.AUDIT_ACTION_DELETE, models.AUDIT_TARGET_PASSKEY, v.ID, v, nil)
+	}
+	apiReturn.Success(c)
+}
+
+// 通行密钥登录：开始，填写账号时只允许该账号的通行密钥，否则使用可发现凭据(无需输入账号)
+func (l LoginApi) PasskeyBegin(c *gin.Context) {
+	req := systemApiStructs.PasskeyLoginBeginReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+	wa, ok := getWebAuthn(c)
+	if !ok {
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		options, sessionData, err := wa.BeginDiscoverableLogin()
+		if err != nil {
+			apiReturn.Error(c, err.Error())
+			return
+		}
+		passkeyBeginReturn(c, 0, sessionData, options)
+		return
+	}
+
+	// 账号不存在和没有通行密钥返回相同的错误
+	mUser := models.User{}
+	userInfo, err := mUser.GetUserInfoByUsername(req.Username)
+	if err == gorm.ErrRecordNotFound {
+		apiReturn.ErrorByCode(c, 1082)
+		return
+	} else if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	user, err := passkey.LoadUser(userInfo)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	if len(user.Passkeys) == 0 {
+		apiReturn.ErrorByCode(c, 1082)
+		return
+	}
+	options, sessionData, err := wa.BeginLogin(user)
+	if err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
+	passkeyBeginReturn(c, userInfo.ID, sessionData, options)
+}
+
+// 通行密钥登录：完成，验证通过后与密码登录相同，未进行用户验证(PIN、生物识别)时仍需二次验证
+func (l LoginApi) PasskeyFinish(c *gin.Context) {
+	req := systemApiStructs.PasskeyLoginFinishReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	challenge, ok := getPasskeyChallenge(c, req.Challenge)
+	if !ok {
+		apiReturn.ErrorByCode(c, 1013)
+		return
+	}
+	wa, ok := getWebAuthn(c)
+	if !ok {
+		return
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		passkeyVerifyFailed(c, err)
+		return
+	}
+
+	var (
+		user *passkey.User
+		cred *webauthn.Credential
+	)
+	if challenge.UserId != 0 {
+		if user, err = loadPasskeyUser(challenge.UserId); err == nil {
+			cred, err = wa.ValidateLogin(user, challenge.Session, parsed)
+		}
+	} else {
+		cred, err = wa.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			userId, err := passkey.ParseUserHandle(userHandle)
+			if err != nil {
+				return nil, err
+			}
+			if user, err = loadPasskeyUser(userId); err != nil {
+				return nil, err
+			}
+			return user, nil
+		}, challenge.Session, parsed)
+	}
+	if err != nil {
+		passkeyVerifyFailed(c, err)
+		return
+	}
+
+	info, _ := user.GetPasskey(cred.ID)
+	if err := passkey.CheckLogin(cred); err != nil {
+		global.Logger.Warnln(err.Error(), "user:", user.Info.Username, "passkey:", info.ID)
+		apiReturn.ErrorByCode(c, 1081)
+		return
+	}
+	if err := info.UpdateAfterLogin(global.Db, cred.Authenticator.SignCount, cred.Flags.BackupState); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	// 停用、未激活或已过期
+	if errCode := base.UserStatusErrorCode(user.Info); errCode != 0 {
+		apiReturn.ErrorByCode(c, errCode)
+		return
+	}
+	if cred.Flags.UserVerified {
+		loginSuccessReturn(c, user.Info)
+		return
+	}
+	settings := systemSetting.ApplicationSetting{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_APPLICATION, &settings)
+	loginTwoFactorOrSuccess(c, user.Info, settings)
+}
+
+// 获取WebAuthn实例，未设置站点地址时返回错误
+func getWebAuthn(c *gin.Context) (*webauthn.WebAuthn, bool) {
+	wa, err := passkey.New()
+	if err == passkey.ErrNotConfigured {
+		apiReturn.ErrorByCode(c, 1080)
+		return nil, false
+	} else if err != nil {
+		apiReturn.Error(c, err.Error())
+		return nil, false
+	}
+	return wa, true
+}
+
+// 保存挑战并返回传给浏览器的参数
+func passkeyBeginReturn(c *gin.Context, userId uint, sessionData *webauthn.SessionData, options interface{}) {
+	challenge := uuid.NewString()
+	global.PasskeyChallenge.SetDefault(challenge, global.PasskeyChallengeInfo{
+		UserId:  userId,
+		Session: *sessionData,
+	})
+	apiReturn.SuccessData(c, systemApiStructs.PasskeyBeginResp{
+		Challenge: challenge,
+		Options:   options,
+	})
+}
+
+// 获取挑战，挑战只能使用一次
+func getPasskeyChallenge(c *gin.Context, key string) (global.PasskeyChallengeInfo, bool) {
+	challenge, ok := global.PasskeyChallenge.Get(key)
+	if ok {
+		global.PasskeyChallenge.Delete(key)
+	}
+	return challenge, ok
+}
+
+func loadPasskeyUser(userId uint) (*passkey.User, error) {
+	mUser := models.User{}
+	userInfo, err := mUser.GetUserInfoByUid(userId)
+	if err != nil {
+		return nil, err
+	}
+	return passkey.LoadUser(userInfo)
+}
+
+// 验证失败，详细原因只记录到日志
+func passkeyVerifyFailed(c *gin.Context, err error) {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) {
+		global.Logger.Debugln("passkey:", protocolErr.Details, protocolErr.DevInfo)
+	} else {
+		global.Logger.Debugln("passkey:", err.Error())
+	}
+	apiReturn.ErrorByCode(c, 1081)
+}
+
+func buildPasskeyInfo(info models.UserPasskey) systemApiStructs.PasskeyInfo {
+	return systemApiStructs.PasskeyInfo{
+		Id:             info.ID,
+		Name:           info.Name,
+		BackupEligible: info.BackupEligible,
+		BackupState:    info.BackupState,
+		LastUsedAt:     info.LastUsedAt,
+		CreateTime:     info.CreatedAt,
+	}
+}
@@ -0,0 +1,178 @@
+package system
+
+import (
+	"strings"
+	"sun-panel/api/api_v1/common/apiData/systemApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/cmn"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/emailVCode"
+	"sun-panel/lib/mail"
+	"sun-panel/lib/password"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// 自助注册
+type RegisterApi struct{}
+
+// 发送注册邮箱验证码
+func (a RegisterApi) SendRegisterVcode(c *gin.Context) {
+	req := systemApiStructs.RegisterSendVcodeReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if !registerCheckSetting(c, req.Email) {
+		return
+	}
+	if errCode, codeId := base.VerificationCheck(req.Verification.CodeID, req.Verification.VCode); errCode != apiReturn.ERROR_CODE_SUCCESS {
+		apiReturn.ErrorVerification(c, errCode, codeId)
+		return
+	}
+
+	mUser := models.User{}
+	if _, err := mUser.CheckMailExist(req.Email); err != nil {
+		apiReturn.ErrorByCode(c, 1032)
+		return
+	}
+
+	if err := emailVCode.CheckAndCount(emailVCode.PURPOSE_REGISTER, req.Email, c.ClientIP()); err != nil {
+		apiReturn.ErrorByCode(c, 1035)
+		return
+	}
+	if err := emailVCode.Send(emailVCode.PURPOSE_REGISTER, req.Email, req.Email, mail.SendRegisterEmail); err != nil {
+		emailVCodeError(c, err)
+		return
+	}
+	apiReturn.Success(c)
+}
+
+// 提交注册
+func (a RegisterApi) Commit(c *gin.Context) {
+	req := systemApiStructs.RegisterCommitReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	req.Username = strings.TrimSpace(req.Username)
+	if !cmn.VerifyFormat(cmn.VERIFY_EXP_USERNAME, req.Username) {
+		apiReturn.ErrorParamFomat(c, "The account can only contain letters, numbers, _ . @ and must be 5-50 characters long")
+		return
+	}
+
+	if !registerCheckSetting(c, req.Email) {
+		return
+	}
+
+	mUser := models.User{}
+	if _, err := mUser.CheckMailExist(req.Email); err != nil {
+		apiReturn.ErrorByCode(c, 1032)
+		return
+	}
+	if _, err := mUser.CheckUsernameExist(req.Username); err != nil {
+		apiReturn.ErrorByCode(c, 1033)
+		return
+	}
+
+	if !emailVCode.Verify(emailVCode.PURPOSE_REGISTER, req.Email, req.EmailVCode) {
+		apiReturn.ErrorByCode(c, 1034)
+		return
+	}
+
+	passwordHash, err := password.Hash(req.Password)
+	if err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
+
+	setting := systemSetting.ApplicationSetting{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_APPLICATION, &setting)
+	status := 1
+	if setting.RequireApproval {
+		status = 3
+	}
+
+	mUser = models.User{
+		Username: req.Username,
+		Password: passwordHash,
+		Name:     cmn.SubRuneStr(req.Username, 0, 20),
+		Mail:     req.Email,
+		Status:   status,
+		Role:     models.ROLE_USER,
+	}
+	userInfo, err := mUser.CreateOne()
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	global.Logger.Infoln("user registered, username:", userInfo.Username, "mail:", userInfo.Mail, "ip:", c.ClientIP())
+
+	apiReturn.SuccessData(c, systemApiStructs.RegisterCommitResp{
+		UserId:          userInfo.ID,
+		RequireApproval: setting.RequireApproval,
+	})
+}
+
+// 检查是否开放注册以及邮箱后缀是否允许
+func registerCheckSetting(c *gin.Context, email string) bool {
+	setting := systemSetting.ApplicationSetting{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_APPLICATION, &setting)
+	if !setting.OpenRegister {
+		apiReturn.ErrorByCode(c, 1030)
+		return false
+	}
+	if !registerEmailSuffixAllowed(setting.EmailSuffix, email) {
+		apiReturn.ErrorByCode(c, 1031)
+		return false
+	}
+	return true
+}
+
+// 邮箱后缀白名单，支持逗号、分号或空白分隔，"@example.com"和"example.com"均可
+func registerEmailSuffixAllowed(emailSuffix, email string) bool {
+	suffixes := strings.FieldsFunc(emailSuffix, func(r rune) bool {
+		return r == ',' || r == ';' || r == '，' || r == ' ' || r == '\n' || r == '\t' || r == '\r'
+	})
+	if len(suffixes) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at == -1 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, v := range suffixes {
+		if strings.EqualFold(strings.TrimPrefix(v, "@"), domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// 邮箱验证码发送失败
+func emailVCodeError(c *gin.Context, err error) {
+	if err == emailVCode.ErrEmailNotConfigured {
+		apiReturn.ErrorByCode(c, 1036)
+		return
+	}
+	global.Logger.Errorln("send email verification code:", err.Error())
+	apiReturn.ErrorByCode(c, 1037)
+}
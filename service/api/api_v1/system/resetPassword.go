@@ -0,0 +1,104 @@
+package system
+
+import (
+	"strings"
+	"sun-panel/api/api_v1/common/apiData/systemApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/cmn"
+	"sun-panel/lib/emailVCode"
+	"sun-panel/lib/mail"
+	"sun-panel/lib/password"
+	"sun-panel/lib/session"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// 忘记密码：发送验证码到账号绑定的邮箱
+// 无论账号是否存在都返回成功，避免泄露账号信息
+func (l LoginApi) SendResetPasswordVCode(c *gin.Context) {
+	req := systemApiStructs.LoginSendResetPasswordVCodeReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	if _, err := emailVCode.GetEmailer(); err != nil {
+		apiReturn.ErrorByCode(c, 1036)
+		return
+	}
+
+	if errCode, codeId := base.VerificationCheck(req.Verification.CodeID, req.Verification.VCode); errCode != apiReturn.ERROR_CODE_SUCCESS {
+		apiReturn.ErrorVerification(c, errCode, codeId)
+		return
+	}
+
+	account := strings.TrimSpace(req.Email)
+	if err := emailVCode.CheckAndCount(emailVCode.PURPOSE_RESET_PASSWORD, strings.ToLower(account), c.ClientIP()); err != nil {
+		apiReturn.ErrorByCode(c, 1035)
+		return
+	}
+
+	mUser := models.User{}
+	if userInfo, err := mUser.GetUserInfoByUsernameOrMail(account); err == nil && userInfo.Mail != "" {
+		// 异步发送，响应时间不因账号是否存在而不同
+		go func() {
+			if err := emailVCode.Send(emailVCode.PURPOSE_RESET_PASSWORD, resetPasswordVCodeKey(userInfo), userInfo.Mail, mail.SendResetPasswordVCode); err != nil {
+				global.Logger.Errorln("send reset password verification code:", err.Error())
+			}
+		}()
+	} else {
+		global.Logger.Infoln("reset password requested for unknown account:", account, "ip:", c.ClientIP())
+	}
+	apiReturn.Success(c)
+}
+
+// 忘记密码：使用邮箱验证码设置新密码，成功后退出全部会话
+func (l LoginApi) ResetPasswordByVCode(c *gin.Context) {
+	req := systemApiStructs.LoginResetPasswordByVCodeReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	mUser := models.User{}
+	userInfo, err := mUser.GetUserInfoByUsernameOrMail(strings.TrimSpace(req.Email))
+	if err != nil || !emailVCode.Verify(emailVCode.PURPOSE_RESET_PASSWORD, resetPasswordVCodeKey(userInfo), req.EmailVCode) {
+		apiReturn.ErrorByCode(c, 1034)
+		return
+	}
+
+	passwordHash, err := password.Hash(req.Password)
+	if err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
+	if err := mUser.UpdateUserInfoByUserId(userInfo.ID, map[string]interface{}{"password": passwordHash, "must_change_password": false}); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	if err := session.RevokeByUserIds([]uint{userInfo.ID}, 0); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	session.ClearUserCache(userInfo.ID)
+	global.Logger.Infoln("password reset by email, username:", userInfo.Username, "ip:", c.ClientIP())
+	apiReturn.Success(c)
+}
+
+// 重置密码验证码与用户绑定，使用账号或邮箱提交均可
+func resetPasswordVCodeKey(userInfo models.User) string {
+	return "user:" + cmn.UintToStr(userInfo.ID)
+}
@@ -0,0 +1,177 @@
+package system
+
+import (
+	"sun-panel/api/api_v1/common/apiData/commonApiStructs"
+	"sun-panel/api/api_v1/common/apiData/systemApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/audit"
+	"sun-panel/lib/session"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// 登录会话管理
+type SessionApi struct{}
+
+// 获取自己的会话列表
+func (a *SessionApi) GetList(c *gin.Context) {
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	currentSession, _ := base.GetCurrentSession(c)
+
+	list, err := buildSessionInfoList(userInfo.ID, currentSession.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.SuccessListData(c, list, int64(len(list)))
+}
+
+// 吊销自己的会话
+func (a *SessionApi) Revoke(c *gin.Context) {
+	req := commonApiStructs.RequestDeleteIds[uint]{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	if err := session.RevokeByIds(userInfo.ID, req.Ids); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.Success(c)
+}
+
+// 吊销当前会话以外的全部会话
+func (a *SessionApi) RevokeOthers(c *gin.Context) {
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	currentSession, exist := base.GetCurrentSession(c)
+	if !exist {
+		apiReturn.ErrorByCode(c, 1005)
+		return
+	}
+
+	if err := session.RevokeByUserIds([]uint{userInfo.ID}, currentSession.ID); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.Success(c)
+}
+
+// 管理员：获取指定用户的会话列表
+func (a *SessionApi) AdminGetList(c *gin.Context) {
+	req := struct {
+		UserId uint `json:"userId" validate:"required"`
+	}{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	if !base.CheckCanManageUsers(c, []uint{req.UserId}) {
+		return
+	}
+
+	currentSession, _ := base.GetCurrentSession(c)
+	list, err := buildSessionInfoList(req.UserId, currentSession.ID)
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.SuccessListData(c, list, int64(len(list)))
+}
+
+// 管理员：吊销任意用户的会话，ids为空时吊销userIds的全部会话
+func (a *SessionApi) AdminRevoke(c *gin.Context) {
+	req := systemApiStructs.SessionAdminRevokeReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+
+	if len(req.Ids) == 0 && len(req.UserIds) == 0 {
+		apiReturn.ErrorParamFomat(c, "ids or userIds is required")
+		return
+	}
+
+	// 按会话ID吊销时，验证会话所属的用户
+	userIds := req.UserIds
+	if len(req.Ids) > 0 {
+		mSession := models.UserSession{}
+		var err error
+		if userIds, err = mSession.GetUserIdsByIds(global.Db, req.Ids); err != nil {
+			apiReturn.ErrorDatabase(c, err.Error())
+			return
+		}
+	}
+	if !base.CheckCanManageUsers(c, userIds) {
+		return
+	}
+
+	// 吊销前获取会话，按用户记录审计日志
+	revokeSessions := []models.UserSession{}
+	query := global.Db.Select("id", "user_id")
+	if len(req.Ids) > 0 {
+		query = query.Where("id in ?", req.Ids)
+	} else {
+		query = query.Where("user_id in ?", req.UserIds)
+	}
+	if err := query.Find(&revokeSessions).Error; err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	var err error
+	if len(req.Ids) > 0 {
+		err = session.RevokeByIds(0, req.Ids)
+	} else {
+		err = session.RevokeByUserIds(req.UserIds, 0)
+	}
+	if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	userSessionIds := map[uint][]uint{}
+	for _, v := range revokeSessions {
+		userSessionIds[v.UserId] = append(userSessionIds[v.UserId], v.ID)
+	}
+	for userId, ids := range userSessionIds {
+		audit.Record(c, models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_USER, userId, gin.H{"sessionIds": ids}, gin.H{"sessionIds": []uint{}})
+	}
+	apiReturn.Success(c)
+}
+
+// 获取用户的会话列表，currentId为当前会话
+func buildSessionInfoList(userId, currentId uint) ([]systemApiStructs.SessionInfo, error) {
+	mSession := models.UserSession{}
+	list, err := mSession.GetListByUserId(global.Db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := []systemApiStructs.SessionInfo{}
+	for _, v := range list {
+		resp = append(resp, systemApiStructs.SessionInfo{
+			Id:         v.ID,
+			Device:     v.Device,
+			Ip:         v.Ip,
+			UserAgent:  v.UserAgent,
+			CreateTime: v.CreatedAt,
+			LastSeenAt: v.LastSeenAt,
+			Current:    currentId != 0 && v.ID == currentId,
+
+			ImpersonatedBy: v.ImpersonatedBy,
+			ExpiresAt:      v.ExpiresAt,
+		})
+	}
+	return resp, nil
+}
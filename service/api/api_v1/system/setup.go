@@ -0,0 +1,106 @@
+package system
+
+import (
+	"strings"
+	"sun-panel/api/api_v1/common/apiData/systemApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/audit"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/language"
+	"sun-panel/lib/password"
+	"sun-panel/lib/setup"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// 首次运行设置
+type SetupApi struct{}
+
+func (a SetupApi) GetStatus(c *gin.Context) {
+	apiReturn.SuccessData(c, gin.H{
+		"required":  setup.Required(),
+		"languages": language.Languages,
+	})
+}
+
+// 创建管理员并保存基础设置，成功后直接登录
+func (a SetupApi) Commit(c *gin.Context) {
+	req := systemApiStructs.SetupCommitReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+	if req.Language == "" {
+		req.Language = language.Languages[0]
+	}
+	lang, err := language.Load(req.Language)
+	if err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if req.Email != nil {
+		if errMsg, err := base.ValidateInputStruct(*req.Email); err != nil {
+			apiReturn.ErrorParamFomat(c, errMsg)
+			return
+		}
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Name == "" {
+		req.Name = req.Username
+	}
+	passwordHash, err := password.Hash(req.Password)
+	if err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
+
+	userInfo := models.User{
+		Username: req.Username,
+		Password: passwordHash,
+		Name:     req.Name,
+		Status:   models.USER_STATUS_ENABLE,
+		Role:     models.ROLE_ADMIN,
+	}
+	// 先保存设置，最后创建管理员，失败时可以重新提交
+	err = setup.Run(req.SetupToken, func() error {
+		appSetting := systemSetting.ApplicationSetting{}
+		global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_APPLICATION, &appSetting)
+		appSetting.WebSiteUrl = strings.TrimSuffix(req.WebSiteUrl, "/")
+		appSetting.Language = req.Language
+		if err := global.SystemSetting.Set(systemSetting.SYSTEM_APPLICATION, appSetting); err != nil {
+			return err
+		}
+		if req.Email != nil {
+			if err := global.SystemSetting.Set(systemSetting.SYSTEM_EMAIL, *req.Email); err != nil {
+				return err
+			}
+		}
+		return global.Db.Create(&userInfo).Error
+	})
+	if err == setup.ErrNotRequired {
+		apiReturn.ErrorByCode(c, 1072)
+		return
+	} else if err == setup.ErrInvalidToken {
+		apiReturn.ErrorByCode(c, 1071)
+		return
+	} else if err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	global.Lang = lang
+	global.Logger.Infoln("initial setup completed, administrator:", userInfo.Username, "ip:", c.ClientIP())
+
+	c.Set("userInfo", userInfo)
+	audit.Record(c, models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_USER, userInfo.ID, nil, userInfo)
+	loginSuccessReturn(c, userInfo)
+}
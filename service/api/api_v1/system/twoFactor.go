@@ -0,0 +1,181 @@
+package system
+
+import (
+	"sun-panel/api/api_v1/common/apiData/systemApiStructs"
+	"sun-panel/api/api_v1/common/apiReturn"
+	"sun-panel/api/api_v1/common/base"
+	"sun-panel/global"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/password"
+	"sun-panel/lib/permission"
+	"sun-panel/lib/totp"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+const TWO_FACTOR_ISSUER = "Sun-Panel"
+
+// 二次验证(TOTP)
+type TwoFactorApi struct{}
+
+func (a *TwoFactorApi) GetStatus(c *gin.Context) {
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	resp := systemApiStructs.TwoFactorStatusResp{
+		Required: twoFactorRequired(userInfo),
+	}
+
+	mTwoFactor := models.UserTwoFactor{}
+	if info, err := mTwoFactor.GetByUserId(global.Db, userInfo.ID); err == nil {
+		resp.Enabled = info.Enabled == models.INT_TURE
+		resp.RecoveryCodesCount = info.RecoveryCodesCount()
+	} else if err != gorm.ErrRecordNotFound {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+
+	apiReturn.SuccessData(c, resp)
+}
+
+// 生成待绑定的密钥和二维码
+func (a *TwoFactorApi) Generate(c *gin.Context) {
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	mTwoFactor := models.UserTwoFactor{}
+	twoFactor, err := mTwoFactor.GenerateSecret(global.Db, userInfo.ID)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	resp, err := buildTwoFactorSetupResp(userInfo, twoFactor.Secret)
+	if err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
+	apiReturn.SuccessData(c, resp)
+}
+
+// 确认绑定，返回恢复码
+func (a *TwoFactorApi) Enable(c *gin.Context) {
+	req := systemApiStructs.TwoFactorCodeReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	mTwoFactor := models.UserTwoFactor{}
+	recoveryCodes, err := mTwoFactor.Enable(global.Db, userInfo.ID, req.Code)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	apiReturn.SuccessData(c, gin.H{"recoveryCodes": recoveryCodes})
+}
+
+// 关闭二次验证，需要密码和验证码
+func (a *TwoFactorApi) Disable(c *gin.Context) {
+	req := systemApiStructs.TwoFactorDisableReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	if twoFactorRequired(userInfo) {
+		apiReturn.ErrorByCode(c, 1011)
+		return
+	}
+
+	mUser := models.User{}
+	if v, err := mUser.GetUserInfoByUid(userInfo.ID); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	} else if ok, _ := password.Verify(req.Password, v.Password); !ok {
+		apiReturn.ErrorByCode(c, 1007)
+		return
+	}
+
+	mTwoFactor := models.UserTwoFactor{}
+	if err := mTwoFactor.Verify(global.Db, userInfo.ID, req.Code); err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	if err := mTwoFactor.DeleteByUserIds(global.Db, []uint{userInfo.ID}); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	apiReturn.Success(c)
+}
+
+// 重新生成恢复码，旧的恢复码全部作废
+func (a *TwoFactorApi) RegenerateRecoveryCodes(c *gin.Context) {
+	req := systemApiStructs.TwoFactorCodeReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		apiReturn.ErrorParamFomat(c, err.Error())
+		return
+	}
+	if errMsg, err := base.ValidateInputStruct(req); err != nil {
+		apiReturn.ErrorParamFomat(c, errMsg)
+		return
+	}
+
+	userInfo, _ := base.GetCurrentUserInfo(c)
+	mTwoFactor := models.UserTwoFactor{}
+	if err := mTwoFactor.Verify(global.Db, userInfo.ID, req.Code); err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	recoveryCodes, err := mTwoFactor.RegenerateRecoveryCodes(global.Db, userInfo.ID)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+	apiReturn.SuccessData(c, gin.H{"recoveryCodes": recoveryCodes})
+}
+
+// 当前账号是否被要求启用二次验证
+func twoFactorRequired(userInfo models.User) bool {
+	settings := systemSetting.ApplicationSetting{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_APPLICATION, &settings)
+	return settings.AdminTwoFactorRequired && permission.IsAdmin(userInfo.Role)
+}
+
+func buildTwoFactorSetupResp(userInfo models.User, secret string) (systemApiStructs.TwoFactorSetupResp, error) {
+	resp := systemApiStructs.TwoFactorSetupResp{
+		Secret: secret,
+		Uri:    totp.ProvisioningURI(TWO_FACTOR_ISSUER, userInfo.Username, secret),
+	}
+	qrCode, err := totp.QRCodeDataURI(resp.Uri)
+	if err != nil {
+		return resp, err
+	}
+	resp.QrCode = qrCode
+	return resp, nil
+}
+
+func twoFactorError(c *gin.Context, err error) {
+	switch err {
+	case models.ErrTwoFactorCodeInvalid:
+		apiReturn.ErrorByCode(c, 1012)
+	case models.ErrTwoFactorAlreadyEnabled:
+		apiReturn.ErrorByCode(c, 1014)
+	case models.ErrTwoFactorNotEnabled:
+		apiReturn.ErrorByCode(c, 1015)
+	default:
+		apiReturn.ErrorDatabase(c, err.Error())
+	}
+}
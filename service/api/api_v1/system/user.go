@@ -6,6 +6,9 @@ import (
 	"sun-panel/api/api_v1/common/base"
 	"sun-panel/global"
 	"sun-panel/lib/cmn"
+	"sun-panel/lib/password"
+	"sun-panel/lib/permission"
+	"sun-panel/lib/session"
 	"sun-panel/models"
 
 	"github.com/gin-gonic/gin"
@@ -17,11 +20,15 @@ type UserApi struct{}
 func (a *UserApi) GetInfo(c *gin.Context) {
 	userInfo, _ := base.GetCurrentUserInfo(c)
 	apiReturn.SuccessData(c, gin.H{
-		"userId":    userInfo.ID,
-		"id":        userInfo.ID,
-		"headImage": userInfo.HeadImage,
-		"name":      userInfo.Name,
-		"role":      userInfo.Role,
+		"userId":      userInfo.ID,
+		"id":          userInfo.ID,
+		"headImage":   userInfo.HeadImage,
+		"name":        userInfo.Name,
+		"role":        userInfo.Role,
+		"permissions": permission.List(userInfo.Role),
+
+		"mustChangePassword": userInfo.MustChangePassword,
+		"impersonatedBy":     impersonationInfo(c),
 		// "token":     userInfo.Token,
 
 	})
@@ -37,11 +44,30 @@ func (a *UserApi) GetAuthInfo(c *gin.Context) {
 	user.Role = userInfo.Role
 	user.Username = userInfo.Username
 	apiReturn.SuccessData(c, gin.H{
-		"user":      user,
-		"visitMode": visitMode,
+		"user":        user,
+		"visitMode":   visitMode,
+		"permissions": permission.List(user.Role),
+
+		"impersonatedBy": impersonationInfo(c),
 	})
 }
 
+// 模拟登录时返回发起的管理员和会话到期时间，否则返回nil
+func impersonationInfo(c *gin.Context) gin.H {
+	adminId := base.GetImpersonatedBy(c)
+	if adminId == 0 {
+		return nil
+	}
+	adminInfo, _ := session.GetUser(adminId)
+	userSession, _ := base.GetCurrentSession(c)
+	return gin.H{
+		"id":        adminInfo.ID,
+		"username":  adminInfo.Username,
+		"name":      adminInfo.Name,
+		"expiresAt": userSession.ExpiresAt,
+	}
+}
+
 // 修改资料
 func (a *UserApi) UpdateInfo(c *gin.Context) {
 	userInfo, _ := base.GetCurrentUserInfo(c)
@@ -68,7 +94,7 @@ func (a *UserApi) UpdateInfo(c *gin.Context) {
 		"name":       params.Name,
 	})
 	// 删除缓存
-	global.UserToken.Delete(userInfo.Token)
+	session.ClearUserCache(userInfo.ID)
 	if err != nil {
 		apiReturn.ErrorDatabase(c, err.Error())
 	}
@@ -95,22 +121,31 @@ func (a *UserApi) UpdatePasssword(c *gin.Context) {
 		apiReturn.ErrorParamFomat(c, err.Error())
 		return
 	} else {
-		if v.Password != cmn.PasswordEncryption(params.OldPassword) {
+		if ok, _ := password.Verify(params.OldPassword, v.Password); !ok {
 			// 旧密码不正确
 			apiReturn.ErrorByCode(c, 1007)
 			return
 		}
 	}
+	passwordHash, err := password.Hash(params.NewPassword)
+	if err != nil {
+		apiReturn.Error(c, err.Error())
+		return
+	}
 	res := global.Db.Model(&models.User{}).Where("id", userInfo.ID).Updates(map[string]interface{}{
-		"password": cmn.PasswordEncryption(params.NewPassword),
-		"token":    "",
+		"password":             passwordHash,
+		"must_change_password": false,
 	})
 	if res.Error != nil {
 		apiReturn.ErrorDatabase(c, res.Error.Error())
 		return
 	}
-	// 删除token
-	global.UserToken.Delete(userInfo.Token)
+	// 修改密码后退出全部会话
+	if err := session.RevokeByUserIds([]uint{userInfo.ID}, 0); err != nil {
+		apiReturn.ErrorDatabase(c, err.Error())
+		return
+	}
+	session.ClearUserCache(userInfo.ID)
 	apiReturn.Success(c)
 }
 
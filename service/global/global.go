@@ -26,8 +26,8 @@ var (
 var (
 	Lang *language.LangStructObj
 
-	UserToken           cache.Cacher[models.User]
-	CUserToken          cache.Cacher[string] // 用户token
+	UserToken           cache.Cacher[models.User]        // 用户信息，key为用户ID
+	CUserToken          cache.Cacher[models.UserSession] // 登录会话，key为token的哈希
 	Logger              *zap.SugaredLogger
 	LoggerLevel         = zap.NewAtomicLevel() // 支持通过http以及配置文件动态修改日志级别
 	VerifyCodeCachePool cache.Cacher[string]
@@ -37,4 +37,13 @@ var (
 	SystemSetting       *systemSetting.SystemSettingCache
 	SystemMonitor       cache.Cacher[interface{}]
 	RateLimit           *RateLimiter
+	LoginChallenge      cache.Cacher[LoginChallengeInfo]   // 二次验证的登录挑战
+	OidcState           cache.Cacher[OidcStateInfo]        // OIDC登录状态
+	ProxyAuthUser       cache.Cacher[models.User]          // 反向代理认证的用户
+	LoginFailure        cache.Cacher[LoginFailureInfo]     // 登录失败计数
+	EmailVCodeLimit     cache.Cacher[int]                  // 邮箱验证码发送频率和验证次数
+	RoleCache           cache.Cacher[models.Role]          // 角色和权限，key为角色ID
+	PasskeyChallenge    cache.Cacher[PasskeyChallengeInfo] // 通行密钥注册和登录的挑战
+	MagicLinkNonce      cache.Cacher[uint]                 // 邮件登录链接的随机数，值为用户ID，使用后删除
+	NetworkZoneCache    cache.Cacher[[]models.NetworkZone] // 全部网络区域
 )
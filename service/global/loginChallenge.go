@@ -0,0 +1,8 @@
+package global
+
+// 二次验证的登录挑战，密码验证通过后下发，完成二次验证后换取登录token
+type LoginChallengeInfo struct {
+	UserId   uint `json:"userId"`
+	Enroll   bool `json:"enroll"`   // 是否为强制绑定二次验证
+	Attempts int  `json:"attempts"` // 已失败的次数
+}
@@ -0,0 +1,9 @@
+package global
+
+import "time"
+
+// 登录失败计数，key为账号或IP
+type LoginFailureInfo struct {
+	Failures      int       `json:"failures"`
+	NextAllowedAt time.Time `json:"nextAllowedAt"` // 退避延迟，在此之前不允许再次尝试
+}
@@ -0,0 +1,9 @@
+package global
+
+// OIDC登录发起时保存的状态，回调时使用state取出
+type OidcStateInfo struct {
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+	RedirectUrl  string `json:"redirectUrl"`
+	BindUserId   uint   `json:"bindUserId"` // 不为0时将身份绑定到该账号，而不是登录
+}
@@ -0,0 +1,9 @@
+package global
+
+import "github.com/go-webauthn/webauthn/webauthn"
+
+// 通行密钥注册或登录的挑战
+type PasskeyChallengeInfo struct {
+	UserId  uint                 `json:"userId"` // 注册或指定账号登录时的用户，无账号登录时为0
+	Session webauthn.SessionData `json:"session"`
+}
@@ -15,6 +15,11 @@ import (
 	"sun-panel/initialize/systemSettingCache"
 	"sun-panel/initialize/userToken"
 	"sun-panel/lib/cmn"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/healthCheck"
+	"sun-panel/lib/language"
+	"sun-panel/lib/password"
+	"sun-panel/lib/setup"
 	"sun-panel/models"
 	"sun-panel/structs"
 	"time"
@@ -30,7 +35,6 @@ var DB_DRIVER = database.SQLITE
 // var ISDOCER = "" // 是否为docker模式
 
 func InitApp() error {
-	Logo()
 	gin.SetMode(global.RUNCODE) // GIN 运行模式
 
 	// 日志
@@ -41,9 +45,11 @@ func InitApp() error {
 		global.Logger = logger
 	}
 
-	// 命令行运行
+	// 命令行运行，输出可能被脚本解析，在Logo之前执行
 	CommandRun()
 
+	Logo()
+
 	// 配置初始化
 	{
 		if config, err := config.ConfigInit(); err != nil {
@@ -59,28 +65,7 @@ func InitApp() error {
 
 	DatabaseConnect()
 
-	// Redis 连接
-	{
-		// 判断是否有使用redis的驱动，没有将不连接
-		cacheDrive := global.Config.GetValueString("base", "cache_drive")
-		queueDrive := global.Config.GetValueString("base", "queue_drive")
-		if cacheDrive == "redis" || queueDrive == "redis" {
-			redisConfig := structs.IniConfigRedis{}
-			global.Config.GetSection("redis", &redisConfig)
-			rdb, err := redis.InitRedis(redis.Options{
-				Addr:     redisConfig.Address,
-				Password: redisConfig.Password,
-				DB:       redisConfig.Db,
-			})
-
-			if err != nil {
-				log.Panicln("Redis initialization error", err)
-				panic(err)
-				// return err
-			}
-			global.RedisDb = rdb
-		}
-	}
+	RedisConnect()
 
 	// 初始化用户token
 	global.UserToken = userToken.InitUserToken()
@@ -90,6 +75,35 @@ func InitApp() error {
 	global.VerifyCodeCachePool = other.InitVerifyCodeCachePool()
 	global.SystemSetting = systemSettingCache.InItSystemSettingCache()
 	global.SystemMonitor = global.NewCache[interface{}](5*time.Hour, -1, "systemMonitorCache")
+	global.LoginChallenge = global.NewCache[global.LoginChallengeInfo](5*time.Minute, 10*time.Minute, "LoginChallenge")
+	global.OidcState = global.NewCache[global.OidcStateInfo](10*time.Minute, 20*time.Minute, "OidcState")
+	global.ProxyAuthUser = global.NewCache[models.User](1*time.Minute, 5*time.Minute, "ProxyAuthUser")
+	global.LoginFailure = global.NewCache[global.LoginFailureInfo](15*time.Minute, 30*time.Minute, "LoginFailure")
+	global.EmailVCodeLimit = global.NewCache[int](1*time.Hour, 10*time.Minute, "EmailVCodeLimit")
+	global.RoleCache = global.NewCache[models.Role](10*time.Minute, 20*time.Minute, "RoleCache")
+	global.PasskeyChallenge = global.NewCache[global.PasskeyChallengeInfo](5*time.Minute, 10*time.Minute, "PasskeyChallenge")
+	global.MagicLinkNonce = global.NewCache[uint](15*time.Minute, 30*time.Minute, "MagicLinkNonce")
+	global.NetworkZoneCache = global.NewCache[[]models.NetworkZone](10*time.Minute, 20*time.Minute, "NetworkZoneCache")
+
+	// 使用设置的系统语言
+	appSetting := systemSetting.ApplicationSetting{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_APPLICATION, &appSetting)
+	if appSetting.Language != "" {
+		if langObj, err := language.Load(appSetting.Language); err == nil {
+			global.Lang = langObj
+		} else {
+			global.Logger.Errorln("load language:", appSetting.Language, err.Error())
+		}
+	}
+
+	// 没有任何用户时进入首次设置
+	if err := setup.Init(); err != nil {
+		global.Logger.Errorln("Initial setup check error", err)
+		return err
+	}
+
+	// 图标地址的定时健康检测
+	healthCheck.Start()
 
 	return nil
 }
@@ -122,8 +136,29 @@ func DatabaseConnect() {
 	}
 
 	database.CreateDatabase(databaseDrive, global.Db)
+}
 
-	database.NotFoundAndCreateUser(global.Db)
+// Redis 连接
+func RedisConnect() {
+	// 判断是否有使用redis的驱动，没有将不连接
+	cacheDrive := global.Config.GetValueString("base", "cache_drive")
+	queueDrive := global.Config.GetValueString("base", "queue_drive")
+	if cacheDrive == "redis" || queueDrive == "redis" {
+		redisConfig := structs.IniConfigRedis{}
+		global.Config.GetSection("redis", &redisConfig)
+		rdb, err := redis.InitRedis(redis.Options{
+			Addr:     redisConfig.Address,
+			Password: redisConfig.Password,
+			DB:       redisConfig.Db,
+		})
+
+		if err != nil {
+			log.Panicln("Redis initialization error", err)
+			panic(err)
+			// return err
+		}
+		global.RedisDb = rdb
+	}
 }
 
 // 命令行运行
@@ -134,7 +169,11 @@ func CommandRun() {
 	)
 
 	flag.BoolVar(&cfg, "config", false, "Generate configuration file")
-	flag.BoolVar(&pwd, "password-reset", false, "Reset the password of the first user")
+	flag.BoolVar(&pwd, "password-reset", false, "Reset the password of the first administrator to a random password")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: sun-panel [options]\n       sun-panel user <command> [options]    (see \"sun-panel user help\")\n\nOptions:")
+		flag.PrintDefaults()
+	}
 
 	flag.Parse()
 
@@ -146,35 +185,31 @@ func CommandRun() {
 		fmt.Println("The configuration file has been created  conf/conf.ini ", "Please modify according to your own needs")
 		os.Exit(0) // 务必退出
 	} else if pwd {
-		// 重置密码
-
-		// 配置初始化
-		config, _ := config.ConfigInit()
-		global.Config = config
-
-		DatabaseConnect()
+		// 重置第一个管理员的密码，密码随机生成
+		if err := commandInit(); err != nil {
+			fmt.Println("ERROR", err.Error())
+			os.Exit(1)
+		}
 		userInfo := models.User{}
-		if err := global.Db.Where("role=?", 1).Order("id").First(&userInfo).Error; err != nil {
+		if err := global.Db.Where("role=?", models.ROLE_ADMIN).Order("id").First(&userInfo).Error; err != nil {
 			fmt.Println("ERROR", err.Error())
-			os.Exit(0) // 务必退出
+			os.Exit(1)
 		}
-
-		newPassword := "12345678"
-
-		updateInfo := models.User{
-			Password: cmn.PasswordEncryption(newPassword),
-			Token:    "",
+		newPassword, err := password.Random(randomPasswordLength)
+		if err == nil {
+			err = resetPassword(userInfo, newPassword, false)
 		}
-		// 重置第一个管理员的密码
-		if err := global.Db.Select("Password", "Token").Where("id=?", userInfo.ID).Updates(&updateInfo).Error; err != nil {
+		if err != nil {
 			fmt.Println("ERROR", err.Error())
-			os.Exit(0) // 务必退出
+			os.Exit(1)
 		}
 
 		fmt.Println("The password has been successfully reset. Here is the account information")
-		fmt.Println("Username ", userInfo.Username)
-		fmt.Println("Password ", newPassword)
+		printAccount(userInfo, "", newPassword, false)
 		os.Exit(0) // 务必退出
+	} else if flag.Arg(0) == "user" {
+		// 用户管理
+		os.Exit(userCommandRun(flag.Args()[1:]))
 	} else {
 		return
 	}
@@ -3,12 +3,14 @@ package cUserToken
 import (
 	"sun-panel/global"
 	"sun-panel/lib/cache"
+	"sun-panel/models"
 
 	"time"
 )
 
-func InitCUserToken() cache.Cacher[string] {
-	return global.NewCache[string](72*time.Hour, 48*time.Hour, "CUserToken")
+// 登录会话缓存，key为token的哈希，会话本身保存在数据库中
+func InitCUserToken() cache.Cacher[models.UserSession] {
+	return global.NewCache[models.UserSession](10*time.Minute, 20*time.Minute, "CUserToken")
 }
 
 // func InitVerifyCodeCachePool() {
@@ -119,34 +119,50 @@ func CreateDatabase(driver string, db *gorm.DB) error {
 		&models.User{},
 		&models.SystemSetting{},
 		&models.ItemIcon{},
+		&models.ItemIconHealthCheck{},
+		&models.NetworkZone{},
+		&models.ItemIconZoneUrl{},
+		&models.Tag{},
+		&models.ItemIconTag{},
+		&models.TagView{},
 		&models.UserConfig{},
 		&models.File{},
 		&models.ItemIconGroup{},
 		&models.ModuleConfig{},
+		&models.UserTwoFactor{},
+		&models.UserApiToken{},
+		&models.UserSession{},
+		&models.LoginLockout{},
+		&models.Role{},
+		&models.Notice{},
+		&models.Team{},
+		&models.TeamMember{},
+		&models.AuditLog{},
+		&models.UserPasskey{},
+		&models.UserOidcIdentity{},
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
-}
+	// 内置角色，ID与旧版本的角色值(1.管理员 2.普通用户)一致，已有用户无需迁移
+	mRole := models.Role{}
+	if err := mRole.CreateBuiltIn(db); err != nil {
+		return err
+	}
 
-// 初始化一个用户,一个用户都没有的时候创建一个
-func NotFoundAndCreateUser(db *gorm.DB) error {
-	fUser := models.User{}
-	if err := db.First(&fUser).Error; err != nil {
-		if err != gorm.ErrRecordNotFound {
-			return err
-		}
-		username := "xianyu"
-		fUser.Mail = username
-		fUser.Username = username
-		fUser.Name = username
-		fUser.Status = 1
-		fUser.Role = 1
-		fUser.Password = cmn.PasswordEncryption("12345678")
-
-		if errCreate := db.Create(&fUser).Error; errCreate != nil {
-			return errCreate
-		}
+	// 旧版本密码参与了联合索引，密码列加长后不再需要
+	if db.Migrator().HasIndex(&models.User{}, "idx_username_password") {
+		err = db.Migrator().DropIndex(&models.User{}, "idx_username_password")
+	}
+	if err != nil {
+		return err
 	}
 
-	return nil
+	// 旧版本的明文token，已改为会话表中保存哈希
+	if db.Migrator().HasColumn(&models.User{}, "token") {
+		err = db.Migrator().DropColumn(&models.User{}, "token")
+	}
+
+	return err
 }
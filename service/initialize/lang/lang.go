@@ -2,9 +2,12 @@ package lang
 
 import (
 	"os"
+	"sun-panel/assets"
 	"sun-panel/global"
 	"sun-panel/lib/cmn"
 	"sun-panel/lib/language"
+
+	"gopkg.in/ini.v1"
 )
 
 func LangInit(lang string) {
@@ -15,8 +18,8 @@ func LangInit(lang string) {
 		os.Exit(1)
 	}
 
-	// 生成语言文件
-	if !exists {
+	// 生成语言文件，内置语言文件版本更新后重新输出
+	if !exists || langFileOutdated(filename) {
 		global.Logger.Infoln("输出语言文件:", filename)
 		err := cmn.AssetsTakeFileToPath("lang/zh-cn.ini", "lang/zh-cn.ini")
 		if err != nil {
@@ -37,3 +40,20 @@ func LangInit(lang string) {
 
 	global.Lang = language.NewLang(filename)
 }
+
+// 已输出的语言文件版本低于内置的版本
+func langFileOutdated(filename string) bool {
+	content, err := assets.Asset("assets/" + filename)
+	if err != nil {
+		return false
+	}
+	builtin, err := ini.Load(content)
+	if err != nil {
+		return false
+	}
+	current, err := ini.Load(filename)
+	if err != nil {
+		return true
+	}
+	return current.Section("lang_info").Key("version").MustFloat64() < builtin.Section("lang_info").Key("version").MustFloat64()
+}
@@ -0,0 +1,487 @@
+package initialize
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sun-panel/global"
+	"sun-panel/initialize/cUserToken"
+	"sun-panel/initialize/config"
+	"sun-panel/initialize/systemSettingCache"
+	"sun-panel/initialize/userToken"
+	"sun-panel/lib/audit"
+	"sun-panel/lib/password"
+	"sun-panel/lib/session"
+	"sun-panel/models"
+	"text/tabwriter"
+	"time"
+)
+
+// 命令行管理用户，只连接数据库(和Redis)，不启动HTTP服务
+// 在运行中的服务上，使用内存缓存时停用账号、修改角色、吊销会话最迟一分钟后生效
+
+const randomPasswordLength = 12
+
+var errLastAdmin = errors.New("at least one enabled administrator must be kept")
+
+const userCommandUsage = `Usage: sun-panel user <command> [options]
+
+Commands:
+  list                                   List all users
+  create <username> [--role <role>] [--name <name>] [--prompt] [--must-change]
+                                         Create a user, the password is random unless --prompt is given
+  reset-password <username> [--prompt] [--must-change]
+                                         Reset the password, random unless --prompt is given
+  disable <username>                     Disable the account and sign it out
+  enable <username>                      Enable the account
+  set-role <username> <role>             Change the role of the account
+  revoke-sessions <username>             Sign out all sessions of the account
+
+<role> can be a role ID or a role name, see "list" for the roles of existing users.
+--prompt reads the password from the standard input, e.g. echo "$PASS" | sun-panel user reset-password admin --prompt
+`
+
+// 执行 user 子命令，返回进程退出码
+func userCommandRun(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Print(userCommandUsage)
+		return 0
+	}
+
+	commands := map[string]func(args []string) error{
+		"list":            userList,
+		"create":          userCreate,
+		"reset-password":  userResetPassword,
+		"disable":         userDisable,
+		"enable":          userEnable,
+		"set-role":        userSetRole,
+		"revoke-sessions": userRevokeSessions,
+	}
+	fn, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Unknown command:", args[0])
+		fmt.Fprint(os.Stderr, userCommandUsage)
+		return 2
+	}
+
+	if err := commandInit(); err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR", err.Error())
+		return 1
+	}
+	if err := fn(args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR", err.Error())
+		return 1
+	}
+	return 0
+}
+
+// 命令行运行时需要的初始化：配置、数据库和缓存
+func commandInit() error {
+	cfg, err := config.ConfigInit()
+	if err != nil {
+		return err
+	}
+	global.Config = cfg
+	DatabaseConnect()
+	RedisConnect()
+
+	global.UserToken = userToken.InitUserToken()
+	global.CUserToken = cUserToken.InitCUserToken()
+	global.SystemSetting = systemSettingCache.InItSystemSettingCache()
+	return nil
+}
+
+// 解析参数，选项可以写在位置参数的前面或后面，返回位置参数
+func parseCommandArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// 解析参数并验证位置参数的数量
+func parseCommandArgsN(fs *flag.FlagSet, args []string, names ...string) ([]string, error) {
+	positional, err := parseCommandArgs(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(positional) != len(names) {
+		return nil, fmt.Errorf("expected arguments: <%s>", strings.Join(names, "> <"))
+	}
+	return positional, nil
+}
+
+func userList(args []string) error {
+	fs := flag.NewFlagSet("user list", flag.ContinueOnError)
+	asJson := fs.Bool("json", false, "Output as JSON")
+	if _, err := parseCommandArgsN(fs, args); err != nil {
+		return err
+	}
+
+	users := []models.User{}
+	if err := global.Db.Omit("Password").Order("id").Find(&users).Error; err != nil {
+		return err
+	}
+	roleNames, err := getRoleNames()
+	if err != nil {
+		return err
+	}
+
+	if *asJson {
+		type userItem struct {
+			Id                 uint       `json:"id"`
+			Username           string     `json:"username"`
+			Name               string     `json:"name"`
+			Mail               string     `json:"mail"`
+			Role               int        `json:"role"`
+			RoleName           string     `json:"roleName"`
+			Status             int        `json:"status"`
+			ExpireAt           *time.Time `json:"expireAt"`
+			MustChangePassword bool       `json:"mustChangePassword"`
+		}
+		list := []userItem{}
+		for _, v := range users {
+			list = append(list, userItem{
+				Id:                 v.ID,
+				Username:           v.Username,
+				Name:               v.Name,
+				Mail:               v.Mail,
+				Role:               v.Role,
+				RoleName:           roleNames[v.Role],
+				Status:             v.Status,
+				ExpireAt:           v.ExpireAt,
+				MustChangePassword: v.MustChangePassword,
+			})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(list)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSERNAME\tNAME\tROLE\tSTATUS\tEXPIRE AT")
+	for _, v := range users {
+		expireAt := "-"
+		if v.ExpireAt != nil {
+			expireAt = v.ExpireAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", v.ID, v.Username, v.Name, fmt.Sprintf("%s(%d)", roleNames[v.Role], v.Role), userStatusText(v), expireAt)
+	}
+	return w.Flush()
+}
+
+func userCreate(args []string) error {
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	role := fs.String("role", strconv.Itoa(models.ROLE_USER), "Role ID or name")
+	name := fs.String("name", "", "Display name, defaults to the username")
+	prompt := fs.Bool("prompt", false, "Read the password from the standard input")
+	mustChange := fs.Bool("must-change", false, "Require a password change after the first login")
+	positional, err := parseCommandArgsN(fs, args, "username")
+	if err != nil {
+		return err
+	}
+
+	username := strings.TrimSpace(positional[0])
+	if len(username) < 5 || len(username) > 50 {
+		return errors.New("the username must be 5 to 50 characters long")
+	}
+	mUser := models.User{}
+	if _, err := mUser.CheckUsernameExist(username); err != nil {
+		return fmt.Errorf("the username %s already exists", username)
+	}
+	roleInfo, err := findRole(*role)
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = username
+	}
+
+	pwd, err := getNewPassword(*prompt)
+	if err != nil {
+		return err
+	}
+	passwordHash, err := password.Hash(pwd)
+	if err != nil {
+		return err
+	}
+
+	userInfo := models.User{
+		Username:           username,
+		Password:           passwordHash,
+		Name:               *name,
+		Status:             models.USER_STATUS_ENABLE,
+		Role:               int(roleInfo.ID),
+		MustChangePassword: *mustChange,
+	}
+	if err := global.Db.Create(&userInfo).Error; err != nil {
+		return err
+	}
+	audit.RecordCommand(models.AUDIT_ACTION_CREATE, models.AUDIT_TARGET_USER, userInfo.ID, nil, userInfo)
+
+	fmt.Println("The user has been created")
+	printAccount(userInfo, roleInfo.Name, pwd, *prompt)
+	return nil
+}
+
+func userResetPassword(args []string) error {
+	fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	prompt := fs.Bool("prompt", false, "Read the password from the standard input")
+	mustChange := fs.Bool("must-change", false, "Require a password change after the next login")
+	positional, err := parseCommandArgsN(fs, args, "username")
+	if err != nil {
+		return err
+	}
+	userInfo, err := findUser(positional[0])
+	if err != nil {
+		return err
+	}
+	pwd, err := getNewPassword(*prompt)
+	if err != nil {
+		return err
+	}
+	if err := resetPassword(userInfo, pwd, *mustChange); err != nil {
+		return err
+	}
+
+	fmt.Println("The password has been reset and all sessions have been signed out")
+	printAccount(userInfo, "", pwd, *prompt)
+	return nil
+}
+
+func userDisable(args []string) error {
+	return setUserStatus("user disable", args, models.USER_STATUS_DISABLE)
+}
+
+func userEnable(args []string) error {
+	return setUserStatus("user enable", args, models.USER_STATUS_ENABLE)
+}
+
+func setUserStatus(command string, args []string, status int) error {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	positional, err := parseCommandArgsN(fs, args, "username")
+	if err != nil {
+		return err
+	}
+	userInfo, err := findUser(positional[0])
+	if err != nil {
+		return err
+	}
+	if userInfo.Status == status {
+		fmt.Println("Nothing to change, the account is already", userStatusText(userInfo))
+		return nil
+	}
+	if status == models.USER_STATUS_DISABLE && userInfo.Role == models.ROLE_ADMIN {
+		if err := keepOneActiveAdminCommand(userInfo.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := global.Db.Model(&models.User{}).Where("id=?", userInfo.ID).Update("status", status).Error; err != nil {
+		return err
+	}
+	if status == models.USER_STATUS_DISABLE {
+		// 与管理接口一致，会话保留，账号停用后会话无效
+		if err := session.ClearUserAndSessionCache([]uint{userInfo.ID}); err != nil {
+			return err
+		}
+	} else {
+		session.ClearUserCache(userInfo.ID)
+	}
+	after := userInfo
+	after.Status = status
+	audit.RecordCommand(models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_USER, userInfo.ID, userInfo, after)
+
+	fmt.Printf("The account %s is now %s\n", userInfo.Username, userStatusText(after))
+	return nil
+}
+
+func userSetRole(args []string) error {
+	fs := flag.NewFlagSet("user set-role", flag.ContinueOnError)
+	positional, err := parseCommandArgsN(fs, args, "username", "role")
+	if err != nil {
+		return err
+	}
+	userInfo, err := findUser(positional[0])
+	if err != nil {
+		return err
+	}
+	roleInfo, err := findRole(positional[1])
+	if err != nil {
+		return err
+	}
+	if userInfo.Role == int(roleInfo.ID) {
+		fmt.Println("Nothing to change, the role is already", roleInfo.Name)
+		return nil
+	}
+	if userInfo.Role == models.ROLE_ADMIN {
+		if err := keepOneActiveAdminCommand(userInfo.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := global.Db.Model(&models.User{}).Where("id=?", userInfo.ID).Update("role", roleInfo.ID).Error; err != nil {
+		return err
+	}
+	session.ClearUserCache(userInfo.ID)
+	after := userInfo
+	after.Role = int(roleInfo.ID)
+	audit.RecordCommand(models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_USER, userInfo.ID, userInfo, after)
+
+	fmt.Printf("The role of %s is now %s(%d)\n", userInfo.Username, roleInfo.Name, roleInfo.ID)
+	return nil
+}
+
+func userRevokeSessions(args []string) error {
+	fs := flag.NewFlagSet("user revoke-sessions", flag.ContinueOnError)
+	positional, err := parseCommandArgsN(fs, args, "username")
+	if err != nil {
+		return err
+	}
+	userInfo, err := findUser(positional[0])
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	if err := global.Db.Model(&models.UserSession{}).Where("user_id=?", userInfo.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if err := session.RevokeByUserIds([]uint{userInfo.ID}, 0); err != nil {
+		return err
+	}
+	fmt.Printf("%d session(s) of %s have been signed out\n", count, userInfo.Username)
+	return nil
+}
+
+// 重置密码并退出该账号的全部会话
+func resetPassword(userInfo models.User, pwd string, mustChange bool) error {
+	passwordHash, err := password.Hash(pwd)
+	if err != nil {
+		return err
+	}
+	updateInfo := models.User{
+		Password:           passwordHash,
+		MustChangePassword: mustChange,
+	}
+	if err := global.Db.Select("Password", "MustChangePassword").Where("id=?", userInfo.ID).Updates(&updateInfo).Error; err != nil {
+		return err
+	}
+	if err := session.RevokeByUserIds([]uint{userInfo.ID}, 0); err != nil {
+		return err
+	}
+	session.ClearUserCache(userInfo.ID)
+
+	after := userInfo
+	after.Password = passwordHash
+	after.MustChangePassword = mustChange
+	audit.RecordCommand(models.AUDIT_ACTION_UPDATE, models.AUDIT_TARGET_USER, userInfo.ID, userInfo, after)
+	return nil
+}
+
+func findUser(username string) (models.User, error) {
+	mUser := models.User{}
+	userInfo, err := mUser.GetUserInfoByUsername(strings.TrimSpace(username))
+	if err != nil || userInfo.ID == 0 {
+		return userInfo, fmt.Errorf("the user %s does not exist", username)
+	}
+	return userInfo, nil
+}
+
+// 根据角色ID或名称(不区分大小写)查找角色
+func findRole(value string) (models.Role, error) {
+	mRole := models.Role{}
+	if id, err := strconv.Atoi(value); err == nil {
+		roleInfo, err := mRole.GetById(global.Db, id)
+		if err != nil {
+			return roleInfo, fmt.Errorf("the role %s does not exist", value)
+		}
+		return roleInfo, nil
+	}
+	roles, err := mRole.GetList(global.Db)
+	if err != nil {
+		return models.Role{}, err
+	}
+	for _, v := range roles {
+		if strings.EqualFold(v.Name, value) {
+			return v, nil
+		}
+	}
+	return models.Role{}, fmt.Errorf("the role %s does not exist", value)
+}
+
+func getRoleNames() (map[int]string, error) {
+	mRole := models.Role{}
+	roles, err := mRole.GetList(global.Db)
+	if err != nil {
+		return nil, err
+	}
+	names := map[int]string{}
+	for _, v := range roles {
+		names[int(v.ID)] = v.Name
+	}
+	return names, nil
+}
+
+func keepOneActiveAdminCommand(userId uint) error {
+	mUser := models.User{}
+	if count, err := mUser.CountActiveAdmins(global.Db, []uint{userId}); err != nil {
+		return err
+	} else if count == 0 {
+		return errLastAdmin
+	}
+	return nil
+}
+
+// 获取新密码，prompt为true时从标准输入读取，否则随机生成
+func getNewPassword(prompt bool) (string, error) {
+	if !prompt {
+		return password.Random(randomPasswordLength)
+	}
+	fmt.Fprint(os.Stderr, "New password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no password was entered")
+	}
+	pwd := strings.TrimRight(line, "\r\n")
+	if len(pwd) < 6 || len(pwd) > 50 {
+		return "", errors.New("the password must be 6 to 50 characters long")
+	}
+	return pwd, nil
+}
+
+// 输出账号信息，密码为输入的时不再显示
+func printAccount(userInfo models.User, roleName, pwd string, prompt bool) {
+	fmt.Println("Username ", userInfo.Username)
+	if roleName != "" {
+		fmt.Println("Role     ", roleName)
+	}
+	if !prompt {
+		fmt.Println("Password ", pwd)
+	}
+}
+
+func userStatusText(userInfo models.User) string {
+	switch {
+	case userInfo.Status == models.USER_STATUS_DISABLE:
+		return "disabled"
+	case userInfo.Status == models.USER_STATUS_NOT_ACTIVE:
+		return "not activated"
+	case userInfo.IsExpired():
+		return "expired"
+	default:
+		return "enabled"
+	}
+}
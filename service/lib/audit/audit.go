@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sun-panel/global"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/models"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const cleanupInterval = time.Hour // 清理过期日志的最小间隔
+
+const COMMAND_USERNAME = "cli" // 命令行操作的操作人，账号不少于5个字符不会与真实账号重复
+
+// 敏感字段(小写的完整字段名)，只记录是否修改，不记录内容
+// 不按包含匹配，避免 mustChangePassword 等普通字段被隐藏
+var sensitiveFields = map[string]bool{"password": true, "token": true, "tokenhash": true, "secret": true}
+
+// 不记录的字段
+var ignoreFields = []string{"ID", "CreatedAt", "UpdatedAt", "DeletedAt", "createTime", "updateTime", "user"}
+
+var (
+	lastCleanup   time.Time
+	lastCleanupMu sync.Mutex
+)
+
+// 获取审计日志设置，未设置时使用默认值
+func GetSetting() systemSetting.AuditLog {
+	setting := systemSetting.DefaultAuditLog()
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_AUDIT_LOG, &setting)
+	return setting
+}
+
+// 记录一条审计日志，操作人为当前登录用户
+// before和after为修改前后的对象，创建时before为nil，删除时after为nil，修改时只记录有变化的字段
+func Record(c *gin.Context, action, targetType string, targetId interface{}, before, after interface{}) {
+	info := models.AuditLog{
+		Ip:         c.ClientIP(),
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   fmt.Sprint(targetId),
+	}
+	// 与base.GetCurrentUserInfo相同，lib不依赖api包
+	if value, exist := c.Get("userInfo"); exist {
+		if userInfo, ok := value.(models.User); ok {
+			info.UserId = userInfo.ID
+			info.Username = userInfo.Username
+		}
+	}
+
+	save(info, before, after)
+}
+
+// 记录命令行执行的操作，操作人记为 cli
+func RecordCommand(action, targetType string, targetId interface{}, before, after interface{}) {
+	save(models.AuditLog{
+		Username:   COMMAND_USERNAME,
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   fmt.Sprint(targetId),
+	}, before, after)
+}
+
+func save(info models.AuditLog, before, after interface{}) {
+	beforeMap, afterMap := diff(toMap(before), toMap(after))
+	if info.Action == models.AUDIT_ACTION_UPDATE && len(beforeMap) == 0 && len(afterMap) == 0 {
+		return
+	}
+	info.Before = toJson(beforeMap)
+	info.After = toJson(afterMap)
+
+	if err := global.Db.Create(&info).Error; err != nil {
+		global.Logger.Errorln("audit log:", err.Error())
+	}
+	cleanup()
+}
+
+// 删除超过保留天数的日志，每小时最多执行一次
+func cleanup() {
+	lastCleanupMu.Lock()
+	if time.Since(lastCleanup) < cleanupInterval {
+		lastCleanupMu.Unlock()
+		return
+	}
+	lastCleanup = time.Now()
+	lastCleanupMu.Unlock()
+
+	if err := Cleanup(); err != nil {
+		global.Logger.Errorln("audit log cleanup:", err.Error())
+	}
+}
+
+// 立即删除超过保留天数的日志
+func Cleanup() error {
+	setting := GetSetting()
+	if setting.RetentionDays <= 0 {
+		return nil
+	}
+	mAuditLog := models.AuditLog{}
+	return mAuditLog.DeleteBefore(global.Db, time.Now().AddDate(0, 0, -setting.RetentionDays))
+}
+
+// 对象转为字段map，nil返回nil
+func toMap(v interface{}) map[string]interface{} {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil
+	}
+	res := map[string]interface{}{}
+	b, err := json.Marshal(v)
+	if err != nil || json.Unmarshal(b, &res) != nil {
+		return map[string]interface{}{"value": v}
+	}
+	for _, k := range ignoreFields {
+		delete(res, k)
+	}
+	return res
+}
+
+// 比较修改前后的字段，只保留有变化的字段，敏感字段隐藏内容
+func diff(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	if before != nil && after != nil {
+		for k, v := range before {
+			if av, ok := after[k]; ok && reflect.DeepEqual(v, av) {
+				delete(before, k)
+				delete(after, k)
+			}
+		}
+	}
+	mask(before)
+	mask(after)
+	return before, after
+}
+
+func mask(m map[string]interface{}) {
+	for k, v := range m {
+		if sensitiveFields[strings.ToLower(k)] && v != nil && v != "" {
+			m[k] = "******"
+		}
+	}
+}
+
+func toJson(m map[string]interface{}) string {
+	if m == nil {
+		return ""
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
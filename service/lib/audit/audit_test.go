@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffMask(t *testing.T) {
+	before := map[string]interface{}{"password": "old", "mustChangePassword": false, "tokenPrefix": "sp_ab", "name": "a"}
+	after := map[string]interface{}{"password": "new", "mustChangePassword": true, "tokenPrefix": "sp_cd", "name": "a"}
+	gotBefore, gotAfter := diff(before, after)
+
+	// 敏感字段只记录修改，名称中包含敏感词的普通字段记录内容
+	wantBefore := map[string]interface{}{"password": "******", "mustChangePassword": false, "tokenPrefix": "sp_ab"}
+	wantAfter := map[string]interface{}{"password": "******", "mustChangePassword": true, "tokenPrefix": "sp_cd"}
+	if !reflect.DeepEqual(gotBefore, wantBefore) || !reflect.DeepEqual(gotAfter, wantAfter) {
+		t.Fatalf("diff() = %v, %v, want %v, %v", gotBefore, gotAfter, wantBefore, wantAfter)
+	}
+}
+
+func TestMask(t *testing.T) {
+	m := map[string]interface{}{"Password": "x", "token": "x", "TokenHash": "x", "secret": "", "setupSecret": "x", "mustChangePassword": true}
+	mask(m)
+	// 空值不隐藏，可以看出是否设置
+	want := map[string]interface{}{"Password": "******", "token": "******", "TokenHash": "******", "secret": "", "setupSecret": "x", "mustChangePassword": true}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("mask() = %v, want %v", m, want)
+	}
+}
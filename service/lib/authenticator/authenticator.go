@@ -0,0 +1,78 @@
+package authenticator
+
+import (
+	"errors"
+	"sun-panel/global"
+	"sun-panel/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("incorrect username or password")
+	ErrGroupDenied        = errors.New("user is not in an allowed group")
+	ErrUserNotFound       = errors.New("user does not exist and auto create is disabled")
+	ErrUsernameInvalid    = errors.New("username is empty or too long")
+	ErrSubjectInvalid     = errors.New("issuer or subject claim is missing")
+	ErrUsernameExists     = errors.New("the username is used by an existing account, log in to it and bind the identity first")
+	ErrIdentityBound      = errors.New("the identity is already bound to another account")
+	ErrAccountNotLinked   = errors.New("the username is used by a local account that is not linked to LDAP")
+)
+
+// 账号密码认证器
+type Authenticator interface {
+	// 认证器名称
+	Name() string
+
+	// 认证成功返回对应的用户，账号不存在或密码错误返回 ErrInvalidCredentials
+	Authenticate(username, password string) (models.User, error)
+}
+
+// 获取已启用的认证器，按顺序依次尝试
+// 外部认证器在前，本地数据库在最后作为兜底(紧急账号)
+func GetAuthenticators() []Authenticator {
+	list := []Authenticator{}
+	localFallback := true
+
+	if ldapConfig := GetLdapConfig(); ldapConfig.Enable {
+		list = append(list, &Ldap{Config: ldapConfig})
+		localFallback = ldapConfig.LocalFallback
+	}
+
+	if localFallback || len(list) == 0 {
+		list = append(list, &Local{})
+	}
+	return list
+}
+
+// 使用账号密码登录
+func Authenticate(username, password string) (models.User, error) {
+	var lastErr error = ErrInvalidCredentials
+	for _, v := range GetAuthenticators() {
+		userInfo, err := v.Authenticate(username, password)
+		if err == nil {
+			return userInfo, nil
+		}
+		if err != ErrInvalidCredentials {
+			global.Logger.Errorln("authenticator", v.Name(), "error:", err.Error())
+			lastErr = err
+		}
+	}
+	return models.User{}, lastErr
+}
+
+// 本地数据库认证
+type Local struct{}
+
+func (a *Local) Name() string {
+	return "local"
+}
+
+func (a *Local) Authenticate(username, password string) (models.User, error) {
+	mUser := models.User{}
+	userInfo, err := mUser.GetUserInfoByUsernameAndPassword(username, password)
+	if err == gorm.ErrRecordNotFound {
+		return userInfo, ErrInvalidCredentials
+	}
+	return userInfo, err
+}
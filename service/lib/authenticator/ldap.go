@@ -0,0 +1,207 @@
+package authenticator
+
+import (
+	"crypto/tls"
+	"net/url"
+	"strings"
+	"sun-panel/global"
+	"sun-panel/lib/cmn"
+	"sun-panel/models"
+	"sun-panel/structs"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAP / Active Directory 认证
+type Ldap struct {
+	Config structs.IniConfigLdap
+}
+
+// 获取LDAP配置，未配置的项使用默认值
+func GetLdapConfig() structs.IniConfigLdap {
+	cfg := structs.IniConfigLdap{
+		LocalFallback: true,
+	}
+	if err := global.Config.GetSection("ldap", &cfg); err != nil {
+		cfg.Enable = false
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(&(objectClass=person)(uid={username}))"
+	}
+	if cfg.UsernameAttribute == "" {
+		cfg.UsernameAttribute = "uid"
+	}
+	if cfg.NameAttribute == "" {
+		cfg.NameAttribute = "cn"
+	}
+	if cfg.MailAttribute == "" {
+		cfg.MailAttribute = "mail"
+	}
+	if cfg.MemberOfAttribute == "" {
+		cfg.MemberOfAttribute = "memberOf"
+	}
+	if cfg.GroupNameAttribute == "" {
+		cfg.GroupNameAttribute = "cn"
+	}
+	return cfg
+}
+
+func (a *Ldap) Name() string {
+	return "ldap"
+}
+
+func (a *Ldap) Authenticate(username, password string) (models.User, error) {
+	// 空密码会被当作匿名绑定，必须拒绝
+	if username == "" || password == "" {
+		return models.User{}, ErrInvalidCredentials
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		return models.User{}, err
+	}
+	defer conn.Close()
+
+	// 使用服务账号查询用户
+	if a.Config.BindDn != "" {
+		err = conn.Bind(a.Config.BindDn, a.Config.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	entry, err := a.searchUser(conn, username)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	// 使用用户的DN和密码验证
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return models.User{}, ErrInvalidCredentials
+		}
+		return models.User{}, err
+	}
+
+	// 用户组映射角色，未配置用户组时不修改角色
+	role := 0
+	adminGroups := cmn.SplitAndTrim(a.Config.AdminGroups, ",")
+	userGroups := cmn.SplitAndTrim(a.Config.UserGroups, ",")
+	if len(adminGroups) != 0 || len(userGroups) != 0 {
+		// 重新使用服务账号查询用户组，部分目录不允许普通用户查询
+		if a.Config.BindDn != "" {
+			if err := conn.Bind(a.Config.BindDn, a.Config.BindPassword); err != nil {
+				return models.User{}, err
+			}
+		}
+		groups, err := a.searchGroups(conn, entry, username)
+		if err != nil {
+			return models.User{}, err
+		}
+		if role, err = MapGroupsToRole(groups, adminGroups, userGroups); err != nil {
+			return models.User{}, err
+		}
+	}
+
+	ldapUsername := entry.GetAttributeValue(a.Config.UsernameAttribute)
+	if ldapUsername == "" {
+		ldapUsername = username
+	}
+
+	return ProvisionLdapUser(ExternalUser{
+		Username: ldapUsername,
+		Name:     entry.GetAttributeValue(a.Config.NameAttribute),
+		Mail:     entry.GetAttributeValue(a.Config.MailAttribute),
+		Role:     role,
+	}, a.Config.AutoCreate, a.Config.LinkExisting)
+}
+
+func (a *Ldap) connect() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: a.Config.InsecureSkipVerify,
+	}
+	if u, err := url.Parse(a.Config.Url); err == nil {
+		tlsConfig.ServerName = u.Hostname()
+	}
+
+	conn, err := ldap.DialURL(a.Config.Url, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(10 * time.Second)
+
+	if a.Config.StartTls {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// 查询用户，不存在或不唯一时返回nil
+func (a *Ldap) searchUser(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	filter := strings.ReplaceAll(a.Config.UserFilter, "{username}", ldap.EscapeFilter(username))
+	req := ldap.NewSearchRequest(
+		a.Config.BaseDn,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 10, false,
+		filter,
+		[]string{"dn", a.Config.UsernameAttribute, a.Config.NameAttribute, a.Config.MailAttribute, a.Config.MemberOfAttribute},
+		nil,
+	)
+	res, err := conn.Search(req)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) || ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if len(res.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return res.Entries[0], nil
+}
+
+// 查询用户所属的组，返回组的DN和名称
+func (a *Ldap) searchGroups(conn *ldap.Conn, entry *ldap.Entry, username string) ([]string, error) {
+	groups := []string{}
+
+	// 未配置用户组查询，使用用户的memberOf属性
+	if a.Config.GroupFilter == "" {
+		for _, dn := range entry.GetAttributeValues(a.Config.MemberOfAttribute) {
+			groups = append(groups, dn)
+			if parsed, err := ldap.ParseDN(dn); err == nil && len(parsed.RDNs) > 0 && len(parsed.RDNs[0].Attributes) > 0 {
+				groups = append(groups, parsed.RDNs[0].Attributes[0].Value)
+			}
+		}
+		return groups, nil
+	}
+
+	baseDn := a.Config.GroupBaseDn
+	if baseDn == "" {
+		baseDn = a.Config.BaseDn
+	}
+	filter := strings.ReplaceAll(a.Config.GroupFilter, "{dn}", ldap.EscapeFilter(entry.DN))
+	filter = strings.ReplaceAll(filter, "{username}", ldap.EscapeFilter(username))
+	req := ldap.NewSearchRequest(
+		baseDn,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 10, false,
+		filter,
+		[]string{"dn", a.Config.GroupNameAttribute},
+		nil,
+	)
+	res, err := conn.Search(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range res.Entries {
+		groups = append(groups, v.DN)
+		if name := v.GetAttributeValue(a.Config.GroupNameAttribute); name != "" {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
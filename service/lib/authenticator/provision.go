@@ -0,0 +1,234 @@
+package authenticator
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"sun-panel/lib/cmn"
+	"sun-panel/lib/password"
+	"sun-panel/lib/session"
+	"sun-panel/models"
+
+	"gorm.io/gorm"
+)
+
+// 外部认证(LDAP/OIDC等)得到的用户资料
+type ExternalUser struct {
+	Username string
+	Name     string
+	Mail     string
+	Role     int // 0.不修改角色 models.ROLE_ADMIN 管理员 models.ROLE_USER 普通用户
+}
+
+// 根据用户组映射角色，adminGroups优先；userGroups不为空时必须属于其中之一
+func MapGroupsToRole(groups, adminGroups, userGroups []string) (int, error) {
+	if containsFold(groups, adminGroups) {
+		return models.ROLE_ADMIN, nil
+	}
+	if len(userGroups) == 0 || containsFold(groups, userGroups) {
+		return models.ROLE_USER, nil
+	}
+	return 0, ErrGroupDenied
+}
+
+// 匹配外部认证的用户，不存在时按配置自动创建，并同步角色
+// 用于认证代理，用户名由认证服务保证唯一且不可被用户修改
+func ProvisionUser(extUser ExternalUser, autoCreate bool) (models.User, error) {
+	username := strings.TrimSpace(extUser.Username)
+	if username == "" || cmn.RuneStrLen(username) > 50 {
+		return models.User{}, ErrUsernameInvalid
+	}
+
+	mUser := models.User{}
+	userInfo, err := mUser.GetUserInfoByUsername(username)
+	if err == gorm.ErrRecordNotFound {
+		if !autoCreate {
+			return userInfo, ErrUserNotFound
+		}
+		newUser, err := newExternalUser(extUser, username)
+		if err != nil {
+			return userInfo, err
+		}
+		return newUser.CreateOne()
+	} else if err != nil {
+		return userInfo, err
+	}
+
+	return syncExternalRole(userInfo, extUser.Role)
+}
+
+// 匹配LDAP用户，只匹配由LDAP创建或已关联LDAP的账号，不接管同名的本地账号(如紧急管理员账号)
+// linkExisting为true时按用户名关联已有账号，用于升级前由LDAP创建的账号，关联时不降低管理员的角色
+func ProvisionLdapUser(extUser ExternalUser, autoCreate, linkExisting bool) (models.User, error) {
+	username := strings.TrimSpace(extUser.Username)
+	if username == "" || cmn.RuneStrLen(username) > 50 {
+		return models.User{}, ErrUsernameInvalid
+	}
+
+	mUser := models.User{}
+	userInfo, err := mUser.GetUserInfoByUsername(username)
+	if err == gorm.ErrRecordNotFound {
+		if !autoCreate {
+			return userInfo, ErrUserNotFound
+		}
+		newUser, err := newExternalUser(extUser, username)
+		if err != nil {
+			return userInfo, err
+		}
+		newUser.AuthSource = models.USER_AUTH_SOURCE_LDAP
+		return newUser.CreateOne()
+	} else if err != nil {
+		return userInfo, err
+	}
+
+	if userInfo.AuthSource != models.USER_AUTH_SOURCE_LDAP {
+		if !linkExisting {
+			return models.User{}, ErrAccountNotLinked
+		}
+		if err := models.Db.Model(&models.User{}).Where("id=?", userInfo.ID).Update("auth_source", models.USER_AUTH_SOURCE_LDAP).Error; err != nil {
+			return userInfo, err
+		}
+		userInfo.AuthSource = models.USER_AUTH_SOURCE_LDAP
+		session.ClearUserCache(userInfo.ID)
+		if userInfo.Role == models.ROLE_ADMIN {
+			return userInfo, nil
+		}
+	}
+
+	return syncExternalRole(userInfo, extUser.Role)
+}
+
+// 匹配OIDC用户，只按签发者和sub匹配已绑定的账号，不按用户名关联已有的本地账号
+// 未绑定时按配置自动创建账号并绑定，用户名已存在时需要先登录该账号再绑定
+func ProvisionOidcUser(extUser ExternalUser, issuer, subject string, autoCreate bool) (models.User, error) {
+	if issuer == "" || subject == "" {
+		return models.User{}, ErrSubjectInvalid
+	}
+
+	mUser := models.User{}
+	mIdentity := models.UserOidcIdentity{}
+	identity, err := mIdentity.Get(models.Db, issuer, subject)
+	if err == nil {
+		userInfo, err := mUser.GetUserInfoByUid(identity.UserId)
+		if err == nil {
+			return syncExternalRole(userInfo, extUser.Role)
+		} else if err != gorm.ErrRecordNotFound {
+			return userInfo, err
+		}
+		// 账号已被删除，清除失效的绑定
+		if err := models.Db.Delete(&identity).Error; err != nil {
+			return models.User{}, err
+		}
+	} else if err != gorm.ErrRecordNotFound {
+		return models.User{}, err
+	}
+
+	if !autoCreate {
+		return models.User{}, ErrUserNotFound
+	}
+	username := strings.TrimSpace(extUser.Username)
+	if username == "" || cmn.RuneStrLen(username) > 50 {
+		return models.User{}, ErrUsernameInvalid
+	}
+	if _, err := mUser.GetUserInfoByUsername(username); err == nil {
+		return models.User{}, ErrUsernameExists
+	} else if err != gorm.ErrRecordNotFound {
+		return models.User{}, err
+	}
+
+	newUser, err := newExternalUser(extUser, username)
+	if err != nil {
+		return models.User{}, err
+	}
+	err = models.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserOidcIdentity{UserId: newUser.ID, Issuer: issuer, Subject: subject}).Error
+	})
+	return newUser, err
+}
+
+// 将OIDC身份绑定到已登录的账号，已绑定到其他账号时返回 ErrIdentityBound
+func LinkOidcIdentity(userId uint, issuer, subject string) error {
+	if issuer == "" || subject == "" {
+		return ErrSubjectInvalid
+	}
+	mIdentity := models.UserOidcIdentity{}
+	identity, err := mIdentity.Get(models.Db, issuer, subject)
+	if err == nil {
+		if identity.UserId == userId {
+			return nil
+		}
+		// 绑定的账号已被删除时可以重新绑定
+		mUser := models.User{}
+		if _, err := mUser.GetUserInfoByUid(identity.UserId); err != gorm.ErrRecordNotFound {
+			if err != nil {
+				return err
+			}
+			return ErrIdentityBound
+		}
+		return models.Db.Model(&identity).Update("user_id", userId).Error
+	} else if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	return models.Db.Create(&models.UserOidcIdentity{UserId: userId, Issuer: issuer, Subject: subject}).Error
+}
+
+// 外部认证自动创建的账号，使用随机密码，无法使用本地密码登录，需要时由管理员重置
+func newExternalUser(extUser ExternalUser, username string) (models.User, error) {
+	randBytes := make([]byte, 32)
+	if _, err := rand.Read(randBytes); err != nil {
+		return models.User{}, err
+	}
+	passwordHash, err := password.Hash(base64.RawURLEncoding.EncodeToString(randBytes))
+	if err != nil {
+		return models.User{}, err
+	}
+
+	role := extUser.Role
+	if role == 0 {
+		role = models.ROLE_USER
+	}
+	name := extUser.Name
+	if name == "" {
+		name = username
+	}
+	return models.User{
+		Username: username,
+		Password: passwordHash,
+		Name:     cmn.SubRuneStr(name, 0, 20),
+		Mail:     cmn.SubRuneStr(extUser.Mail, 0, 50),
+		Status:   1,
+		Role:     role,
+	}, nil
+}
+
+// 同步外部认证的角色
+// 只同步是否为管理员，普通用户保留管理员分配的其他角色(如面板编辑)
+func syncExternalRole(userInfo models.User, role int) (models.User, error) {
+	if role == models.ROLE_USER && userInfo.Role != models.ROLE_ADMIN {
+		role = userInfo.Role
+	}
+	if role != 0 && role != userInfo.Role {
+		mUser := models.User{}
+		if err := mUser.UpdateUserInfoByUserId(userInfo.ID, map[string]interface{}{"role": role}); err != nil {
+			return userInfo, err
+		}
+		userInfo.Role = role
+		session.ClearUserCache(userInfo.ID)
+	}
+	return userInfo, nil
+}
+
+// 是否有相同的元素，忽略大小写
+func containsFold(items, targets []string) bool {
+	for _, v := range items {
+		for _, t := range targets {
+			if strings.EqualFold(v, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
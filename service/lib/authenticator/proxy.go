@@ -0,0 +1,117 @@
+package authenticator
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sun-panel/global"
+	"sun-panel/lib/cmn"
+	"sun-panel/models"
+	"sun-panel/structs"
+	"sync"
+)
+
+// 反向代理认证，信任来自可信代理的用户名请求头
+type ProxyAuth struct {
+	Config  structs.IniConfigProxyAuth
+	trusted []*net.IPNet
+}
+
+var (
+	proxyAuthLock  sync.Mutex
+	proxyAuthCache *ProxyAuth
+)
+
+// 获取反向代理认证配置，可信代理地址解析后缓存
+func GetProxyAuth() *ProxyAuth {
+	cfg := structs.IniConfigProxyAuth{}
+	if err := global.Config.GetSection("proxy_auth", &cfg); err != nil {
+		cfg.Enable = false
+	}
+	if cfg.UserHeader == "" {
+		cfg.UserHeader = "Remote-User"
+	}
+
+	proxyAuthLock.Lock()
+	defer proxyAuthLock.Unlock()
+	if proxyAuthCache != nil && proxyAuthCache.Config == cfg {
+		return proxyAuthCache
+	}
+
+	p := &ProxyAuth{Config: cfg}
+	for _, v := range cmn.SplitAndTrim(cfg.TrustedProxies, ",") {
+		if !strings.Contains(v, "/") {
+			if ip := net.ParseIP(v); ip != nil && ip.To4() != nil {
+				v += "/32"
+			} else {
+				v += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(v)
+		if err != nil {
+			global.Logger.Errorln("proxy_auth: invalid trusted proxy", v)
+			continue
+		}
+		p.trusted = append(p.trusted, ipNet)
+	}
+	proxyAuthCache = p
+	return p
+}
+
+// 请求是否来自可信代理，使用TCP连接的地址，不信任 X-Forwarded-For
+func (p *ProxyAuth) IsTrusted(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, v := range p.trusted {
+		if v.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取请求头中的用户名，未启用、不是可信代理或没有请求头时返回空
+func (p *ProxyAuth) Username(r *http.Request) string {
+	if !p.Config.Enable {
+		return ""
+	}
+	username := strings.TrimSpace(r.Header.Get(p.Config.UserHeader))
+	if username == "" {
+		return ""
+	}
+	if !p.IsTrusted(r) {
+		global.Logger.Warnln("proxy_auth: ignore", p.Config.UserHeader, "header from untrusted address", r.RemoteAddr)
+		return ""
+	}
+	return username
+}
+
+// 根据可信代理的请求头匹配用户，不存在时按配置自动创建
+func (p *ProxyAuth) Authenticate(r *http.Request, username string) (models.User, error) {
+	extUser := ExternalUser{Username: username}
+	if p.Config.NameHeader != "" {
+		extUser.Name = strings.TrimSpace(r.Header.Get(p.Config.NameHeader))
+	}
+	if p.Config.MailHeader != "" {
+		extUser.Mail = strings.TrimSpace(r.Header.Get(p.Config.MailHeader))
+	}
+
+	adminGroups := cmn.SplitAndTrim(p.Config.AdminGroups, ",")
+	userGroups := cmn.SplitAndTrim(p.Config.UserGroups, ",")
+	if p.Config.GroupsHeader != "" && (len(adminGroups) > 0 || len(userGroups) > 0) {
+		groups := cmn.SplitAndTrim(r.Header.Get(p.Config.GroupsHeader), ",")
+		role, err := MapGroupsToRole(groups, adminGroups, userGroups)
+		if err != nil {
+			return models.User{}, err
+		}
+		extUser.Role = role
+	}
+
+	return ProvisionUser(extUser, p.Config.AutoCreate)
+}
@@ -23,6 +23,9 @@ type Cacher[T any] interface {
 	// 删除
 	Delete(k string)
 
+	// 取值并删除，同时调用时只有一个能取到(一次性的验证码、链接等)
+	GetDel(k string) (T, bool)
+
 	// 只有在给定Key项尚未存在，或者现有项已过期时，才能将项添加到缓存中。否则返回错误。
 	// Add(k string, v T, d time.Duration)
 	// IncrementInt(k string, n int) (num int, err error)
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"sync"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -10,6 +11,7 @@ import (
 type GoCacheStruct[T any] struct {
 	gocahce *cache.Cache
 	Result  T
+	getDel  sync.Mutex
 }
 
 type GoCacheValue[T any] struct {
@@ -67,6 +69,17 @@ func (c *GoCacheStruct[T]) Delete(k string) {
 	c.gocahce.Delete(k)
 }
 
+// 取值并删除
+func (c *GoCacheStruct[T]) GetDel(k string) (T, bool) {
+	c.getDel.Lock()
+	defer c.getDel.Unlock()
+	v, ok := c.Get(k)
+	if ok {
+		c.gocahce.Delete(k)
+	}
+	return v, ok
+}
+
 // Add() 加入缓存
 func (c *GoCacheStruct[T]) Add(k string, v T, d time.Duration) {
 	c.gocahce.Add(k, GoCacheValue[T]{Value: v}, d)
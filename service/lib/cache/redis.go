@@ -131,6 +131,28 @@ func (r *RedisCacheStruct[T]) SetKeepExpiration(k string, v T) {
 	}
 }
 
+// 取值并删除，在事务中执行，删除成功的调用才能取到值
+func (r *RedisCacheStruct[T]) GetDel(k string) (T, bool) {
+	var getCmd *redis.StringCmd
+	var delCmd *redis.IntCmd
+	if _, err := r.Redis.TxPipelined(r.Ctx, func(pipe redis.Pipeliner) error {
+		getCmd = pipe.HGet(r.Ctx, r.HashKey, k)
+		delCmd = pipe.HDel(r.Ctx, r.HashKey, k)
+		return nil
+	}); err != nil || delCmd.Val() != 1 {
+		return r.Result, false
+	}
+
+	value := RedisValue[T]{}
+	if err := json.Unmarshal([]byte(getCmd.Val()), &value); err != nil {
+		return r.Result, false
+	}
+	if value.IsExpiration && time.Now().Unix() > value.ExpirationTimeStamp {
+		return r.Result, false
+	}
+	return value.Value, true
+}
+
 // 删除 cache
 func (r *RedisCacheStruct[T]) Delete(k string) {
 	r.Redis.HDel(r.Ctx, r.HashKey, k)
@@ -1,14 +1,27 @@
 package captcha
 
 import (
-	"sun-panel/global"
+	"errors"
+	"io"
+	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mojocn/base64Captcha"
 )
 
+// 验证码ID的cookie和header名称
+const CAPTCHA_ID_KEY = "CaptchaId"
+
 var Store = base64Captcha.DefaultMemStore
 
+var ErrCaptchaIdEmpty = errors.New("captcha id is empty")
+
+var (
+	baseDriver     *base64Captcha.DriverString
+	baseDriverOnce sync.Once
+)
+
 func NewDriver(width, height int) *base64Captcha.DriverString {
 	driver := new(base64Captcha.DriverString)
 	driver.Height = height
@@ -21,32 +34,65 @@ func NewDriver(width, height int) *base64Captcha.DriverString {
 	return driver
 }
 
+// 获取指定尺寸的驱动
+// 字体从base64Captcha内嵌的字体中加载(不依赖系统字体，alpine容器中也可用)，只加载一次
+func getDriver(width, height int) *base64Captcha.DriverString {
+	baseDriverOnce.Do(func() {
+		baseDriver = NewDriver(0, 0).ConvertFonts()
+	})
+	driver := *baseDriver
+	driver.Width = width
+	driver.Height = height
+	return &driver
+}
+
 // 生成图形验证码
 func GenerateCaptchaHandler(id string, width, height int) string {
-	var driver = NewDriver(width, height).ConvertFonts()
-	c := base64Captcha.NewCaptcha(driver, Store)
-	_, content, answer := c.Driver.GenerateIdQuestionAnswer()
-
-	item, _ := c.Driver.DrawCaptcha(content)
-	c.Store.Set(id, answer)
+	item, err := generate(id, width, height)
+	if err != nil {
+		return ""
+	}
 	return item.EncodeB64string()
 }
 
-// 验证
+// 生成图形验证码，输出png图片
+func WriteCaptchaImage(w io.Writer, id string, width, height int) error {
+	item, err := generate(id, width, height)
+	if err != nil {
+		return err
+	}
+	_, err = item.WriteTo(w)
+	return err
+}
+
+func generate(id string, width, height int) (base64Captcha.Item, error) {
+	driver := getDriver(width, height)
+	_, content, answer := driver.GenerateIdQuestionAnswer()
+	item, err := driver.DrawCaptcha(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := Store.Set(id, answer); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// 验证，不区分大小写，验证后作废
 func CaptchaVerifyHandle(id, vcode string) bool {
-	return Store.Verify(id, vcode, true)
+	if id == "" || vcode == "" {
+		return false
+	}
+	return Store.Verify(id, strings.ToLower(strings.TrimSpace(vcode)), true)
 }
 
-// 根据key获取验证码ID
+// 根据key获取验证码ID，优先cookie，其次header
 func CaptchaGetIdByCookieHeader(c *gin.Context, key string) (captchaId string, err error) {
-
-	captchaId, err = c.Cookie("CaptchaId")
-	if err != nil {
-		global.Logger.Errorf("failed to get captchaId from cookie, err:%+v\n", err)
-		return captchaId, err
+	if captchaId, _ = c.Cookie(key); captchaId == "" {
+		captchaId = c.GetHeader(key)
 	}
 	if captchaId == "" {
-		captchaId = c.GetHeader(key)
+		return "", ErrCaptchaIdEmpty
 	}
-	return
+	return captchaId, nil
 }
@@ -90,6 +90,17 @@ func InSlice(items []string, item string) bool {
 	return false
 }
 
+// 拆分字符串并去除每一项前后的空格，忽略空项
+func SplitAndTrim(str, sep string) []string {
+	result := []string{}
+	for _, v := range strings.Split(str, sep) {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
 // 字符串转int
 func StrToInt(str string) int {
 	intStr, _ := strconv.Atoi(str)
@@ -213,7 +224,7 @@ func AssetsTakeFileToPath(assetsPath, targetPath string) error {
 	return os.WriteFile(targetPath, bytes, 0666)
 }
 
-// 密码加密
+// 旧版密码加密，仅用于验证历史密码，新密码请使用 lib/password
 func PasswordEncryption(password string) string {
 	return Md5(Md5(Md5(password)))
 }
@@ -15,6 +15,9 @@ const (
 	DISCLAIMER            = "disclaimer"            // 免责声明 储存类型：字符串
 	WEB_ABOUT_DESCRIPTION = "web_about_description" // 关于的描述信息
 	PANEL_PUBLIC_USER_ID  = "panel_public_user_id"  // 公开访问模式用户id *uint|null
+	SYSTEM_LOGIN_LIMIT    = "system_login_limit"    // 登录失败限制
+	SYSTEM_AUDIT_LOG      = "system_audit_log"      // 审计日志
+	MAGIC_LINK_SECRET     = "magic_link_secret"     // 邮件登录链接的签名密钥，首次使用时生成
 )
 
 type SystemSettingCache struct {
@@ -29,18 +32,62 @@ type Email struct {
 }
 
 type Register struct {
-	EmailSuffix  string `json:"emailSuffix"`  // 注册邮箱后缀
-	OpenRegister bool   `json:"openRegister"` // 开放注册
+	EmailSuffix     string `json:"emailSuffix"`     // 注册邮箱后缀，多个用逗号分隔，为空不限制
+	OpenRegister    bool   `json:"openRegister"`    // 开放注册
+	RequireApproval bool   `json:"requireApproval"` // 注册后需要管理员审核(未激活状态)
 }
 
 type Login struct {
-	LoginCaptcha bool `json:"loginCaptcha"` // 登录验证码
+	LoginCaptcha              bool `json:"loginCaptcha"`                                     // 登录验证码
+	LoginCaptchaAfterFailures int  `json:"loginCaptchaAfterFailures"`                        // 同一IP登录失败达到次数后需要验证码，0为不启用
+	AdminTwoFactorRequired    bool `json:"adminTwoFactorRequired"`                           // 管理员必须启用二次验证
+	MagicLinkLogin            bool `json:"magicLinkLogin"`                                   // 邮件登录，发送一次性登录链接到邮箱，需要设置站点地址
+	MagicLinkExpireMinutes    int  `json:"magicLinkExpireMinutes" validate:"min=0,max=1440"` // 登录链接有效时长(分钟)，0为默认值
 }
 
 type ApplicationSetting struct {
 	Register
 	Login
 	WebSiteUrl string `json:"webSiteUrl"` // 站点地址
+	Language   string `json:"language"`   // 系统语言(邮件等服务端文本)，为空使用zh-cn
+}
+
+// 登录失败限制(防暴力破解)
+type LoginLimit struct {
+	Enable               bool `json:"enable"`
+	UsernameMaxFailures  int  `json:"usernameMaxFailures" validate:"min=1"`  // 同一账号失败次数达到后锁定账号
+	IpMaxFailures        int  `json:"ipMaxFailures" validate:"min=1"`        // 同一IP失败次数达到后锁定IP
+	FailureWindowMinutes int  `json:"failureWindowMinutes" validate:"min=1"` // 失败次数的统计周期(分钟)，超过后重新计数
+	BackoffAfter         int  `json:"backoffAfter" validate:"min=0"`         // 失败几次后开始延迟，下次允许尝试的等待时间按次数翻倍
+	BackoffBaseSeconds   int  `json:"backoffBaseSeconds" validate:"min=1"`   // 首次延迟(秒)
+	BackoffMaxSeconds    int  `json:"backoffMaxSeconds" validate:"min=1"`    // 最长延迟(秒)
+	LockoutMinutes       int  `json:"lockoutMinutes" validate:"min=1"`       // 锁定时长(分钟)
+}
+
+// 登录失败限制的默认值，未设置时使用
+func DefaultLoginLimit() LoginLimit {
+	return LoginLimit{
+		Enable:               true,
+		UsernameMaxFailures:  10,
+		IpMaxFailures:        30,
+		FailureWindowMinutes: 15,
+		BackoffAfter:         3,
+		BackoffBaseSeconds:   1,
+		BackoffMaxSeconds:    60,
+		LockoutMinutes:       15,
+	}
+}
+
+// 审计日志
+type AuditLog struct {
+	RetentionDays int `json:"retentionDays" validate:"min=0"` // 保留天数，0为永久保留
+}
+
+// 审计日志的默认值，未设置时使用
+func DefaultAuditLog() AuditLog {
+	return AuditLog{
+		RetentionDays: 180,
+	}
 }
 
 var (
@@ -0,0 +1,124 @@
+package emailVCode
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sun-panel/global"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/lib/mail"
+	"time"
+)
+
+// 验证码用途，不同用途的验证码互不通用
+const (
+	PURPOSE_REGISTER       = "register"
+	PURPOSE_RESET_PASSWORD = "reset_password"
+	PURPOSE_MAGIC_LINK     = "magic_link"
+)
+
+const (
+	resendInterval = time.Minute // 同一邮箱两次发送的最小间隔
+	ipMaxPerHour   = 10          // 同一IP每小时最多发送次数
+	maxAttempts    = 5           // 验证码最多尝试次数，超过后作废
+)
+
+var (
+	ErrTooFrequent        = errors.New("verification code requested too frequently")
+	ErrEmailNotConfigured = errors.New("email service is not configured")
+)
+
+// 发送验证码的方法，例如 mail.SendRegisterEmail
+type SendFunc func(emailer *mail.Emailer, mailTo, vcode string) error
+
+// 根据系统邮箱设置创建发件器
+func GetEmailer() (*mail.Emailer, error) {
+	emailSetting := systemSetting.Email{}
+	if err := global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_EMAIL, &emailSetting); err != nil || emailSetting.Host == "" || emailSetting.Mail == "" {
+		return nil, ErrEmailNotConfigured
+	}
+	return mail.NewEmailer(mail.EmailInfo{
+		Username: emailSetting.Mail,
+		Password: emailSetting.Password,
+		Host:     emailSetting.Host,
+		Port:     emailSetting.Port,
+	}), nil
+}
+
+// 检查发送频率，未超出时计入一次，key为验证码对应的邮箱或账号
+func CheckAndCount(purpose, key, ip string) error {
+	mailKey := "resend:" + purpose + ":" + key
+	ipKey := "ip:" + ip
+	if _, ok := global.EmailVCodeLimit.Get(mailKey); ok {
+		return ErrTooFrequent
+	}
+	times, _ := global.EmailVCodeLimit.Get(ipKey)
+	if times >= ipMaxPerHour {
+		return ErrTooFrequent
+	}
+	global.EmailVCodeLimit.Set(mailKey, 1, resendInterval)
+	global.EmailVCodeLimit.SetKeepExpiration(ipKey, times+1)
+	return nil
+}
+
+// 生成验证码并发送到邮箱，验证码10分钟内有效，验证时使用相同的key
+func Send(purpose, key, mailTo string, send SendFunc) error {
+	emailer, err := GetEmailer()
+	if err != nil {
+		return err
+	}
+	vcode, err := generate()
+	if err != nil {
+		return err
+	}
+	if err := send(emailer, mailTo, vcode); err != nil {
+		return err
+	}
+	global.VerifyCodeCachePool.SetDefault(codeKey(purpose, key), vcode)
+	global.EmailVCodeLimit.Delete(attemptsKey(purpose, key))
+	return nil
+}
+
+// 验证邮箱验证码，验证通过后立即作废
+func Verify(purpose, key, vcode string) bool {
+	cacheKey := codeKey(purpose, key)
+	code, ok := global.VerifyCodeCachePool.Get(cacheKey)
+	if !ok || vcode == "" {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(code), []byte(strings.TrimSpace(vcode))) == 1 {
+		global.VerifyCodeCachePool.Delete(cacheKey)
+		global.EmailVCodeLimit.Delete(attemptsKey(purpose, key))
+		return true
+	}
+
+	// 错误次数过多，验证码作废
+	attempts, _ := global.EmailVCodeLimit.Get(attemptsKey(purpose, key))
+	if attempts+1 >= maxAttempts {
+		global.VerifyCodeCachePool.Delete(cacheKey)
+		global.EmailVCodeLimit.Delete(attemptsKey(purpose, key))
+	} else {
+		global.EmailVCodeLimit.SetKeepExpiration(attemptsKey(purpose, key), attempts+1)
+	}
+	return false
+}
+
+// 6位数字验证码
+func generate() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func codeKey(purpose, key string) string {
+	return "email_vcode:" + purpose + ":" + key
+}
+
+func attemptsKey(purpose, key string) string {
+	return "attempts:" + purpose + ":" + key
+}
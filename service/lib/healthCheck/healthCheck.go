@@ -0,0 +1,352 @@
+package healthCheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sun-panel/global"
+	"sun-panel/models"
+	"sync"
+	"time"
+)
+
+// 健康检测：定时检测图标的Url和LanUrl，检测结果保存为历史记录
+
+const (
+	DEFAULT_INTERVAL  = 60    // 默认检测间隔(秒)
+	MIN_INTERVAL      = 30    // 最短检测间隔(秒)
+	MAX_INTERVAL      = 86400 // 最长检测间隔(秒)
+	DEFAULT_TIMEOUT   = 10    // 默认超时时间(秒)
+	MAX_TIMEOUT       = 60    // 最长超时时间(秒)
+	HISTORY_RETENTION = 7 * 24 * time.Hour
+
+	scheduleInterval = 10 * time.Second // 检查是否有到期需要检测的图标
+	cleanupInterval  = time.Hour
+	maxConcurrent    = 10      // 同时检测的图标数量
+	maxBodySize      = 1 << 20 // 检测关键字时最多读取的内容
+	userAgent        = "Sun-Panel-HealthCheck"
+)
+
+var (
+	ErrInvalidType           = errors.New("invalid health check type")
+	ErrInvalidExpectedStatus = errors.New("invalid expected status code")
+)
+
+// 不跟随重定向，重定向的状态码视为正常，避免通过跳转访问其他地址
+var httpClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+var (
+	mu          sync.Mutex
+	lastChecked = map[uint]time.Time{} // 图标最后一次检测的时间
+	running     = map[uint]bool{}      // 正在检测的图标
+)
+
+// 校验检测设置，未填写的间隔和超时使用默认值，超出范围时取最近的有效值
+func Normalize(item *models.ItemIcon) error {
+	switch item.HealthCheckType {
+	case "":
+		return nil
+	case models.HEALTH_CHECK_TYPE_HTTP, models.HEALTH_CHECK_TYPE_TCP, models.HEALTH_CHECK_TYPE_DNS:
+	default:
+		return ErrInvalidType
+	}
+	if item.HealthCheckExpectedStatus != 0 && (item.HealthCheckExpectedStatus < 100 || item.HealthCheckExpectedStatus > 599) {
+		return ErrInvalidExpectedStatus
+	}
+	item.HealthCheckInterval = clamp(item.HealthCheckInterval, DEFAULT_INTERVAL, MIN_INTERVAL, MAX_INTERVAL)
+	item.HealthCheckTimeout = clamp(item.HealthCheckTimeout, DEFAULT_TIMEOUT, 1, MAX_TIMEOUT)
+	return nil
+}
+
+// 获取开启检测的图标的健康状态，图标ID:健康状态，地址为空时不返回
+// 按各图标的检测间隔只查询最近的记录，所有图标只查询一次
+func GetHealth(items []models.ItemIcon) (map[uint]models.ItemIconHealth, error) {
+	res := map[uint]models.ItemIconHealth{}
+	now := time.Now()
+	conds := []string{}
+	args := []interface{}{}
+	idsByInterval := map[int][]uint{}
+	for _, v := range items {
+		if v.HealthCheckType == "" {
+			continue
+		}
+		interval := clamp(v.HealthCheckInterval, DEFAULT_INTERVAL, MIN_INTERVAL, MAX_INTERVAL)
+		idsByInterval[interval] = append(idsByInterval[interval], v.ID)
+	}
+	if len(idsByInterval) == 0 {
+		return res, nil
+	}
+	for interval, ids := range idsByInterval {
+		window := time.Duration(models.HEALTH_CHECK_HISTORY_COUNT) * (time.Duration(interval)*time.Second + scheduleInterval)
+		conds = append(conds, "(item_icon_id in ? AND created_at>=?)")
+		args = append(args, ids, now.Add(-window))
+	}
+
+	list := []models.ItemIconHealthCheck{}
+	if err := global.Db.Order("id").Where(strings.Join(conds, " OR "), args...).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	histories := map[uint]map[string][]models.ItemIconHealthCheck{}
+	for _, v := range list {
+		if histories[v.ItemIconId] == nil {
+			histories[v.ItemIconId] = map[string][]models.ItemIconHealthCheck{}
+		}
+		histories[v.ItemIconId][v.Target] = append(histories[v.ItemIconId][v.Target], v)
+	}
+
+	for _, v := range items {
+		if v.HealthCheckType == "" {
+			continue
+		}
+		health := models.ItemIconHealth{}
+		health.Url = healthStatus(v.Url, histories[v.ID][models.HEALTH_CHECK_TARGET_URL])
+		health.LanUrl = healthStatus(v.LanUrl, histories[v.ID][models.HEALTH_CHECK_TARGET_LAN_URL])
+		res[v.ID] = health
+	}
+	return res, nil
+}
+
+// 地址最近一次的检测结果和最近的记录(正序)，地址为空或没有记录时返回nil
+func healthStatus(rawUrl string, history []models.ItemIconHealthCheck) *models.ItemIconHealthStatus {
+	if strings.TrimSpace(rawUrl) == "" || len(history) == 0 {
+		return nil
+	}
+	if len(history) > models.HEALTH_CHECK_HISTORY_COUNT {
+		history = history[len(history)-models.HEALTH_CHECK_HISTORY_COUNT:]
+	}
+	return &models.ItemIconHealthStatus{ItemIconHealthCheck: history[len(history)-1], History: history}
+}
+
+// 是否开启、修改了检测设置或修改了被检测的地址，服务端会访问这些地址，需要健康检测权限
+// 关闭检测不需要权限
+func Changed(before, after models.ItemIcon) bool {
+	if after.HealthCheckType == "" {
+		return false
+	}
+	return after.HealthCheckType != before.HealthCheckType ||
+		after.HealthCheckInterval != before.HealthCheckInterval ||
+		after.HealthCheckTimeout != before.HealthCheckTimeout ||
+		after.HealthCheckExpectedStatus != before.HealthCheckExpectedStatus ||
+		after.HealthCheckKeyword != before.HealthCheckKeyword ||
+		after.Url != before.Url ||
+		after.LanUrl != before.LanUrl
+}
+
+// 启动定时检测
+func Start() {
+	go func() {
+		ticker := time.NewTicker(scheduleInterval)
+		defer ticker.Stop()
+
+		lastCleanup := time.Time{}
+		for range ticker.C {
+			runDue()
+			if time.Since(lastCleanup) >= cleanupInterval {
+				lastCleanup = time.Now()
+				mHealthCheck := models.ItemIconHealthCheck{}
+				if err := mHealthCheck.DeleteBefore(global.Db, time.Now().Add(-HISTORY_RETENTION)); err != nil {
+					global.Logger.Errorln("health check history cleanup:", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+// 修改检测设置后调用，下一轮立即检测
+func Reset(itemIconId uint) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(lastChecked, itemIconId)
+}
+
+// 检测到期的图标
+func runDue() {
+	items := []models.ItemIcon{}
+	if err := global.Db.Find(&items, "health_check_type<>''").Error; err != nil {
+		global.Logger.Errorln("health check load items:", err.Error())
+		return
+	}
+
+	now := time.Now()
+	due := []models.ItemIcon{}
+	mu.Lock()
+	exists := map[uint]bool{}
+	for _, v := range items {
+		exists[v.ID] = true
+		interval := time.Duration(clamp(v.HealthCheckInterval, DEFAULT_INTERVAL, MIN_INTERVAL, MAX_INTERVAL)) * time.Second
+		if running[v.ID] || now.Sub(lastChecked[v.ID]) < interval {
+			continue
+		}
+		running[v.ID] = true
+		lastChecked[v.ID] = now
+		due = append(due, v)
+	}
+	// 清理已删除或关闭检测的图标
+	for id := range lastChecked {
+		if !exists[id] {
+			delete(lastChecked, id)
+		}
+	}
+	mu.Unlock()
+
+	sem := make(chan struct{}, maxConcurrent)
+	for _, v := range due {
+		sem <- struct{}{}
+		go func(item models.ItemIcon) {
+			defer func() {
+				mu.Lock()
+				delete(running, item.ID)
+				mu.Unlock()
+				<-sem
+			}()
+			CheckItem(item)
+		}(v)
+	}
+}
+
+// 检测图标的地址并保存结果
+func CheckItem(item models.ItemIcon) {
+	targets := map[string]string{
+		models.HEALTH_CHECK_TARGET_URL:     item.Url,
+		models.HEALTH_CHECK_TARGET_LAN_URL: item.LanUrl,
+	}
+	for target, rawUrl := range targets {
+		if strings.TrimSpace(rawUrl) == "" {
+			continue
+		}
+		result := Check(item, rawUrl)
+		result.ItemIconId = item.ID
+		result.Target = target
+		if err := global.Db.Create(&result).Error; err != nil {
+			global.Logger.Errorln("health check save result:", err.Error())
+		}
+	}
+}
+
+// 按图标的检测设置检测地址
+func Check(item models.ItemIcon, rawUrl string) models.ItemIconHealthCheck {
+	timeout := time.Duration(clamp(item.HealthCheckTimeout, DEFAULT_TIMEOUT, 1, MAX_TIMEOUT)) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := models.ItemIconHealthCheck{}
+	start := time.Now()
+	u, err := parseUrl(rawUrl)
+	if err == nil {
+		switch item.HealthCheckType {
+		case models.HEALTH_CHECK_TYPE_HTTP:
+			result.StatusCode, err = checkHttp(ctx, u, item.HealthCheckExpectedStatus, item.HealthCheckKeyword)
+		case models.HEALTH_CHECK_TYPE_TCP:
+			err = checkTcp(ctx, u)
+		case models.HEALTH_CHECK_TYPE_DNS:
+			err = checkDns(ctx, u)
+		default:
+			err = ErrInvalidType
+		}
+	}
+	result.ResponseTime = int(time.Since(start).Milliseconds())
+	result.Up = err == nil
+	if err != nil {
+		result.Message = err.Error()
+		if len(result.Message) > 255 {
+			result.Message = result.Message[:255]
+		}
+	}
+	return result
+}
+
+func checkHttp(ctx context.Context, u *url.URL, expectedStatus int, keyword string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if expectedStatus != 0 && resp.StatusCode != expectedStatus {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d, expected %d", resp.StatusCode, expectedStatus)
+	} else if expectedStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 399) {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	if keyword != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+		if err != nil {
+			return resp.StatusCode, err
+		}
+		if !strings.Contains(string(body), keyword) {
+			return resp.StatusCode, errors.New("keyword not found")
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func checkTcp(ctx context.Context, u *url.URL) error {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func checkDns(ctx context.Context, u *url.URL) error {
+	addrs, err := net.DefaultResolver.LookupHost(ctx, u.Hostname())
+	if err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return errors.New("no address found")
+	}
+	return nil
+}
+
+// 解析地址，没有协议时按http处理
+func parseUrl(rawUrl string) (*url.URL, error) {
+	rawUrl = strings.TrimSpace(rawUrl)
+	if !strings.Contains(rawUrl, "://") {
+		rawUrl = "http://" + rawUrl
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New("invalid url: " + strconv.Quote(rawUrl))
+	}
+	return u, nil
+}
+
+func clamp(v, def, min, max int) int {
+	if v == 0 {
+		return def
+	}
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -1,6 +1,7 @@
 package language
 
 import (
+	"errors"
 	"os"
 	"strings"
 	"sun-panel/lib/cmn"
@@ -11,6 +12,25 @@ type LangStructObj struct {
 	LangContet *iniConfig.IniConfig
 }
 
+// 支持的语言
+var Languages = []string{"zh-cn", "en-us"}
+
+var ErrLanguageNotSupported = errors.New("language not supported")
+
+// 加载语言文件(lang/<语言>.ini)，语言不支持或文件不存在时返回错误
+func Load(lang string) (*LangStructObj, error) {
+	if !cmn.InSlice(Languages, lang) {
+		return nil, ErrLanguageNotSupported
+	}
+	langPath := "lang/" + lang + ".ini"
+	if exists, err := cmn.PathExists(langPath); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, ErrLanguageNotSupported
+	}
+	return &LangStructObj{LangContet: iniConfig.NewIniConfig(langPath)}, nil
+}
+
 func NewLang(langPath string) *LangStructObj {
 	langObj := LangStructObj{}
 	exists, _ := cmn.PathExists(langPath)
@@ -0,0 +1,158 @@
+package loginLimit
+
+import (
+	"errors"
+	"strings"
+	"sun-panel/global"
+	"sun-panel/lib/cmn"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/models"
+	"time"
+)
+
+var (
+	ErrLocked      = errors.New("too many failed login attempts, temporarily locked")
+	ErrTooFrequent = errors.New("login attempts are too frequent")
+)
+
+type target struct {
+	kind        string
+	value       string
+	maxFailures int
+}
+
+// 获取登录失败限制设置，未设置时使用默认值
+func GetSetting() systemSetting.LoginLimit {
+	setting := systemSetting.DefaultLoginLimit()
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_LOGIN_LIMIT, &setting)
+	return setting
+}
+
+// 登录前检查账号和IP是否被锁定或需要等待，返回剩余等待时间
+func Check(username, ip string) (time.Duration, error) {
+	setting := GetSetting()
+	if !setting.Enable {
+		return 0, nil
+	}
+
+	targets := getTargets(setting, username, ip)
+	mLockout := models.LoginLockout{}
+	for _, v := range targets {
+		info, locked, err := mLockout.GetActive(global.Db, v.kind, v.value)
+		if err != nil {
+			return 0, err
+		}
+		if locked {
+			return time.Until(info.LockedUntil), ErrLocked
+		}
+	}
+
+	var wait time.Duration
+	for _, v := range targets {
+		if info, ok := global.LoginFailure.Get(cacheKey(v.kind, v.value)); ok {
+			if d := time.Until(info.NextAllowedAt); d > wait {
+				wait = d
+			}
+		}
+	}
+	if wait > 0 {
+		return wait, ErrTooFrequent
+	}
+	return 0, nil
+}
+
+// 登录失败，增加账号和IP的失败次数，超过阈值后锁定
+// 未启用失败限制时仍然计数，用于判断是否需要验证码
+func Failed(username, ip string) {
+	setting := GetSetting()
+	global.Logger.Warnln("login failed, username:", username, "ip:", ip)
+
+	window := time.Duration(setting.FailureWindowMinutes) * time.Minute
+	mLockout := models.LoginLockout{}
+	for _, v := range getTargets(setting, username, ip) {
+		key := cacheKey(v.kind, v.value)
+		info, _ := global.LoginFailure.Get(key)
+		info.Failures++
+
+		if setting.Enable && info.Failures >= v.maxFailures {
+			global.LoginFailure.Delete(key)
+			lockout := time.Duration(setting.LockoutMinutes) * time.Minute
+			if _, err := mLockout.Lock(global.Db, v.kind, v.value, ip, info.Failures, lockout); err != nil {
+				global.Logger.Errorln("login lockout:", err.Error())
+				continue
+			}
+			global.Logger.Warnln("login locked,", v.kind+":", v.value, "failures:", info.Failures, "minutes:", setting.LockoutMinutes)
+			continue
+		}
+
+		if setting.Enable {
+			info.NextAllowedAt = time.Now().Add(backoff(setting, info.Failures))
+		}
+		global.LoginFailure.Set(key, info, window)
+	}
+}
+
+// IP在统计周期内的登录失败次数
+func IpFailures(ip string) int {
+	info, _ := global.LoginFailure.Get(cacheKey(models.LOGIN_LOCKOUT_KIND_IP, ip))
+	return info.Failures
+}
+
+// 登录是否需要图形验证码：开启了登录验证码，或IP失败次数达到阈值
+func CaptchaRequired(setting systemSetting.Login, ip string) bool {
+	if setting.LoginCaptcha {
+		return true
+	}
+	return setting.LoginCaptchaAfterFailures > 0 && IpFailures(ip) >= setting.LoginCaptchaAfterFailures
+}
+
+// 登录成功，清除账号的失败次数(IP的失败次数继续保留，防止用已知账号重置计数)
+func Succeeded(username string) {
+	global.LoginFailure.Delete(cacheKey(models.LOGIN_LOCKOUT_KIND_USERNAME, normalizeUsername(username)))
+}
+
+// 解除锁定
+func Unlock(ids []uint) error {
+	mLockout := models.LoginLockout{}
+	list, err := mLockout.DeleteByIds(global.Db, ids)
+	for _, v := range list {
+		global.LoginFailure.Delete(cacheKey(v.Kind, v.Value))
+	}
+	return err
+}
+
+// 连续失败超过BackoffAfter次后，等待时间按次数翻倍
+func backoff(setting systemSetting.LoginLimit, failures int) time.Duration {
+	n := failures - setting.BackoffAfter
+	if n <= 0 {
+		return 0
+	}
+	maxDelay := time.Duration(setting.BackoffMaxSeconds) * time.Second
+	delay := time.Duration(setting.BackoffBaseSeconds) * time.Second
+	for i := 1; i < n && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+func getTargets(setting systemSetting.LoginLimit, username, ip string) []target {
+	targets := []target{}
+	if username = normalizeUsername(username); username != "" {
+		targets = append(targets, target{models.LOGIN_LOCKOUT_KIND_USERNAME, username, setting.UsernameMaxFailures})
+	}
+	if ip != "" {
+		targets = append(targets, target{models.LOGIN_LOCKOUT_KIND_IP, ip, setting.IpMaxFailures})
+	}
+	return targets
+}
+
+func normalizeUsername(username string) string {
+	return cmn.SubRuneStr(strings.ToLower(strings.TrimSpace(username)), 0, 100)
+}
+
+func cacheKey(kind, value string) string {
+	return kind + ":" + value
+}
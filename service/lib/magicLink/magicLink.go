@@ -0,0 +1,118 @@
+package magicLink
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sun-panel/global"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/models"
+	"sync"
+	"time"
+)
+
+// 邮件登录链接：token为 用户ID.过期时间.随机数.签名，签名包含用户当前的邮箱，修改邮箱后链接失效
+// 随机数保存在缓存中，使用后立即删除，保证链接只能使用一次
+
+const DEFAULT_EXPIRE_MINUTES = 15 // 未设置时链接的有效时长(分钟)
+
+var ErrInvalid = errors.New("invalid or expired login link")
+
+var keyMu sync.Mutex
+
+// 是否可以使用邮件登录，需要开启设置并填写站点地址
+func Enabled(settings systemSetting.ApplicationSetting) bool {
+	return settings.MagicLinkLogin && settings.WebSiteUrl != ""
+}
+
+// 链接的有效时长
+func ExpireDuration(settings systemSetting.ApplicationSetting) time.Duration {
+	minutes := settings.MagicLinkExpireMinutes
+	if minutes <= 0 {
+		minutes = DEFAULT_EXPIRE_MINUTES
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// 生成登录token
+func Generate(userInfo models.User, expire time.Duration) (string, error) {
+	key, err := signingKey()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(buf)
+	expiresAt := time.Now().Add(expire).Unix()
+
+	payload := strconv.FormatUint(uint64(userInfo.ID), 10) + "." + strconv.FormatInt(expiresAt, 10) + "." + nonce
+	global.MagicLinkNonce.Set(nonce, userInfo.ID, expire)
+	return payload + "." + sign(key, payload, userInfo.Mail), nil
+}
+
+// 验证登录token，验证通过后作废，返回对应的用户
+func Verify(token string) (models.User, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return models.User{}, ErrInvalid
+	}
+	userId, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return models.User{}, ErrInvalid
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return models.User{}, ErrInvalid
+	}
+
+	key, err := signingKey()
+	if err != nil {
+		return models.User{}, err
+	}
+	mUser := models.User{}
+	userInfo, err := mUser.GetUserInfoByUid(uint(userId))
+	if err != nil || userInfo.Mail == "" {
+		return models.User{}, ErrInvalid
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(sign(key, payload, userInfo.Mail)), []byte(parts[3])) {
+		return models.User{}, ErrInvalid
+	}
+
+	// 只能使用一次，取值和删除是一个原子操作，同时使用同一个链接时只有一个成功
+	if id, ok := global.MagicLinkNonce.GetDel(parts[2]); !ok || id != userInfo.ID {
+		return models.User{}, ErrInvalid
+	}
+	return userInfo, nil
+}
+
+func sign(key []byte, payload, mail string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload + "." + strings.ToLower(mail)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// 签名密钥，首次使用时生成并保存到系统设置
+func signingKey() ([]byte, error) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	if secret, err := global.SystemSetting.GetValueString(systemSetting.MAGIC_LINK_SECRET); err == nil && secret != "" {
+		return hex.DecodeString(secret)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	if err := global.SystemSetting.Set(systemSetting.MAGIC_LINK_SECRET, hex.EncodeToString(buf)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
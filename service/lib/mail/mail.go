@@ -1,6 +1,7 @@
 package mail
 
 import (
+	"strconv"
 	"sun-panel/global"
 )
 
@@ -42,6 +43,29 @@ func SendResetPasswordVCode(emailer *Emailer, mailTo, vcode string) error {
 	return err
 }
 
+// 发送登录链接
+//
+//	@param emailer
+//	@param mailTo
+//	@param link 登录链接
+//	@param minute 有效时长(分钟)
+//	@return error
+func SendMagicLink(emailer *Emailer, mailTo, link string, minute int) error {
+	appName := global.Lang.Get("common.app_name")
+	title := global.Lang.GetWithFields("mail.magic_link_title", map[string]string{
+		"AppName": appName,
+	})
+	content := global.Lang.GetWithFields("mail.magic_link_content", map[string]string{
+		"AppName": appName,
+		"Minute":  strconv.Itoa(minute),
+	})
+	err := emailer.SendMailOfLink(mailTo, title, content, global.Lang.Get("mail.magic_link_click_btn"), link)
+	if err != nil {
+		global.Logger.Errorf("failed to send email to %s, err:%+v\n", mailTo, err)
+	}
+	return err
+}
+
 // // 事件提醒
 // //
 // //	@param emailer
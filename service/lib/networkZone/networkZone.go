@@ -0,0 +1,160 @@
+package networkZone
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sun-panel/global"
+	"sun-panel/lib/cmn"
+	"sun-panel/models"
+)
+
+// 网络区域：根据访问者IP选择图标的地址，公开模式下不向外部访问者发送内部地址
+
+const cacheKey = "all"
+
+var ErrInvalidCidr = errors.New("invalid IP or CIDR")
+
+// 获取全部网络区域，优先使用缓存
+func GetList() ([]models.NetworkZone, error) {
+	if list, ok := global.NetworkZoneCache.Get(cacheKey); ok {
+		return list, nil
+	}
+	mZone := models.NetworkZone{}
+	list, err := mZone.GetList(global.Db)
+	if err != nil {
+		return nil, err
+	}
+	global.NetworkZoneCache.SetDefault(cacheKey, list)
+	return list, nil
+}
+
+// 清除缓存，修改网络区域后调用
+func ClearCache() {
+	global.NetworkZoneCache.Delete(cacheKey)
+}
+
+// 解析逗号分隔的IP或CIDR，单个IP视为只包含该地址的网段
+func ParseCidrs(cidrs string) ([]*net.IPNet, error) {
+	list := []*net.IPNet{}
+	for _, v := range cmn.SplitAndTrim(cidrs, ",") {
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, errors.New(ErrInvalidCidr.Error() + ": " + v)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			list = append(list, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, errors.New(ErrInvalidCidr.Error() + ": " + v)
+		}
+		list = append(list, ipNet)
+	}
+	return list, nil
+}
+
+// 获取IP所在的网络区域，不在任何区域时返回nil
+func Match(ip string) (*models.NetworkZone, error) {
+	clientIp := net.ParseIP(ip)
+	if clientIp == nil {
+		return nil, nil
+	}
+	list, err := GetList()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range list {
+		ipNets, err := ParseCidrs(v.Cidrs)
+		if err != nil {
+			global.Logger.Errorln("network zone", v.Name, err.Error())
+			continue
+		}
+		for _, ipNet := range ipNets {
+			if ipNet.Contains(clientIp) {
+				return &list[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// 根据访问者所在的网络区域设置图标的地址
+// hideInternal为true时(公开模式下的外部访问者)，去除局域网地址、内部区域的地址及其健康状态，
+// 以及检测失败的原因(可能包含解析到的内网地址)
+func Apply(items []models.ItemIcon, zone *models.NetworkZone, hideInternal bool) error {
+	if len(items) == 0 {
+		return nil
+	}
+	zones, err := GetList()
+	if err != nil {
+		return err
+	}
+	internalZones := map[uint]bool{}
+	for _, v := range zones {
+		internalZones[v.ID] = v.Internal
+	}
+
+	ids := []uint{}
+	for _, v := range items {
+		ids = append(ids, v.ID)
+	}
+	mZoneUrl := models.ItemIconZoneUrl{}
+	zoneUrls, err := mZoneUrl.GetByItemIconIds(global.Db, ids)
+	if err != nil {
+		return err
+	}
+	itemZoneUrls := map[uint][]models.ItemIconZoneUrl{}
+	for _, v := range zoneUrls {
+		itemZoneUrls[v.ItemIconId] = append(itemZoneUrls[v.ItemIconId], v)
+	}
+
+	for k := range items {
+		item := &items[k]
+		item.ZoneUrls = []models.ItemIconZoneUrl{}
+		item.ResolvedUrl = item.Url
+		zoneUrl := ""
+		for _, v := range itemZoneUrls[item.ID] {
+			if zone != nil && v.NetworkZoneId == zone.ID {
+				zoneUrl = v.Url
+			}
+			if hideInternal && internalZones[v.NetworkZoneId] {
+				continue
+			}
+			item.ZoneUrls = append(item.ZoneUrls, v)
+		}
+
+		// 优先使用所在区域的地址，内部区域没有设置时使用局域网地址
+		if zoneUrl != "" {
+			item.ResolvedUrl = zoneUrl
+		} else if zone != nil && zone.Internal && item.LanUrl != "" {
+			item.ResolvedUrl = item.LanUrl
+		}
+
+		if hideInternal {
+			item.LanUrl = ""
+			if item.Health != nil {
+				item.Health.LanUrl = nil
+				hideHealthMessage(item.Health.Url)
+			}
+		}
+	}
+	return nil
+}
+
+// 去除检测失败的原因，只保留是否正常等结果
+func hideHealthMessage(status *models.ItemIconHealthStatus) {
+	if status == nil {
+		return
+	}
+	status.Message = ""
+	for k := range status.History {
+		status.History[k].Message = ""
+	}
+}
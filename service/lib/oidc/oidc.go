@@ -0,0 +1,159 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"sun-panel/lib/cmn"
+	"sun-panel/structs"
+	"sync"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrNotEnabled  = errors.New("oidc login is not enabled")
+	ErrNoIdToken   = errors.New("no id_token in token response")
+	ErrNonceFailed = errors.New("nonce verification failed")
+)
+
+type Provider struct {
+	Config   structs.IniConfigOidc
+	provider *gooidc.Provider
+	verifier *gooidc.IDTokenVerifier
+}
+
+var (
+	providerLock  sync.Mutex
+	providerCache *Provider
+)
+
+// 获取签发者，发现文档会被缓存，配置变化后重新获取
+func GetProvider(ctx context.Context, cfg structs.IniConfigOidc) (*Provider, error) {
+	if !cfg.Enable || cfg.Issuer == "" || cfg.ClientId == "" {
+		return nil, ErrNotEnabled
+	}
+
+	providerLock.Lock()
+	defer providerLock.Unlock()
+	if providerCache != nil && providerCache.Config == cfg {
+		return providerCache, nil
+	}
+
+	provider, err := gooidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	providerCache = &Provider{
+		Config:   cfg,
+		provider: provider,
+		verifier: provider.Verifier(&gooidc.Config{ClientID: cfg.ClientId}),
+	}
+	return providerCache, nil
+}
+
+func (p *Provider) oauth2Config(redirectUrl string) oauth2.Config {
+	scopes := cmn.SplitAndTrim(p.Config.Scopes, ",")
+	if !cmn.InSlice(scopes, gooidc.ScopeOpenID) {
+		scopes = append([]string{gooidc.ScopeOpenID}, scopes...)
+	}
+	return oauth2.Config{
+		ClientID:     p.Config.ClientId,
+		ClientSecret: p.Config.ClientSecret,
+		RedirectURL:  redirectUrl,
+		Endpoint:     p.provider.Endpoint(),
+		Scopes:       scopes,
+	}
+}
+
+// 生成授权地址(授权码模式 + PKCE S256)
+func (p *Provider) AuthCodeURL(redirectUrl, state, nonce, codeVerifier string) string {
+	cfg := p.oauth2Config(redirectUrl)
+	return cfg.AuthCodeURL(state,
+		gooidc.Nonce(nonce),
+		oauth2.SetAuthURLParam("code_challenge", CodeChallengeS256(codeVerifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+}
+
+// 使用授权码换取token，验证id_token并返回合并了userinfo的claims
+func (p *Provider) Exchange(ctx context.Context, redirectUrl, code, codeVerifier, nonce string) (map[string]interface{}, error) {
+	cfg := p.oauth2Config(redirectUrl)
+	token, err := cfg.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIdToken == "" {
+		return nil, ErrNoIdToken
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIdToken)
+	if err != nil {
+		return nil, err
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrNonceFailed
+	}
+
+	claims := map[string]interface{}{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	// id_token中没有的claim从userinfo补充(部分签发者默认不在id_token中包含用户组)
+	if userInfo, err := p.provider.UserInfo(ctx, oauth2.StaticTokenSource(token)); err == nil {
+		userClaims := map[string]interface{}{}
+		if err := userInfo.Claims(&userClaims); err == nil && userClaims["sub"] == claims["sub"] {
+			for k, v := range userClaims {
+				if _, exist := claims[k]; !exist {
+					claims[k] = v
+				}
+			}
+		}
+	}
+
+	return claims, nil
+}
+
+// 随机字符串(base64url)，用于state、nonce、code_verifier
+func RandomString(byteLen int) (string, error) {
+	buf := make([]byte, byteLen)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func CodeChallengeS256(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// 获取字符串类型的claim
+func ClaimString(claims map[string]interface{}, name string) string {
+	if v, ok := claims[name].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// 获取字符串数组类型的claim，兼容单个字符串
+func ClaimStrings(claims map[string]interface{}, name string) []string {
+	result := []string{}
+	switch v := claims[name].(type) {
+	case string:
+		result = append(result, v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
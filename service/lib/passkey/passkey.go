@@ -0,0 +1,201 @@
+package passkey
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"sun-panel/global"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/models"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// 通行密钥(WebAuthn)，RP ID为站点地址的域名，只接受来自站点地址的请求
+
+const (
+	RP_DISPLAY_NAME   = "Sun-Panel"
+	CEREMONY_TIMEOUT  = 5 * time.Minute // 注册和登录挑战的有效期
+	MAX_PASSKEY_COUNT = 20              // 每个用户最多注册的数量
+)
+
+var (
+	ErrNotConfigured = errors.New("passkey requires the website URL to be set")
+	ErrCloneWarning  = errors.New("passkey sign count did not increase, the authenticator may be cloned")
+)
+
+// 根据站点地址创建WebAuthn实例
+func New() (*webauthn.WebAuthn, error) {
+	appSetting := systemSetting.ApplicationSetting{}
+	global.SystemSetting.GetValueByInterface(systemSetting.SYSTEM_APPLICATION, &appSetting)
+	return newWebAuthn(appSetting.WebSiteUrl)
+}
+
+func newWebAuthn(webSiteUrl string) (*webauthn.WebAuthn, error) {
+	siteUrl, err := url.Parse(webSiteUrl)
+	if webSiteUrl == "" || err != nil || siteUrl.Hostname() == "" {
+		return nil, ErrNotConfigured
+	}
+
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    CEREMONY_TIMEOUT,
+		TimeoutUVD: CEREMONY_TIMEOUT,
+	}
+	return webauthn.New(&webauthn.Config{
+		RPID:          siteUrl.Hostname(),
+		RPDisplayName: RP_DISPLAY_NAME,
+		RPOrigins:     []string{siteUrl.Scheme + "://" + siteUrl.Host},
+		// 尽量创建可发现凭据，支持不输入账号直接登录
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			RequireResidentKey: protocol.ResidentKeyNotRequired(),
+			ResidentKey:        protocol.ResidentKeyRequirementPreferred,
+			UserVerification:   protocol.VerificationPreferred,
+		},
+		AttestationPreference: protocol.PreferNoAttestation,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+}
+
+// 是否可以使用通行密钥
+func Enabled() bool {
+	_, err := New()
+	return err == nil
+}
+
+// WebAuthn用户，包含用户已注册的通行密钥
+type User struct {
+	Info     models.User
+	Passkeys []models.UserPasskey
+}
+
+// 获取用户和已注册的通行密钥
+func LoadUser(userInfo models.User) (*User, error) {
+	mPasskey := models.UserPasskey{}
+	passkeys, err := mPasskey.GetListByUserId(global.Db, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &User{Info: userInfo, Passkeys: passkeys}, nil
+}
+
+func (u *User) WebAuthnID() []byte {
+	return UserHandle(u.Info.ID)
+}
+
+func (u *User) WebAuthnName() string {
+	return u.Info.Username
+}
+
+func (u *User) WebAuthnDisplayName() string {
+	if u.Info.Name != "" {
+		return u.Info.Name
+	}
+	return u.Info.Username
+}
+
+func (u *User) WebAuthnIcon() string {
+	return ""
+}
+
+func (u *User) WebAuthnCredentials() []webauthn.Credential {
+	list := []webauthn.Credential{}
+	for _, v := range u.Passkeys {
+		if cred, err := toCredential(v); err == nil {
+			list = append(list, cred)
+		}
+	}
+	return list
+}
+
+// 已注册的凭据，注册时排除，避免同一认证器重复注册
+func (u *User) Exclusions() []protocol.CredentialDescriptor {
+	list := []protocol.CredentialDescriptor{}
+	for _, v := range u.WebAuthnCredentials() {
+		list = append(list, v.Descriptor())
+	}
+	return list
+}
+
+// 根据凭据ID查找用户的通行密钥
+func (u *User) GetPasskey(credentialId []byte) (models.UserPasskey, bool) {
+	id := EncodeId(credentialId)
+	for _, v := range u.Passkeys {
+		if v.CredentialId == id {
+			return v, true
+		}
+	}
+	return models.UserPasskey{}, false
+}
+
+// 检查登录验证通过的凭据，签名计数没有增加时认证器可能被复制，拒绝登录
+func CheckLogin(cred *webauthn.Credential) error {
+	if cred.Authenticator.CloneWarning {
+		return ErrCloneWarning
+	}
+	return nil
+}
+
+// 用户句柄，保存在可发现凭据中，无账号登录时用于查找用户
+func UserHandle(userId uint) []byte {
+	return []byte(strconv.FormatUint(uint64(userId), 10))
+}
+
+// 解析用户句柄
+func ParseUserHandle(userHandle []byte) (uint, error) {
+	id, err := strconv.ParseUint(string(userHandle), 10, 64)
+	return uint(id), err
+}
+
+func EncodeId(id []byte) string {
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+// 注册完成的凭据转为保存的通行密钥
+func NewPasskey(userId uint, name string, cred *webauthn.Credential) models.UserPasskey {
+	transports := []string{}
+	for _, v := range cred.Transport {
+		transports = append(transports, string(v))
+	}
+	return models.UserPasskey{
+		UserId:          userId,
+		Name:            name,
+		CredentialId:    EncodeId(cred.ID),
+		PublicKey:       EncodeId(cred.PublicKey),
+		AttestationType: cred.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		Aaguid:          hex.EncodeToString(cred.Authenticator.AAGUID),
+		SignCount:       cred.Authenticator.SignCount,
+		BackupEligible:  cred.Flags.BackupEligible,
+		BackupState:     cred.Flags.BackupState,
+	}
+}
+
+func toCredential(info models.UserPasskey) (webauthn.Credential, error) {
+	cred := webauthn.Credential{AttestationType: info.AttestationType}
+	var err error
+	if cred.ID, err = base64.RawURLEncoding.DecodeString(info.CredentialId); err != nil {
+		return cred, err
+	}
+	if cred.PublicKey, err = base64.RawURLEncoding.DecodeString(info.PublicKey); err != nil {
+		return cred, err
+	}
+	if info.Transports != "" {
+		for _, v := range strings.Split(info.Transports, ",") {
+			cred.Transport = append(cred.Transport, protocol.AuthenticatorTransport(v))
+		}
+	}
+	cred.Authenticator.AAGUID, _ = hex.DecodeString(info.Aaguid)
+	cred.Authenticator.SignCount = info.SignCount
+	cred.Flags.BackupEligible = info.BackupEligible
+	cred.Flags.BackupState = info.BackupState
+	return cred, nil
+}
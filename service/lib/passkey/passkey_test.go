@@ -0,0 +1,276 @@
+package passkey
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sun-panel/models"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const testSiteUrl = "https://panel.example.com"
+
+// 软件认证器，使用P-256密钥，不提供证明(attestation none)
+type softAuthenticator struct {
+	key    *ecdsa.PrivateKey
+	credId []byte
+	origin string
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credId := make([]byte, 16)
+	if _, err := rand.Read(credId); err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{key: key, credId: credId, origin: testSiteUrl}
+}
+
+// 认证器数据：rpIdHash | flags | signCount | attestedCredentialData
+func (a *softAuthenticator) authData(rpId string, flags byte, signCount uint32, attested []byte) []byte {
+	rpIdHash := sha256.Sum256([]byte(rpId))
+	data := append([]byte{}, rpIdHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, signCount)
+	return append(data, attested...)
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony, challenge string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{"type": ceremony, "challenge": challenge, "origin": a.origin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// 创建凭据，返回浏览器提交的注册结果
+func (a *softAuthenticator) create(t *testing.T, options *protocol.CredentialCreation) []byte {
+	t.Helper()
+	cosePublicKey, err := cbor.Marshal(map[int]interface{}{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credId)))
+	attested = append(attested, a.credId...)
+	attested = append(attested, cosePublicKey...)
+
+	// UP | UV | AT
+	authData := a.authData(options.Response.RelyingParty.ID, 0x45, 0, attested)
+	attestationObject, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.marshalCredential(t, map[string]interface{}{
+		"clientDataJSON":    a.clientData(t, "webauthn.create", options.Response.Challenge.String()),
+		"attestationObject": attestationObject,
+	})
+}
+
+// 使用凭据签名登录挑战，返回浏览器提交的登录结果
+func (a *softAuthenticator) get(t *testing.T, options *protocol.CredentialAssertion, flags byte, signCount uint32, userHandle []byte) []byte {
+	t.Helper()
+	authData := a.authData(options.Response.RelyingPartyID, flags, signCount, nil)
+	clientData := a.clientData(t, "webauthn.get", options.Response.Challenge.String())
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.marshalCredential(t, map[string]interface{}{
+		"clientDataJSON":    clientData,
+		"authenticatorData": authData,
+		"signature":         signature,
+		"userHandle":        userHandle,
+	})
+}
+
+func (a *softAuthenticator) marshalCredential(t *testing.T, response map[string]interface{}) []byte {
+	t.Helper()
+	encoded := map[string]string{}
+	for k, v := range response {
+		encoded[k] = EncodeId(v.([]byte))
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"id":       EncodeId(a.credId),
+		"rawId":    EncodeId(a.credId),
+		"type":     "public-key",
+		"response": encoded,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func testUser() *User {
+	info := models.User{Username: "alice", Name: "Alice"}
+	info.ID = 7
+	return &User{Info: info}
+}
+
+// 注册通行密钥，返回保存的通行密钥
+func register(t *testing.T, wa *webauthn.WebAuthn, user *User, authenticator *softAuthenticator) models.UserPasskey {
+	t.Helper()
+	options, session, err := wa.BeginRegistration(user, webauthn.WithExclusions(user.Exclusions()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(authenticator.create(t, options)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := wa.CreateCredential(user, *session, parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewPasskey(user.Info.ID, "test", cred)
+}
+
+// 登录，验证通过后检查凭据
+func login(t *testing.T, wa *webauthn.WebAuthn, user *User, authenticator *softAuthenticator, flags byte, signCount uint32) (*webauthn.Credential, error) {
+	t.Helper()
+	options, session, err := wa.BeginLogin(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := authenticator.get(t, options, flags, signCount, user.WebAuthnID())
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	cred, err := wa.ValidateLogin(user, *session, parsed)
+	if err != nil {
+		return nil, err
+	}
+	return cred, CheckLogin(cred)
+}
+
+func TestNewWebAuthn(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"", true},
+		{"/path-only", true},
+		{"https://panel.example.com", false},
+		{"http://localhost:3002/", false},
+	}
+	for _, tt := range tests {
+		if _, err := newWebAuthn(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("newWebAuthn(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	wa, err := newWebAuthn(testSiteUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := testUser()
+	authenticator := newSoftAuthenticator(t)
+
+	info := register(t, wa, user, authenticator)
+	if info.CredentialId != EncodeId(authenticator.credId) || info.UserId != user.Info.ID || info.SignCount != 0 {
+		t.Fatalf("unexpected passkey %+v", info)
+	}
+	user.Passkeys = append(user.Passkeys, info)
+
+	// 已注册的凭据在再次注册时排除
+	if exclusions := user.Exclusions(); len(exclusions) != 1 || !bytes.Equal(exclusions[0].CredentialID, authenticator.credId) {
+		t.Fatalf("unexpected exclusions %+v", exclusions)
+	}
+
+	// UP | UV
+	cred, err := login(t, wa, user, authenticator, 0x05, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cred.Flags.UserVerified || cred.Authenticator.SignCount != 1 {
+		t.Fatalf("unexpected credential flags %+v, sign count %d", cred.Flags, cred.Authenticator.SignCount)
+	}
+	if got, ok := user.GetPasskey(cred.ID); !ok || got.CredentialId != info.CredentialId {
+		t.Fatalf("GetPasskey() = %+v, %v", got, ok)
+	}
+
+	// 只有UP，未进行用户验证
+	cred, err = login(t, wa, user, authenticator, 0x01, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Flags.UserVerified {
+		t.Fatal("expected user not verified")
+	}
+}
+
+func TestLoginRejected(t *testing.T) {
+	wa, err := newWebAuthn(testSiteUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const storedSignCount = 5
+	tests := []struct {
+		name      string
+		signCount uint32
+		otherKey  bool
+		origin    string
+		wantErr   bool
+		wantClone bool
+	}{
+		{name: "sign count increased", signCount: storedSignCount + 1},
+		{name: "sign count unchanged", signCount: storedSignCount, wantErr: true, wantClone: true},
+		{name: "sign count decreased", signCount: storedSignCount - 2, wantErr: true, wantClone: true},
+		{name: "signed by another key", signCount: storedSignCount + 1, otherKey: true, wantErr: true},
+		{name: "other origin", signCount: storedSignCount + 1, origin: "https://evil.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := testUser()
+			authenticator := newSoftAuthenticator(t)
+			info := register(t, wa, user, authenticator)
+			info.SignCount = storedSignCount
+			user.Passkeys = append(user.Passkeys, info)
+
+			if tt.otherKey {
+				authenticator.key = newSoftAuthenticator(t).key
+			}
+			if tt.origin != "" {
+				authenticator.origin = tt.origin
+			}
+			_, err := login(t, wa, user, authenticator, 0x05, tt.signCount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("login error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrCloneWarning) != tt.wantClone {
+				t.Fatalf("login error = %v, want clone warning %v", err, tt.wantClone)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sun-panel/lib/cmn"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// 当前使用的 argon2id 参数，修改后旧的哈希会在下次登录时自动升级
+const (
+	argon2Memory  uint32 = 64 * 1024 // KiB
+	argon2Time    uint32 = 3
+	argon2Threads uint8  = 2
+	argon2KeyLen  uint32 = 32
+	saltLen              = 16
+
+	prefixArgon2id = "$argon2id$"
+	legacyMd5Len   = 32 // 旧版 Md5(Md5(Md5(password))) 的长度
+)
+
+var (
+	ErrInvalidHash         = errors.New("invalid password hash")
+	ErrIncompatibleVersion = errors.New("incompatible argon2 version")
+)
+
+type params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	keyLen  uint32
+}
+
+// 生成密码哈希
+// 格式：$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func Hash(password string) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		prefixArgon2id,
+		argon2.Version,
+		argon2Memory,
+		argon2Time,
+		argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// 验证密码
+// ok:密码是否正确 needRehash:哈希是否为旧格式或旧参数，需要重新生成
+func Verify(password, encoded string) (ok bool, needRehash bool) {
+	if IsLegacy(encoded) {
+		legacy := cmn.PasswordEncryption(password)
+		ok = subtle.ConstantTimeCompare([]byte(legacy), []byte(strings.ToLower(encoded))) == 1
+		return ok, ok
+	}
+
+	p, salt, key, err := decode(encoded)
+	if err != nil {
+		return false, false
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, p.keyLen)
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return false, false
+	}
+
+	needRehash = p.memory != argon2Memory || p.time != argon2Time || p.threads != argon2Threads || p.keyLen != argon2KeyLen
+	return true, needRehash
+}
+
+// 生成随机密码，用于命令行重置密码等场景
+func Random(length int) (string, error) {
+	const chars = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789" // 去掉容易混淆的字符
+	buf := make([]byte, length)
+	max := big.NewInt(int64(len(chars)))
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		buf[i] = chars[n.Int64()]
+	}
+	return string(buf), nil
+}
+
+// 是否为旧版的MD5密码
+func IsLegacy(encoded string) bool {
+	return len(encoded) == legacyMd5Len && !strings.HasPrefix(encoded, "$")
+}
+
+func decode(encoded string) (p params, salt, key []byte, err error) {
+	if !strings.HasPrefix(encoded, prefixArgon2id) {
+		err = ErrInvalidHash
+		return
+	}
+
+	// ["", "argon2id", "v=19", "m=65536,t=3,p=2", salt, hash]
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		err = ErrInvalidHash
+		return
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		err = ErrInvalidHash
+		return
+	}
+	if version != argon2.Version {
+		err = ErrIncompatibleVersion
+		return
+	}
+
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		err = ErrInvalidHash
+		return
+	}
+
+	if salt, err = base64.RawStdEncoding.Strict().DecodeString(parts[4]); err != nil {
+		err = ErrInvalidHash
+		return
+	}
+
+	if key, err = base64.RawStdEncoding.Strict().DecodeString(parts[5]); err != nil {
+		err = ErrInvalidHash
+		return
+	}
+	p.keyLen = uint32(len(key))
+	return
+}
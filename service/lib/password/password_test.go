@@ -0,0 +1,194 @@
+package password
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// 旧版密码：Md5(Md5(Md5(password)))
+func legacyHash(password string) string {
+	return md5Hex(md5Hex(md5Hex(password)))
+}
+
+// 使用指定参数生成哈希，模拟旧参数生成的哈希
+func hashWithParams(password string, memory, time uint32, threads uint8, keyLen uint32) string {
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, keyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, memory, time, threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func TestHashAndVerify(t *testing.T) {
+	encoded, err := Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=65536,t=3,p=2$") {
+		t.Fatalf("unexpected hash format %q", encoded)
+	}
+
+	tests := []struct {
+		password   string
+		wantOk     bool
+		wantRehash bool
+	}{
+		{"correct horse", true, false},
+		{"Correct horse", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		ok, needRehash := Verify(tt.password, encoded)
+		if ok != tt.wantOk || needRehash != tt.wantRehash {
+			t.Errorf("Verify(%q) = %v, %v, want %v, %v", tt.password, ok, needRehash, tt.wantOk, tt.wantRehash)
+		}
+	}
+
+	// 相同密码每次使用不同的盐
+	other, err := Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == encoded {
+		t.Fatal("expected different salts for the same password")
+	}
+}
+
+func TestVerifyLegacy(t *testing.T) {
+	legacy := legacyHash("123456")
+	tests := []struct {
+		name       string
+		password   string
+		encoded    string
+		wantOk     bool
+		wantRehash bool
+	}{
+		{"legacy md5", "123456", legacy, true, true},
+		{"legacy md5 upper case", "123456", strings.ToUpper(legacy), true, true},
+		{"legacy md5 wrong password", "1234567", legacy, false, false},
+		{"legacy md5 of md5", legacyHash("123456"), legacy, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needRehash := Verify(tt.password, tt.encoded)
+			if ok != tt.wantOk || needRehash != tt.wantRehash {
+				t.Fatalf("Verify() = %v, %v, want %v, %v", ok, needRehash, tt.wantOk, tt.wantRehash)
+			}
+		})
+	}
+}
+
+// 旧格式验证通过后重新生成的哈希为argon2id，且不再需要升级
+func TestLegacyUpgrade(t *testing.T) {
+	ok, needRehash := Verify("123456", legacyHash("123456"))
+	if !ok || !needRehash {
+		t.Fatalf("Verify() = %v, %v, want true, true", ok, needRehash)
+	}
+	upgraded, err := Hash("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsLegacy(upgraded) {
+		t.Fatalf("upgraded hash %q is still legacy", upgraded)
+	}
+	if ok, needRehash := Verify("123456", upgraded); !ok || needRehash {
+		t.Fatalf("Verify(upgraded) = %v, %v, want true, false", ok, needRehash)
+	}
+}
+
+func TestVerifyRehashOnParamsChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		encoded    string
+		wantRehash bool
+	}{
+		{"current params", hashWithParams("secret", argon2Memory, argon2Time, argon2Threads, argon2KeyLen), false},
+		{"lower memory", hashWithParams("secret", 32*1024, argon2Time, argon2Threads, argon2KeyLen), true},
+		{"fewer iterations", hashWithParams("secret", argon2Memory, 1, argon2Threads, argon2KeyLen), true},
+		{"more threads", hashWithParams("secret", argon2Memory, argon2Time, 4, argon2KeyLen), true},
+		{"shorter key", hashWithParams("secret", argon2Memory, argon2Time, argon2Threads, 16), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needRehash := Verify("secret", tt.encoded)
+			if !ok || needRehash != tt.wantRehash {
+				t.Fatalf("Verify() = %v, %v, want true, %v", ok, needRehash, tt.wantRehash)
+			}
+			if ok, _ := Verify("other", tt.encoded); ok {
+				t.Fatal("Verify() accepted a wrong password")
+			}
+		})
+	}
+}
+
+func TestVerifyInvalidHash(t *testing.T) {
+	valid := hashWithParams("secret", argon2Memory, argon2Time, argon2Threads, argon2KeyLen)
+	parts := strings.Split(valid, "$")
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"plain text", "secret"},
+		{"short md5", legacyHash("secret")[:31]},
+		{"other algorithm", strings.Replace(valid, "$argon2id$", "$argon2i$", 1)},
+		{"other version", strings.Replace(valid, "$v=19$", "$v=16$", 1)},
+		{"missing part", strings.Join(parts[:5], "$")},
+		{"bad params", strings.Replace(valid, "m=65536,t=3,p=2", "m=x,t=3,p=2", 1)},
+		{"bad salt", strings.Replace(valid, parts[4], "!!!!", 1)},
+		{"bad key", strings.Replace(valid, parts[5], "!!!!", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok, needRehash := Verify("secret", tt.encoded); ok || needRehash {
+				t.Fatalf("Verify() = %v, %v, want false, false", ok, needRehash)
+			}
+		})
+	}
+
+	if _, _, _, err := decode(strings.Replace(valid, "$v=19$", "$v=16$", 1)); err != ErrIncompatibleVersion {
+		t.Fatalf("decode() error = %v, want %v", err, ErrIncompatibleVersion)
+	}
+}
+
+func TestIsLegacy(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    bool
+	}{
+		{legacyHash("123456"), true},
+		{strings.Repeat("a", 31), false},
+		{"$" + strings.Repeat("a", 31), false},
+		{hashWithParams("secret", argon2Memory, argon2Time, argon2Threads, argon2KeyLen), false},
+	}
+	for _, tt := range tests {
+		if got := IsLegacy(tt.encoded); got != tt.want {
+			t.Errorf("IsLegacy(%q) = %v, want %v", tt.encoded, got, tt.want)
+		}
+	}
+}
+
+func TestRandom(t *testing.T) {
+	for _, length := range []int{1, 12, 32} {
+		s, err := Random(length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(s) != length {
+			t.Fatalf("Random(%d) length = %d", length, len(s))
+		}
+		if strings.ContainsAny(s, "0O1lIo") {
+			t.Fatalf("Random(%d) = %q contains ambiguous characters", length, s)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package permission
+
+import (
+	"strconv"
+	"sun-panel/global"
+	"sun-panel/models"
+)
+
+// 获取角色，优先使用缓存
+func GetRole(roleId int) (models.Role, error) {
+	key := strconv.Itoa(roleId)
+	if info, ok := global.RoleCache.Get(key); ok {
+		return info, nil
+	}
+	mRole := models.Role{}
+	info, err := mRole.GetById(global.Db, roleId)
+	if err != nil {
+		return info, err
+	}
+	global.RoleCache.SetDefault(key, info)
+	return info, nil
+}
+
+// 角色是否拥有某个权限，角色不存在时没有任何权限
+func Has(roleId int, permission string) bool {
+	info, err := GetRole(roleId)
+	if err != nil {
+		return false
+	}
+	return info.HasPermission(permission)
+}
+
+// 角色是否拥有管理类权限
+func IsAdmin(roleId int) bool {
+	info, err := GetRole(roleId)
+	if err != nil {
+		return false
+	}
+	return info.IsAdmin()
+}
+
+// 角色的权限列表
+func List(roleId int) []string {
+	info, err := GetRole(roleId)
+	if err != nil {
+		return []string{}
+	}
+	if info.ID == models.ROLE_ADMIN {
+		return models.Permissions
+	}
+	return info.PermissionList()
+}
+
+// 是否拥有全部权限，管理员角色拥有全部权限
+func HasAll(roleId int, permissions []string) bool {
+	info, err := GetRole(roleId)
+	if err != nil {
+		return false
+	}
+	for _, v := range permissions {
+		if !info.HasPermission(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// 是否可以将角色分配给用户(包括自己)，只有管理员可以分配管理员角色，
+// 其他角色只能分配权限不超过自己的角色，防止越权
+func CanAssign(actorRoleId, roleId int) bool {
+	if actorRoleId == models.ROLE_ADMIN {
+		return true
+	}
+	if roleId == models.ROLE_ADMIN {
+		return false
+	}
+	return HasAll(actorRoleId, List(roleId))
+}
+
+// 是否可以管理这些用户(删除、重置二次验证、吊销会话等)，不能管理权限超过自己的用户
+func CanManageUsers(actorRoleId int, userIds []uint) (bool, error) {
+	roles := []int{}
+	if err := global.Db.Model(&models.User{}).Where("id in ?", userIds).Distinct().Pluck("role", &roles).Error; err != nil {
+		return false, err
+	}
+	for _, v := range roles {
+		if !CanAssign(actorRoleId, v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// 修改或删除角色后清除缓存
+func ClearCache(roleId int) {
+	global.RoleCache.Delete(strconv.Itoa(roleId))
+}
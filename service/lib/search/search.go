@@ -0,0 +1,210 @@
+package search
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 搜索评分：关键字按空格拆分，每个词都需要匹配至少一个字段
+// 匹配方式按得分从高到低：完全相同、前缀、单词前缀、包含、模糊(按顺序包含全部字符)
+
+const (
+	SCORE_EXACT       = 100
+	SCORE_PREFIX      = 80
+	SCORE_WORD_PREFIX = 60
+	SCORE_CONTAINS    = 40
+	SCORE_FUZZY       = 20
+
+	fuzzyMinTermLength = 2 // 少于该长度的词不使用模糊匹配
+	fuzzyMaxSpanRatio  = 3 // 模糊匹配的跨度最多为词长度的倍数
+)
+
+// 匹配范围，按字符(非字节)计算，[开始,结束)
+type Range [2]int
+
+// 参与搜索的字段
+type Field struct {
+	Name   string
+	Text   string
+	Weight int // 权重，字段得分乘以权重
+}
+
+// 字段的匹配结果
+type FieldMatch struct {
+	Field  string  `json:"field"`
+	Text   string  `json:"text"`
+	Ranges []Range `json:"ranges"`
+}
+
+// 拆分关键字，转为小写
+func Terms(keyword string) []string {
+	return strings.Fields(strings.ToLower(keyword))
+}
+
+// 计算记录的得分，有词没有匹配任何字段时ok为false
+func Score(fields []Field, terms []string) (score int, matches []FieldMatch, ok bool) {
+	if len(terms) == 0 {
+		return 0, nil, false
+	}
+	fieldRanges := make([][]Range, len(fields))
+	for _, term := range terms {
+		termScore := 0
+		for i, f := range fields {
+			s, ranges := Match(f.Text, term)
+			if s == 0 {
+				continue
+			}
+			termScore += s * f.Weight
+			fieldRanges[i] = append(fieldRanges[i], ranges...)
+		}
+		if termScore == 0 {
+			return 0, nil, false
+		}
+		score += termScore
+	}
+
+	for i, f := range fields {
+		if len(fieldRanges[i]) > 0 {
+			matches = append(matches, FieldMatch{Field: f.Name, Text: f.Text, Ranges: mergeRanges(fieldRanges[i])})
+		}
+	}
+	return score, matches, true
+}
+
+// 单个词与文本匹配，返回得分和匹配范围，不匹配时得分为0
+func Match(text, term string) (int, []Range) {
+	textRunes := []rune(strings.ToLower(text))
+	termRunes := []rune(term)
+	if len(termRunes) == 0 || len(textRunes) == 0 {
+		return 0, nil
+	}
+
+	if pos := indexRunes(textRunes, termRunes, 0); pos >= 0 {
+		r := []Range{{pos, pos + len(termRunes)}}
+		switch {
+		case pos == 0 && len(textRunes) == len(termRunes):
+			return SCORE_EXACT, r
+		case pos == 0:
+			return SCORE_PREFIX, r
+		}
+		// 优先使用单词开头的位置
+		for p := pos; p >= 0; p = indexRunes(textRunes, termRunes, p+1) {
+			if isWordStart(textRunes, p) {
+				return SCORE_WORD_PREFIX, []Range{{p, p + len(termRunes)}}
+			}
+		}
+		return SCORE_CONTAINS, r
+	}
+
+	if len(termRunes) < fuzzyMinTermLength {
+		return 0, nil
+	}
+	return fuzzy(textRunes, termRunes)
+}
+
+// 模糊匹配：按顺序包含词的全部字符，跨度越小得分越高
+func fuzzy(text, term []rune) (int, []Range) {
+	best := -1
+	var bestRanges []Range
+	for start := 0; start < len(text); start++ {
+		if text[start] != term[0] {
+			continue
+		}
+		ranges := []Range{}
+		j := 0
+		end := start
+		for i := start; i < len(text) && j < len(term); i++ {
+			if text[i] == term[j] {
+				ranges = append(ranges, Range{i, i + 1})
+				j++
+				end = i
+			}
+		}
+		if j < len(term) {
+			break
+		}
+		span := end - start + 1
+		if span > len(term)*fuzzyMaxSpanRatio {
+			continue
+		}
+		if best == -1 || span < best {
+			best = span
+			bestRanges = mergeRanges(ranges)
+		}
+	}
+	if best == -1 {
+		return 0, nil
+	}
+	score := SCORE_FUZZY - (best - len(term))
+	if score < 1 {
+		score = 1
+	}
+	return score, bestRanges
+}
+
+// 获取地址的域名，没有协议时按http处理
+func UrlHost(rawUrl string) string {
+	rawUrl = strings.TrimSpace(rawUrl)
+	if rawUrl == "" {
+		return ""
+	}
+	if !strings.Contains(rawUrl, "://") {
+		rawUrl = "http://" + rawUrl
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+func indexRunes(text, term []rune, from int) int {
+	for i := from; i+len(term) <= len(text); i++ {
+		match := true
+		for j := range term {
+			if text[i+j] != term[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+func isWordStart(text []rune, pos int) bool {
+	if pos == 0 {
+		return true
+	}
+	prev, cur := text[pos-1], text[pos]
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+	// 中文等没有空格分隔的文字，每个字都视为单词开头
+	return unicode.Is(unicode.Han, cur)
+}
+
+// 合并重叠或相邻的范围
+func mergeRanges(ranges []Range) []Range {
+	if len(ranges) == 0 {
+		return ranges
+	}
+	sorted := append([]Range{}, ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+	merged := []Range{sorted[0]}
+	for _, v := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if v[0] <= last[1] {
+			if v[1] > last[1] {
+				last[1] = v[1]
+			}
+			continue
+		}
+		merged = append(merged, v)
+	}
+	return merged
+}
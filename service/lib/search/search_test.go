@@ -0,0 +1,183 @@
+package search
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		keyword string
+		want    []string
+	}{
+		{"", []string{}},
+		{"   ", []string{}},
+		{"Grafana", []string{"grafana"}},
+		{"  Home   ASSISTANT ", []string{"home", "assistant"}},
+	}
+	for _, tt := range tests {
+		if got := Terms(tt.keyword); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %v, want %v", tt.keyword, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		term       string
+		wantScore  int
+		wantRanges []Range
+	}{
+		{"exact", "Grafana", "grafana", SCORE_EXACT, []Range{{0, 7}}},
+		{"prefix", "Grafana Dashboard", "graf", SCORE_PREFIX, []Range{{0, 4}}},
+		{"word prefix", "Home Assistant", "assist", SCORE_WORD_PREFIX, []Range{{5, 11}}},
+		{"word prefix after separator", "my-nas", "nas", SCORE_WORD_PREFIX, []Range{{3, 6}}},
+		{"later word prefix preferred", "dynast nas", "nas", SCORE_WORD_PREFIX, []Range{{7, 10}}},
+		{"contains", "Portainer", "tainer", SCORE_CONTAINS, []Range{{3, 9}}},
+		{"case insensitive", "NextCloud", "cloud", SCORE_CONTAINS, []Range{{4, 9}}},
+		{"han characters are word starts", "家庭影院", "影院", SCORE_WORD_PREFIX, []Range{{2, 4}}},
+		{"fuzzy", "Jellyfin", "jlf", SCORE_FUZZY - 3, []Range{{0, 1}, {2, 3}, {5, 6}}},
+		{"fuzzy adjacent", "abc", "ac", SCORE_FUZZY - 1, []Range{{0, 1}, {2, 3}}},
+		{"fuzzy span too wide", "abcdefghijz", "az", 0, nil},
+		{"single character no fuzzy", "abc", "z", 0, nil},
+		{"no match", "Grafana", "plex", 0, nil},
+		{"empty text", "", "plex", 0, nil},
+		{"empty term", "Grafana", "", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, ranges := Match(tt.text, tt.term)
+			if score != tt.wantScore || !reflect.DeepEqual(ranges, tt.wantRanges) {
+				t.Fatalf("Match(%q, %q) = %d, %v, want %d, %v", tt.text, tt.term, score, ranges, tt.wantScore, tt.wantRanges)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	fields := []Field{
+		{Name: "title", Text: "Grafana", Weight: 10},
+		{Name: "description", Text: "graf charts", Weight: 3},
+	}
+	score, matches, ok := Score(fields, []string{"graf"})
+	if !ok {
+		t.Fatal("Score() ok = false")
+	}
+	if want := SCORE_PREFIX*10 + SCORE_PREFIX*3; score != want {
+		t.Fatalf("Score() = %d, want %d", score, want)
+	}
+	wantMatches := []FieldMatch{
+		{Field: "title", Text: "Grafana", Ranges: []Range{{0, 4}}},
+		{Field: "description", Text: "graf charts", Ranges: []Range{{0, 4}}},
+	}
+	if !reflect.DeepEqual(matches, wantMatches) {
+		t.Fatalf("Score() matches = %+v, want %+v", matches, wantMatches)
+	}
+}
+
+// 每个词都需要匹配参与搜索的字段之一，未传入的字段(如外部访问者的局域网地址)不能匹配
+func TestScoreScope(t *testing.T) {
+	title := Field{Name: "title", Text: "Home Assistant", Weight: 10}
+	lanUrl := Field{Name: "lanUrl", Text: UrlHost("http://192.168.1.10:8123"), Weight: 6}
+	tests := []struct {
+		name   string
+		fields []Field
+		terms  []string
+		wantOk bool
+	}{
+		{"all terms match", []Field{title}, []string{"home", "assist"}, true},
+		{"terms match different fields", []Field{title, lanUrl}, []string{"home", "192.168"}, true},
+		{"one term does not match", []Field{title}, []string{"home", "plex"}, false},
+		{"field not searched", []Field{title}, []string{"192.168"}, false},
+		{"field searched", []Field{title, lanUrl}, []string{"192.168"}, true},
+		{"no terms", []Field{title}, []string{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, ok := Score(tt.fields, tt.terms); ok != tt.wantOk {
+				t.Fatalf("Score() ok = %v, want %v", ok, tt.wantOk)
+			}
+		})
+	}
+}
+
+// 匹配方式和字段权重决定排序
+func TestScoreRanking(t *testing.T) {
+	records := []struct {
+		name   string
+		fields []Field
+	}{
+		{"description word prefix", []Field{{Name: "title", Text: "Prometheus", Weight: 10}, {Name: "description", Text: "Metrics in grafana", Weight: 3}}},
+		{"title prefix", []Field{{Name: "title", Text: "Grafana Dashboard", Weight: 10}}},
+		{"title fuzzy", []Field{{Name: "title", Text: "Gra-Fa-N-A", Weight: 10}}},
+		{"title exact", []Field{{Name: "title", Text: "Grafana", Weight: 10}}},
+		{"host contains", []Field{{Name: "title", Text: "Monitor", Weight: 10}, {Name: "url", Text: UrlHost("https://mygrafana.example.com"), Weight: 6}}},
+		{"no match", []Field{{Name: "title", Text: "Plex", Weight: 10}}},
+	}
+	type result struct {
+		name  string
+		score int
+	}
+	results := []result{}
+	for _, v := range records {
+		if score, _, ok := Score(v.fields, Terms("grafana")); ok {
+			results = append(results, result{v.name, score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].score > results[j].score })
+
+	names := []string{}
+	for _, v := range results {
+		names = append(names, v.name)
+	}
+	want := []string{"title exact", "title prefix", "host contains", "description word prefix", "title fuzzy"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("ranking = %v (%v), want %v", names, results, want)
+	}
+}
+
+func TestScoreMergesRanges(t *testing.T) {
+	fields := []Field{{Name: "title", Text: "Home Assistant", Weight: 1}}
+	tests := []struct {
+		terms []string
+		want  []Range
+	}{
+		{[]string{"home", "ass"}, []Range{{0, 4}, {5, 8}}},
+		{[]string{"hom", "home"}, []Range{{0, 4}}},
+		{[]string{"home", "me"}, []Range{{0, 4}}},
+	}
+	for _, tt := range tests {
+		_, matches, ok := Score(fields, tt.terms)
+		if !ok || len(matches) != 1 || !reflect.DeepEqual(matches[0].Ranges, tt.want) {
+			t.Errorf("Score(%v) matches = %+v, want ranges %v", tt.terms, matches, tt.want)
+		}
+	}
+}
+
+func TestMergeRanges(t *testing.T) {
+	got := mergeRanges([]Range{{5, 6}, {0, 2}, {2, 3}, {1, 2}})
+	if want := []Range{{0, 3}, {5, 6}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("mergeRanges() = %v, want %v", got, want)
+	}
+}
+
+func TestUrlHost(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"", ""},
+		{"https://nas.local:5000/path", "nas.local"},
+		{"192.168.1.2:8080", "192.168.1.2"},
+		{" grafana.example.com ", "grafana.example.com"},
+		{"http://[::1]:80/", "::1"},
+	}
+	for _, tt := range tests {
+		if got := UrlHost(tt.url); got != tt.want {
+			t.Errorf("UrlHost(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
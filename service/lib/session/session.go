@@ -0,0 +1,145 @@
+package session
+
+import (
+	"strconv"
+	"sun-panel/global"
+	"sun-panel/lib/cmn"
+	"sun-panel/models"
+	"time"
+)
+
+// 创建登录会话，返回会话信息和明文token
+func Create(userId uint, ip, userAgent string) (models.UserSession, string, error) {
+	mSession := models.UserSession{}
+	// 顺便清理过期的会话
+	if err := mSession.DeleteExpired(global.Db); err != nil {
+		global.Logger.Errorln("delete expired sessions:", err.Error())
+	}
+
+	info, token, err := mSession.Create(global.Db, userId, cmn.ParseUserAgentDevice(userAgent), ip, userAgent)
+	if err != nil {
+		return info, "", err
+	}
+	global.CUserToken.SetDefault(info.TokenHash, info)
+	return info, token, nil
+}
+
+// 创建模拟登录会话，管理员以该用户身份只读查看，到期后失效
+func CreateImpersonation(userId, adminId uint, duration time.Duration, ip, userAgent string) (models.UserSession, string, error) {
+	mSession := models.UserSession{}
+	info, token, err := mSession.CreateImpersonation(global.Db, userId, adminId, time.Now().Add(duration), cmn.ParseUserAgentDevice(userAgent), ip, userAgent)
+	if err != nil {
+		return info, "", err
+	}
+	global.CUserToken.SetDefault(info.TokenHash, info)
+	return info, token, nil
+}
+
+// 根据token获取会话和对应的用户，并更新最后访问时间
+func GetByToken(token, ip string) (models.User, models.UserSession, error) {
+	tokenHash := models.HashToken(token)
+	info, ok := global.CUserToken.Get(tokenHash)
+	if !ok || info.IsExpired() {
+		var err error
+		mSession := models.UserSession{}
+		if info, err = mSession.GetByTokenHash(global.Db, tokenHash); err != nil {
+			global.CUserToken.Delete(tokenHash)
+			return models.User{}, info, err
+		}
+		global.CUserToken.SetDefault(tokenHash, info)
+	}
+
+	if updated, err := info.Touch(global.Db, ip); err == models.ErrSessionInvalid {
+		global.CUserToken.Delete(tokenHash)
+		return models.User{}, info, err
+	} else if err != nil {
+		global.Logger.Errorln("update session:", err.Error())
+	} else if updated {
+		global.CUserToken.SetDefault(tokenHash, info)
+	}
+
+	// 停用或过期的账号会话无效
+	userInfo, err := GetUser(info.UserId)
+	if err != nil || !userInfo.IsActive() {
+		return userInfo, info, models.ErrSessionInvalid
+	}
+	// 发起模拟登录的管理员被停用或不再是管理员后会话无效
+	if info.IsImpersonation() && !canImpersonate(info.ImpersonatedBy) {
+		return userInfo, info, models.ErrSessionInvalid
+	}
+	return userInfo, info, nil
+}
+
+// 获取用户信息，优先使用缓存
+func GetUser(userId uint) (models.User, error) {
+	if userInfo, ok := global.UserToken.Get(userCacheKey(userId)); ok {
+		return userInfo, nil
+	}
+	mUser := models.User{}
+	userInfo, err := mUser.GetUserInfoByUid(userId)
+	if err != nil {
+		return userInfo, err
+	}
+	userInfo.Password = ""
+	global.UserToken.SetDefault(userCacheKey(userId), userInfo)
+	return userInfo, nil
+}
+
+// 清除用户信息缓存，修改用户资料、角色、状态后调用
+func ClearUserCache(userId uint) {
+	global.UserToken.Delete(userCacheKey(userId))
+}
+
+// 吊销单个token(退出登录)
+func RevokeToken(token string) error {
+	tokenHash := models.HashToken(token)
+	global.CUserToken.Delete(tokenHash)
+	return global.Db.Unscoped().Delete(&models.UserSession{}, "token_hash=?", tokenHash).Error
+}
+
+// 吊销指定的会话，userId为0时不限制用户(管理员)
+func RevokeByIds(userId uint, ids []uint) error {
+	mSession := models.UserSession{}
+	hashes, err := mSession.DeleteByIds(global.Db, userId, ids)
+	clearSessionCache(hashes)
+	return err
+}
+
+// 吊销用户的全部会话，exceptId不为0时保留该会话
+func RevokeByUserIds(userIds []uint, exceptId uint) error {
+	mSession := models.UserSession{}
+	hashes, err := mSession.DeleteByUserIds(global.Db, userIds, exceptId)
+	clearSessionCache(hashes)
+	return err
+}
+
+// 清除用户的用户信息缓存和全部会话缓存，停用账号后调用
+func ClearUserAndSessionCache(userIds []uint) error {
+	hashes := []string{}
+	if err := global.Db.Model(&models.UserSession{}).Where("user_id in ?", userIds).Pluck("token_hash", &hashes).Error; err != nil {
+		return err
+	}
+	clearSessionCache(hashes)
+	for _, v := range userIds {
+		ClearUserCache(v)
+	}
+	return nil
+}
+
+func canImpersonate(adminId uint) bool {
+	adminInfo, err := GetUser(adminId)
+	if err != nil || !adminInfo.IsActive() {
+		return false
+	}
+	return adminInfo.Role == models.ROLE_ADMIN
+}
+
+func clearSessionCache(hashes []string) {
+	for _, v := range hashes {
+		global.CUserToken.Delete(v)
+	}
+}
+
+func userCacheKey(userId uint) string {
+	return strconv.FormatUint(uint64(userId), 10)
+}
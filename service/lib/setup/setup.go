@@ -0,0 +1,74 @@
+package setup
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"sun-panel/global"
+	"sun-panel/models"
+	"sync"
+)
+
+// 首次运行设置：数据库中没有任何用户时，只能访问设置接口创建管理员
+// 设置令牌在启动时输出到日志，无界面安装时也可以完成设置
+
+var (
+	ErrNotRequired  = errors.New("setup has already been completed")
+	ErrInvalidToken = errors.New("invalid setup token")
+)
+
+var (
+	mu       sync.Mutex
+	required bool
+	token    string
+)
+
+// 检查是否需要首次设置，需要时生成设置令牌并输出到日志
+func Init() error {
+	var count int64
+	if err := global.Db.Unscoped().Model(&models.User{}).Count(&count).Error; err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	required = count == 0
+	if !required {
+		token = ""
+		return nil
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	token = hex.EncodeToString(buf)
+	global.Logger.Warnln("No user exists, please complete the initial setup in the browser. Setup token:", token)
+	return nil
+}
+
+// 是否需要首次设置
+func Required() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return required
+}
+
+// 验证设置令牌后执行设置，成功后令牌作废，同一时间只能执行一次
+func Run(setupToken string, fn func() error) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if !required {
+		return ErrNotRequired
+	}
+	if setupToken == "" || subtle.ConstantTimeCompare([]byte(setupToken), []byte(token)) != 1 {
+		return ErrInvalidToken
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	required = false
+	token = ""
+	return nil
+}
@@ -0,0 +1,126 @@
+package team
+
+import (
+	"sun-panel/global"
+	"sun-panel/lib/cmn/systemSetting"
+	"sun-panel/models"
+)
+
+// 用户对分组的访问权限
+type Access struct {
+	UserId       uint
+	Roles        map[uint]int // 团队ID:成员角色
+	PublicUserId uint         // 公开账号，其登录可见的个人分组共享给其他登录用户，为0时没有
+}
+
+// 获取用户的团队访问权限
+func GetAccess(userId uint) (Access, error) {
+	mMember := models.TeamMember{}
+	roles, err := mMember.GetRolesByUserId(global.Db, userId)
+	if err != nil {
+		return Access{}, err
+	}
+	access := Access{UserId: userId, Roles: roles}
+	var publicUserId *uint
+	if err := global.SystemSetting.GetValueByInterface(systemSetting.PANEL_PUBLIC_USER_ID, &publicUserId); err == nil && publicUserId != nil && *publicUserId != userId {
+		access.PublicUserId = *publicUserId
+	}
+	return access, nil
+}
+
+// 加入的全部团队
+func (a Access) TeamIds() []uint {
+	ids := []uint{}
+	for teamId := range a.Roles {
+		ids = append(ids, teamId)
+	}
+	return ids
+}
+
+// 作为编辑者的团队
+func (a Access) EditorTeamIds() []uint {
+	ids := []uint{}
+	for teamId, role := range a.Roles {
+		if role == models.TEAM_MEMBER_ROLE_EDITOR {
+			ids = append(ids, teamId)
+		}
+	}
+	return ids
+}
+
+// 是否可以查看分组
+func (a Access) CanView(group models.ItemIconGroup) bool {
+	if group.TeamId == 0 {
+		return group.UserId == a.UserId || a.IsPublicUserGroup(group)
+	}
+	_, ok := a.Roles[group.TeamId]
+	return ok
+}
+
+// 是否可以编辑分组及其中的图标
+func (a Access) CanEdit(group models.ItemIconGroup) bool {
+	if group.TeamId == 0 {
+		return group.UserId == a.UserId
+	}
+	return a.Roles[group.TeamId] == models.TEAM_MEMBER_ROLE_EDITOR
+}
+
+// 是否为共享给登录用户的公开账号分组
+func (a Access) IsPublicUserGroup(group models.ItemIconGroup) bool {
+	return a.PublicUserId != 0 && group.TeamId == 0 && group.UserId == a.PublicUserId && group.Visibility == models.VISIBILITY_LOGIN
+}
+
+// 按可见性是否可以查看分组或其中的图标，public为公开模式的访客
+func (a Access) CanSee(group models.ItemIconGroup, visibility string, public bool) bool {
+	switch visibility {
+	case models.VISIBILITY_LOGIN:
+		return !public
+	case models.VISIBILITY_PRIVATE:
+		return !public && a.CanEdit(group)
+	}
+	return true
+}
+
+// 获取分组，分组不存在或者无权查看时ok为false
+func (a Access) GetGroup(groupId uint) (group models.ItemIconGroup, ok bool, err error) {
+	if err = global.Db.Limit(1).Find(&group, "id=?", groupId).Error; err != nil {
+		return
+	}
+	ok = group.ID != 0 && a.CanView(group)
+	return
+}
+
+// 可以查看的分组ID：个人分组、所在团队的分组和公开账号共享的分组
+func (a Access) ViewableGroupIds() ([]uint, error) {
+	ids := []uint{}
+	db := global.Db.Model(&models.ItemIconGroup{}).Where("user_id=? AND team_id=0", a.UserId)
+	if teamIds := a.TeamIds(); len(teamIds) > 0 {
+		db = db.Or("team_id in ?", teamIds)
+	}
+	if a.PublicUserId != 0 {
+		db = db.Or("user_id=? AND team_id=0 AND visibility=?", a.PublicUserId, models.VISIBILITY_LOGIN)
+	}
+	err := db.Pluck("id", &ids).Error
+	return ids, err
+}
+
+// 公开账号共享给登录用户的分组
+func (a Access) PublicUserGroups() ([]models.ItemIconGroup, error) {
+	groups := []models.ItemIconGroup{}
+	if a.PublicUserId == 0 {
+		return groups, nil
+	}
+	err := global.Db.Order("sort ,created_at").Find(&groups, "user_id=? AND team_id=0 AND visibility=?", a.PublicUserId, models.VISIBILITY_LOGIN).Error
+	return groups, err
+}
+
+// 可以编辑的分组ID：个人分组和作为编辑者的团队分组
+func (a Access) EditableGroupIds() ([]uint, error) {
+	ids := []uint{}
+	db := global.Db.Model(&models.ItemIconGroup{}).Where("user_id=? AND team_id=0", a.UserId)
+	if teamIds := a.EditorTeamIds(); len(teamIds) > 0 {
+		db = db.Or("team_id in ?", teamIds)
+	}
+	err := db.Pluck("id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,170 @@
+package totp
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录B的SHA1密钥 "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 附录B的SHA1测试向量，验证码取8位结果的后6位
+func TestCodeAtRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},          // 94287082
+		{1111111109, "081804"},  // 07081804
+		{1111111111, "050471"},  // 14050471
+		{1234567890, "005924"},  // 89005924
+		{2000000000, "279037"},  // 69279037
+		{20000000000, "353130"}, // 65353130
+	}
+	for _, tt := range tests {
+		got, err := CodeAt(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("CodeAt(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeAtSecretFormat(t *testing.T) {
+	want, _ := CodeAt(rfcSecret, 1)
+	for _, secret := range []string{strings.ToLower(rfcSecret), " " + rfcSecret + " "} {
+		got, err := CodeAt(secret, 1)
+		if err != nil || got != want {
+			t.Errorf("CodeAt(%q) = %s, %v, want %s", secret, got, err, want)
+		}
+	}
+	if _, err := CodeAt("not base32!", 1); err == nil {
+		t.Error("CodeAt() expected error for invalid secret")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	tests := []struct {
+		name   string
+		offset int64
+		wantOk bool
+	}{
+		{"current step", 0, true},
+		{"previous step", -1, true},
+		{"next step", 1, true},
+		{"two steps ago", -2, false},
+		{"two steps ahead", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := CodeAt(rfcSecret, current+tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			step, ok := Validate(rfcSecret, code, now)
+			if ok != tt.wantOk {
+				t.Fatalf("Validate() ok = %v, want %v", ok, tt.wantOk)
+			}
+			// 返回匹配的步数，用于防止重复使用
+			if ok && step != current+tt.offset {
+				t.Fatalf("Validate() step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidCode(t *testing.T) {
+	now := time.Unix(59, 0)
+	tests := []struct {
+		name   string
+		code   string
+		secret string
+		wantOk bool
+	}{
+		{"valid", "287082", rfcSecret, true},
+		{"surrounding spaces", " 287082 ", rfcSecret, true},
+		{"wrong code", "287083", rfcSecret, false},
+		{"eight digits", "94287082", rfcSecret, false},
+		{"too short", "28708", rfcSecret, false},
+		{"empty", "", rfcSecret, false},
+		{"invalid secret", "287082", "not base32!", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.secret, tt.code, now); ok != tt.wantOk {
+				t.Fatalf("Validate(%q) ok = %v, want %v", tt.code, ok, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 20字节的base32编码为32个字符
+	if len(secret) != 32 {
+		t.Fatalf("GenerateSecret() length = %d, want 32", len(secret))
+	}
+	if _, err := CodeAt(secret, 1); err != nil {
+		t.Fatalf("generated secret is not valid base32: %v", err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Sun-Panel", "alice", rfcSecret)
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Sun-Panel:alice" {
+		t.Fatalf("unexpected uri %q", uri)
+	}
+	query := u.Query()
+	want := map[string]string{"secret": rfcSecret, "issuer": "Sun-Panel", "algorithm": "SHA1", "digits": "6", "period": "30"}
+	for k, v := range want {
+		if query.Get(k) != v {
+			t.Errorf("query %s = %q, want %q", k, query.Get(k), v)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("GenerateRecoveryCodes() count = %d, want 10", len(codes))
+	}
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	used := map[string]bool{}
+	for _, v := range codes {
+		if !format.MatchString(v) {
+			t.Errorf("recovery code %q has invalid format", v)
+		}
+		if used[v] {
+			t.Errorf("duplicate recovery code %q", v)
+		}
+		used[v] = true
+	}
+
+	// 忽略大小写、空格和分隔符
+	hash := HashRecoveryCode("abcde-fghij")
+	for _, v := range []string{"ABCDE-FGHIJ", " abcdefghij ", "abcde-fghij"} {
+		if got := HashRecoveryCode(v); got != hash {
+			t.Errorf("HashRecoveryCode(%q) = %s, want %s", v, got, hash)
+		}
+	}
+	if HashRecoveryCode("abcde-fghik") == hash {
+		t.Error("different recovery codes have the same hash")
+	}
+}
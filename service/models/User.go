@@ -2,24 +2,67 @@ package models
 
 import (
 	"errors"
+	"strings"
+	"sun-panel/lib/password"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 // 用户表
 type User struct {
 	BaseModel
-	Username     string `gorm:"index:;index:idx_username_password,priority:1;type:varchar(50)" json:"username" validate:"required"` // 账号
-	Password     string `gorm:"index:idx_username_password;type:varchar(32)" json:"password" validate:"required"`                   // 密码
-	Name         string `gorm:"type:varchar(20)" json:"name"`                                                                       // 名称
-	HeadImage    string `gorm:"type:varchar(200)" json:"headImage"`                                                                 // 头像地址
-	Status       int    `gorm:"type:tinyint(1)" json:"status"`                                                                      // 状态 1.启用 2.停用 3.未激活
-	Role         int    `gorm:"type:int(11)" json:"role"`                                                                           // 角色 1.管理员 2.普通用户
-	Mail         string `gorm:"type:varchar(50)" json:"mail"`                                                                       // 邮箱
-	ReferralCode string `gorm:"type:varchar(10)" json:"referralCode"`                                                               // 推荐码
-	Token        string `gorm:"type:varchar(32)" json:"token"`
+	Username     string `gorm:"index:;type:varchar(50)" json:"username" validate:"required"` // 账号
+	Password     string `gorm:"type:varchar(255)" json:"password" validate:"required"`       // 密码 argon2id哈希(兼容旧版32位MD5)
+	Name         string `gorm:"type:varchar(20)" json:"name"`                                // 名称
+	HeadImage    string `gorm:"type:varchar(200)" json:"headImage"`                          // 头像地址
+	Status       int    `gorm:"type:tinyint(1)" json:"status"`                               // 状态 1.启用 2.停用 3.未激活
+	Role         int    `gorm:"type:int(11)" json:"role"`                                    // 角色 1.管理员 2.普通用户
+	Mail         string `gorm:"type:varchar(50)" json:"mail"`                                // 邮箱
+	ReferralCode string `gorm:"type:varchar(10)" json:"referralCode"`                        // 推荐码
+	Token        string `gorm:"-" json:"token"`                                              // 登录成功时返回的会话token，不保存
+
+	ExpireAt           *time.Time `json:"expireAt"`           // 账号到期时间，为空永不过期
+	MustChangePassword bool       `json:"mustChangePassword"` // 下次登录后必须修改密码
+
+	AuthSource string `gorm:"type:varchar(20)" json:"authSource"` // 账号来源 空.本地账号 ldap.由LDAP创建或已关联LDAP
 
 	UserId uint `gorm:"-"  json:"userId"`
 }
 
+// 账号状态
+const (
+	USER_STATUS_ENABLE     = 1 // 启用
+	USER_STATUS_DISABLE    = 2 // 停用
+	USER_STATUS_NOT_ACTIVE = 3 // 未激活
+)
+
+// 账号来源
+const (
+	USER_AUTH_SOURCE_LDAP = "ldap"
+)
+
+// 账号是否已过期
+func (m *User) IsExpired() bool {
+	return m.ExpireAt != nil && !m.ExpireAt.After(time.Now())
+}
+
+// 账号是否可用：已启用且未过期
+func (m *User) IsActive() bool {
+	return m.Status == USER_STATUS_ENABLE && !m.IsExpired()
+}
+
+// 统计除指定账号外可用的管理员数量
+func (m *User) CountActiveAdmins(db *gorm.DB, exceptIds []uint) (int64, error) {
+	var count int64
+	query := db.Model(&User{}).Where("role=? AND status=? AND (expire_at IS NULL OR expire_at>?)", ROLE_ADMIN, USER_STATUS_ENABLE, time.Now())
+	if len(exceptIds) > 0 {
+		query = query.Where("id not in ?", exceptIds)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
+
 // 获取用户信息
 func (m *User) GetUserInfoByUid(uid uint) (User, error) {
 	mUser := User{}
@@ -28,10 +71,27 @@ func (m *User) GetUserInfoByUid(uid uint) (User, error) {
 }
 
 // 根据用户名和密码查询用户
-func (m *User) GetUserInfoByUsernameAndPassword(username, password string) (User, error) {
+// 密码不匹配时返回 gorm.ErrRecordNotFound，旧格式的密码哈希验证通过后会自动升级
+func (m *User) GetUserInfoByUsernameAndPassword(username, pwd string) (User, error) {
 	userInfo := User{}
-	err := Db.Where("username=?", username).Where("password=?", password).First(&userInfo).Error
-	return userInfo, err
+	if err := Db.Where("username=?", username).First(&userInfo).Error; err != nil {
+		return userInfo, err
+	}
+
+	ok, needRehash := password.Verify(pwd, userInfo.Password)
+	if !ok {
+		return User{}, gorm.ErrRecordNotFound
+	}
+
+	if needRehash {
+		if hash, err := password.Hash(pwd); err == nil {
+			if err := Db.Model(&User{}).Where("id=?", userInfo.ID).Update("password", hash).Error; err == nil {
+				userInfo.Password = hash
+			}
+		}
+	}
+
+	return userInfo, nil
 }
 
 // 根据用户名查询用户
@@ -41,6 +101,13 @@ func (m *User) GetUserInfoByUsername(username string) (User, error) {
 	return mUser, err
 }
 
+// 根据账号或邮箱查询用户，邮箱不区分大小写
+func (m *User) GetUserInfoByUsernameOrMail(account string) (User, error) {
+	mUser := User{}
+	err := Db.Where("username=? OR (mail<>'' AND LOWER(mail)=?)", account, strings.ToLower(account)).First(&mUser).Error
+	return mUser, err
+}
+
 // 根据邮箱查询用户
 func (m *User) GetUserInfoByMail() *User {
 	mUser := User{}
@@ -50,15 +117,8 @@ func (m *User) GetUserInfoByMail() *User {
 	return &mUser
 }
 
-// 根据token查询用户
-func (m *User) GetUserInfoByToken(userToken string) (User, error) {
-	mUser := User{}
-	err := Db.Where("token=?", userToken).First(&mUser).Error
-	return mUser, err
-}
-
 // 更新用户基于id
-// 支持：name,autograph,header_image,status,role,mail,token,password,username,gender
+// 支持：name,autograph,header_image,status,role,mail,password,username,gender
 func (m *User) UpdateUserInfoByUserId(user_id uint, updateInfo map[string]interface{}) error {
 	mUser := User{}
 
@@ -95,9 +155,6 @@ func (m *User) UpdateUserInfoByUserId(user_id uint, updateInfo map[string]interf
 		}
 		data["username"] = v
 	}
-	if v, ok := updateInfo["token"]; ok {
-		data["token"] = v
-	}
 	if v, ok := updateInfo["password"]; ok {
 		data["password"] = v
 	}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 操作类型
+const (
+	AUDIT_ACTION_CREATE = "create"
+	AUDIT_ACTION_UPDATE = "update"
+	AUDIT_ACTION_DELETE = "delete"
+
+	AUDIT_ACTION_IMPERSONATE = "impersonate" // 管理员以该用户身份查看
+)
+
+// 操作对象类型
+const (
+	AUDIT_TARGET_USER              = "user"
+	AUDIT_TARGET_PUBLIC_VISIT_USER = "public_visit_user"
+	AUDIT_TARGET_FILE              = "file"
+	AUDIT_TARGET_ITEM_ICON         = "item_icon"
+	AUDIT_TARGET_ITEM_ICON_GROUP   = "item_icon_group"
+	AUDIT_TARGET_ROLE              = "role"
+	AUDIT_TARGET_TEAM              = "team"
+	AUDIT_TARGET_TEAM_MEMBER       = "team_member"
+	AUDIT_TARGET_SYSTEM_SETTING    = "system_setting"
+	AUDIT_TARGET_PASSKEY           = "passkey"
+	AUDIT_TARGET_NETWORK_ZONE      = "network_zone"
+	AUDIT_TARGET_TAG               = "tag"
+	AUDIT_TARGET_TAG_VIEW          = "tag_view"
+)
+
+// 审计日志，只追加不修改，超过保留天数后删除
+type AuditLog struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `gorm:"index" json:"createTime"`
+	UserId     uint      `gorm:"index" json:"userId"`               // 操作人
+	Username   string    `gorm:"type:varchar(255)" json:"username"` // 操作人账号(用户删除后仍可查看)
+	Ip         string    `gorm:"type:varchar(64)" json:"ip"`        // 操作人IP
+	Action     string    `gorm:"type:varchar(50);index" json:"action"`
+	TargetType string    `gorm:"type:varchar(50);index:idx_target" json:"targetType"`
+	TargetId   string    `gorm:"type:varchar(100);index:idx_target" json:"targetId"`
+	Before     string    `gorm:"type:text" json:"before"` // 修改前的字段(json)，只记录有变化的字段
+	After      string    `gorm:"type:text" json:"after"`  // 修改后的字段(json)
+}
+
+// 删除早于指定时间的日志
+func (m *AuditLog) DeleteBefore(db *gorm.DB, t time.Time) error {
+	return db.Delete(&AuditLog{}, "created_at<?", t).Error
+}
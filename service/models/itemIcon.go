@@ -17,8 +17,22 @@ type ItemIcon struct {
 	OpenMethod      int                       `gorm:"type:tinyint(1)" json:"openMethod"`
 	Sort            int                       `gorm:"type:int(11)" json:"sort"`
 	ItemIconGroupId int                       `json:"itemIconGroupId"`
+	Visibility      string                    `gorm:"type:varchar(10);default:public" json:"visibility"` // 可见性 public/login/private，分组不可见时图标也不可见
 	UserId          uint                      `json:"userId"`
 	User            User                      `json:"user"`
+
+	// 健康检测，定时检测Url和LanUrl
+	HealthCheckType           string          `gorm:"type:varchar(10)" json:"healthCheckType"`     // http/tcp/dns，为空不检测
+	HealthCheckInterval       int             `json:"healthCheckInterval"`                         // 检测间隔(秒)
+	HealthCheckTimeout        int             `json:"healthCheckTimeout"`                          // 超时时间(秒)
+	HealthCheckExpectedStatus int             `json:"healthCheckExpectedStatus"`                   // 期望的HTTP状态码，0为200-399
+	HealthCheckKeyword        string          `gorm:"type:varchar(255)" json:"healthCheckKeyword"` // 页面需要包含的关键字，为空不检测
+	Health                    *ItemIconHealth `gorm:"-" json:"health,omitempty"`                   // 健康状态，仅列表返回
+
+	ZoneUrls    []ItemIconZoneUrl `gorm:"-" json:"zoneUrls"`    // 各网络区域使用的地址
+	ResolvedUrl string            `gorm:"-" json:"resolvedUrl"` // 根据访问者所在网络区域选择的地址，仅列表返回
+
+	TagIds []uint `gorm:"-" json:"tagIds"` // 当前用户添加的标签
 }
 
 func (m *ItemIcon) DeleteByItemIconGroupIds(db *gorm.DB, userId uint, itemIconGroupIds []uint) (err error) {
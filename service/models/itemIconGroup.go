@@ -12,9 +12,31 @@ type ItemIconGroup struct {
 	Sort        int    `gorm:"type:int(11)" json:"sort"`
 	UserId      uint   `json:"userId"`
 	User        User   `json:"user"`
+	TeamId      uint   `gorm:"index;default:0" json:"teamId"`                     // 所属团队，0为个人分组
+	Visibility  string `gorm:"type:varchar(10);default:public" json:"visibility"` // 可见性 public/login/private
+
+	// 分组归属，仅用于返回列表
+	Ownership string `gorm:"-" json:"ownership"` // self.自己的 team.团队的
+	TeamName  string `gorm:"-" json:"teamName"`
+	Editable  bool   `gorm:"-" json:"editable"`
 }
 
+// 分组和图标的可见性
+const (
+	VISIBILITY_PUBLIC  = "public"  // 所有人，包括公开模式的访客
+	VISIBILITY_LOGIN   = "login"   // 登录的用户，公开账号的登录可见分组共享给所有登录用户
+	VISIBILITY_PRIVATE = "private" // 可以编辑所在分组的用户
+)
+
+// 分组归属
+const (
+	ITEM_ICON_GROUP_OWNERSHIP_SELF   = "self"
+	ITEM_ICON_GROUP_OWNERSHIP_TEAM   = "team"
+	ITEM_ICON_GROUP_OWNERSHIP_PUBLIC = "public" // 公开账号共享的，只读
+)
+
+// 删除用户的个人分组，团队分组不随创建者删除
 func (m *ItemIconGroup) DeleteByUserId(db *gorm.DB, userId uint) (err error) {
-	err = db.Delete(&ItemIconGroup{}, "user_id = ?", userId).Error
+	err = db.Delete(&ItemIconGroup{}, "user_id = ? AND team_id=0", userId).Error
 	return
 }
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 健康检测方式，为空不检测
+const (
+	HEALTH_CHECK_TYPE_HTTP = "http" // 检测HTTP状态码，可同时检测页面关键字
+	HEALTH_CHECK_TYPE_TCP  = "tcp"  // 检测端口是否可以连接
+	HEALTH_CHECK_TYPE_DNS  = "dns"  // 检测域名是否可以解析
+)
+
+// 检测的地址
+const (
+	HEALTH_CHECK_TARGET_URL     = "url"
+	HEALTH_CHECK_TARGET_LAN_URL = "lanUrl"
+)
+
+const HEALTH_CHECK_HISTORY_COUNT = 30 // 列表中返回每个地址最近的检测记录数量
+
+// 图标地址的健康检测记录，只追加不修改，超过保留时间后删除
+type ItemIconHealthCheck struct {
+	ID           uint      `gorm:"primarykey" json:"-"`
+	CreatedAt    time.Time `gorm:"index" json:"checkedAt"`
+	ItemIconId   uint      `gorm:"index:idx_item_target" json:"-"`
+	Target       string    `gorm:"type:varchar(10);index:idx_item_target" json:"-"`
+	Up           bool      `json:"up"`
+	StatusCode   int       `json:"statusCode"`                       // HTTP状态码，其他方式为0
+	ResponseTime int       `json:"responseTime"`                     // 响应时间(毫秒)
+	Message      string    `gorm:"type:varchar(255)" json:"message"` // 失败原因
+}
+
+// 图标的健康状态，未检测的地址为nil
+type ItemIconHealth struct {
+	Url    *ItemIconHealthStatus `json:"url"`
+	LanUrl *ItemIconHealthStatus `json:"lanUrl"`
+}
+
+// 地址最近一次的检测结果和最近的记录
+type ItemIconHealthStatus struct {
+	ItemIconHealthCheck
+	History []ItemIconHealthCheck `json:"history"` // 按检测时间正序
+}
+
+// 删除早于指定时间的记录
+func (m *ItemIconHealthCheck) DeleteBefore(db *gorm.DB, t time.Time) error {
+	return db.Delete(&ItemIconHealthCheck{}, "created_at<?", t).Error
+}
+
+// 删除图标的检测记录
+func (m *ItemIconHealthCheck) DeleteByItemIconIds(db *gorm.DB, itemIconIds []uint) error {
+	return db.Delete(&ItemIconHealthCheck{}, "item_icon_id in ?", itemIconIds).Error
+}
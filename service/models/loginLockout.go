@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 锁定类型
+const (
+	LOGIN_LOCKOUT_KIND_USERNAME = "username"
+	LOGIN_LOCKOUT_KIND_IP       = "ip"
+)
+
+// 登录失败次数过多被临时锁定的账号或IP
+type LoginLockout struct {
+	BaseModel
+	Kind        string    `gorm:"type:varchar(10);index:idx_kind_value" json:"kind"`   // 类型 username | ip
+	Value       string    `gorm:"type:varchar(100);index:idx_kind_value" json:"value"` // 账号或IP
+	Failures    int       `json:"failures"`                                            // 锁定时的失败次数
+	LastIp      string    `gorm:"type:varchar(64)" json:"lastIp"`                      // 最后一次失败的IP
+	LockedUntil time.Time `gorm:"index" json:"lockedUntil"`
+}
+
+// 获取仍在锁定中的记录，返回锁定最久的一条
+func (m *LoginLockout) GetActive(db *gorm.DB, kind, value string) (LoginLockout, bool, error) {
+	list := []LoginLockout{}
+	err := db.Order("locked_until desc").Limit(1).Find(&list, "kind=? AND value=? AND locked_until>?", kind, value, time.Now()).Error
+	if err != nil || len(list) == 0 {
+		return LoginLockout{}, false, err
+	}
+	return list[0], true, nil
+}
+
+// 锁定中的列表
+func (m *LoginLockout) GetActiveList(db *gorm.DB) ([]LoginLockout, error) {
+	list := []LoginLockout{}
+	err := db.Order("locked_until desc").Find(&list, "locked_until>?", time.Now()).Error
+	return list, err
+}
+
+// 添加锁定，同时清理已过期的记录
+func (m *LoginLockout) Lock(db *gorm.DB, kind, value, lastIp string, failures int, duration time.Duration) (LoginLockout, error) {
+	if err := db.Unscoped().Delete(&LoginLockout{}, "locked_until<=?", time.Now()).Error; err != nil {
+		return LoginLockout{}, err
+	}
+	info := LoginLockout{
+		Kind:        kind,
+		Value:       value,
+		Failures:    failures,
+		LastIp:      lastIp,
+		LockedUntil: time.Now().Add(duration),
+	}
+	err := db.Create(&info).Error
+	return info, err
+}
+
+// 解除锁定，返回被解除的记录
+func (m *LoginLockout) DeleteByIds(db *gorm.DB, ids []uint) ([]LoginLockout, error) {
+	list := []LoginLockout{}
+	if err := db.Find(&list, "id in ?", ids).Error; err != nil {
+		return nil, err
+	}
+	err := db.Unscoped().Delete(&LoginLockout{}, "id in ?", ids).Error
+	return list, err
+}
@@ -0,0 +1,64 @@
+package models
+
+import "gorm.io/gorm"
+
+// 网络区域，根据访问者IP所在的网段选择图标的地址
+type NetworkZone struct {
+	BaseModel
+	Name     string `gorm:"type:varchar(50)" json:"name"`
+	Cidrs    string `gorm:"type:varchar(2000)" json:"cidrs"` // IP或CIDR，逗号分隔
+	Internal bool   `json:"internal"`                        // 内部网络，该区域的地址不会发送给公开模式下的外部访问者
+	Sort     int    `json:"sort"`                            // 按顺序匹配，使用第一个包含访问者IP的区域
+}
+
+// 图标在网络区域中使用的地址
+type ItemIconZoneUrl struct {
+	ID            uint   `gorm:"primarykey" json:"-"`
+	ItemIconId    uint   `gorm:"uniqueIndex:idx_item_zone" json:"-"`
+	NetworkZoneId uint   `gorm:"uniqueIndex:idx_item_zone;index" json:"networkZoneId"`
+	Url           string `gorm:"type:varchar(1000)" json:"url"`
+}
+
+func (m *NetworkZone) GetList(db *gorm.DB) ([]NetworkZone, error) {
+	list := []NetworkZone{}
+	err := db.Order("sort, id").Find(&list).Error
+	return list, err
+}
+
+func (m *NetworkZone) DeleteByIds(db *gorm.DB, ids []uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&NetworkZone{}, "id in ?", ids).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&ItemIconZoneUrl{}, "network_zone_id in ?", ids).Error
+	})
+}
+
+// 获取图标的区域地址
+func (m *ItemIconZoneUrl) GetByItemIconIds(db *gorm.DB, itemIconIds []uint) ([]ItemIconZoneUrl, error) {
+	list := []ItemIconZoneUrl{}
+	err := db.Order("id").Find(&list, "item_icon_id in ?", itemIconIds).Error
+	return list, err
+}
+
+// 替换图标的区域地址，地址为空的不保存
+func (m *ItemIconZoneUrl) Save(db *gorm.DB, itemIconId uint, list []ItemIconZoneUrl) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&ItemIconZoneUrl{}, "item_icon_id=?", itemIconId).Error; err != nil {
+			return err
+		}
+		for _, v := range list {
+			if v.Url == "" {
+				continue
+			}
+			if err := tx.Create(&ItemIconZoneUrl{ItemIconId: itemIconId, NetworkZoneId: v.NetworkZoneId, Url: v.Url}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *ItemIconZoneUrl) DeleteByItemIconIds(db *gorm.DB, itemIconIds []uint) error {
+	return db.Delete(&ItemIconZoneUrl{}, "item_icon_id in ?", itemIconIds).Error
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 权限
+const (
+	PERMISSION_MANAGE_USERS   = "manage_users"   // 管理用户和角色
+	PERMISSION_MANAGE_SYSTEM  = "manage_system"  // 管理系统设置
+	PERMISSION_UPLOAD_FILES   = "upload_files"   // 上传和管理文件
+	PERMISSION_EDIT_PANEL     = "edit_panel"     // 编辑自己的面板(分组、图标、配置)
+	PERMISSION_VIEW_MONITOR   = "view_monitor"   // 查看系统状态监控
+	PERMISSION_MANAGE_NOTICES = "manage_notices" // 管理公告
+	PERMISSION_HEALTH_CHECK   = "health_check"   // 开启图标的健康检测(服务端会访问图标地址)
+)
+
+var Permissions = []string{
+	PERMISSION_MANAGE_USERS,
+	PERMISSION_MANAGE_SYSTEM,
+	PERMISSION_UPLOAD_FILES,
+	PERMISSION_EDIT_PANEL,
+	PERMISSION_VIEW_MONITOR,
+	PERMISSION_MANAGE_NOTICES,
+	PERMISSION_HEALTH_CHECK,
+}
+
+// 管理类权限，拥有任意一个视为管理员(强制二次验证、admin范围的访问令牌)
+var AdminPermissions = []string{
+	PERMISSION_MANAGE_USERS,
+	PERMISSION_MANAGE_SYSTEM,
+}
+
+// 内置角色ID，与旧版本User.Role的值一致
+const (
+	ROLE_ADMIN        = 1 // 管理员
+	ROLE_USER         = 2 // 普通用户
+	ROLE_PANEL_EDITOR = 3 // 面板编辑：可管理内容，不能管理用户和系统
+)
+
+// 角色
+type Role struct {
+	BaseModel
+	Name        string `gorm:"type:varchar(50)" json:"name"`
+	Description string `gorm:"type:varchar(255)" json:"description"`
+	Permissions string `gorm:"type:varchar(500)" json:"-"` // 权限，逗号分隔
+	BuiltIn     bool   `json:"builtIn"`                    // 内置角色不能删除
+}
+
+// 内置角色
+func BuiltInRoles() []Role {
+	return []Role{
+		{
+			BaseModel:   BaseModel{ID: ROLE_ADMIN},
+			Name:        "Administrator",
+			Description: "All permissions",
+			Permissions: strings.Join(Permissions, ","),
+			BuiltIn:     true,
+		},
+		{
+			BaseModel:   BaseModel{ID: ROLE_USER},
+			Name:        "User",
+			Description: "Edit own panel, upload files and view monitor",
+			Permissions: strings.Join([]string{PERMISSION_UPLOAD_FILES, PERMISSION_EDIT_PANEL, PERMISSION_VIEW_MONITOR}, ","),
+			BuiltIn:     true,
+		},
+		{
+			BaseModel:   BaseModel{ID: ROLE_PANEL_EDITOR},
+			Name:        "Panel editor",
+			Description: "Manage content and notices, but not users or system settings",
+			Permissions: strings.Join([]string{PERMISSION_UPLOAD_FILES, PERMISSION_EDIT_PANEL, PERMISSION_VIEW_MONITOR, PERMISSION_MANAGE_NOTICES}, ","),
+			BuiltIn:     true,
+		},
+	}
+}
+
+// 创建缺少的内置角色
+func (m *Role) CreateBuiltIn(db *gorm.DB) error {
+	for _, v := range BuiltInRoles() {
+		var count int64
+		if err := db.Unscoped().Model(&Role{}).Where("id=?", v.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := db.Create(&v).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 权限列表
+func (m *Role) PermissionList() []string {
+	if m.Permissions == "" {
+		return []string{}
+	}
+	return strings.Split(m.Permissions, ",")
+}
+
+// 是否拥有某个权限，管理员角色拥有全部权限
+func (m *Role) HasPermission(permission string) bool {
+	if m.ID == ROLE_ADMIN {
+		return true
+	}
+	for _, v := range m.PermissionList() {
+		if v == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// 是否拥有任意一个管理类权限
+func (m *Role) IsAdmin() bool {
+	for _, v := range AdminPermissions {
+		if m.HasPermission(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Role) GetById(db *gorm.DB, id int) (Role, error) {
+	info := Role{}
+	err := db.First(&info, "id=?", id).Error
+	return info, err
+}
+
+func (m *Role) GetList(db *gorm.DB) ([]Role, error) {
+	list := []Role{}
+	err := db.Order("id").Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// 标签，每个用户单独管理，一个图标可以有多个标签
+type Tag struct {
+	BaseModel
+	UserId uint   `gorm:"index" json:"userId"`
+	Name   string `gorm:"type:varchar(50)" json:"name"`
+	Color  string `gorm:"type:varchar(20)" json:"color"` // #rrggbb
+	Sort   int    `json:"sort"`
+}
+
+// 图标的标签
+type ItemIconTag struct {
+	ID         uint `gorm:"primarykey" json:"-"`
+	ItemIconId uint `gorm:"uniqueIndex:idx_item_tag" json:"itemIconId"`
+	TagId      uint `gorm:"uniqueIndex:idx_item_tag;index" json:"tagId"`
+}
+
+// 标签视图，由包含指定标签的图标组成的虚拟分组，图标可以来自不同的分组
+type TagView struct {
+	BaseModel
+	UserId     uint   `gorm:"index" json:"userId"`
+	Icon       string `json:"icon"`
+	Title      string `gorm:"type:varchar(50)" json:"title"`
+	TagIdsJson string `gorm:"type:varchar(1000)" json:"-"`
+	TagIds     []uint `gorm:"-" json:"tagIds"`
+	MatchAll   bool   `json:"matchAll"` // 需要包含全部标签，否则包含任一标签即可
+	Sort       int    `json:"sort"`
+}
+
+func (m *Tag) GetListByUserId(db *gorm.DB, userId uint) ([]Tag, error) {
+	list := []Tag{}
+	err := db.Order("sort, id").Find(&list, "user_id=?", userId).Error
+	return list, err
+}
+
+// 删除标签及图标上的该标签
+func (m *Tag) DeleteByIds(db *gorm.DB, userId uint, ids []uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		userTagIds := []uint{}
+		if err := tx.Model(&Tag{}).Where("id in ? AND user_id=?", ids, userId).Pluck("id", &userTagIds).Error; err != nil || len(userTagIds) == 0 {
+			return err
+		}
+		if err := tx.Delete(&Tag{}, "id in ?", userTagIds).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&ItemIconTag{}, "tag_id in ?", userTagIds).Error
+	})
+}
+
+// 删除用户的全部标签和标签视图
+func (m *Tag) DeleteByUserId(db *gorm.DB, userId uint) error {
+	tagIds := db.Model(&Tag{}).Select("id").Where("user_id=?", userId)
+	if err := db.Delete(&ItemIconTag{}, "tag_id in (?)", tagIds).Error; err != nil {
+		return err
+	}
+	if err := db.Delete(&TagView{}, "user_id=?", userId).Error; err != nil {
+		return err
+	}
+	return db.Delete(&Tag{}, "user_id=?", userId).Error
+}
+
+// 获取图标上属于该用户的标签ID，图标ID:标签ID
+func (m *ItemIconTag) GetTagIdsByItemIconIds(db *gorm.DB, userId uint, itemIconIds []uint) (map[uint][]uint, error) {
+	list := []ItemIconTag{}
+	tagIds := db.Model(&Tag{}).Select("id").Where("user_id=?", userId)
+	if err := db.Order("id").Find(&list, "item_icon_id in ? AND tag_id in (?)", itemIconIds, tagIds).Error; err != nil {
+		return nil, err
+	}
+	res := map[uint][]uint{}
+	for _, v := range list {
+		res[v.ItemIconId] = append(res[v.ItemIconId], v.TagId)
+	}
+	return res, nil
+}
+
+// 替换图标上属于该用户的标签，其他用户(团队成员)的标签不受影响
+func (m *ItemIconTag) Save(db *gorm.DB, userId uint, itemIconId uint, tagIds []uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		userTagIds := tx.Model(&Tag{}).Select("id").Where("user_id=?", userId)
+		if err := tx.Delete(&ItemIconTag{}, "item_icon_id=? AND tag_id in (?)", itemIconId, userTagIds).Error; err != nil {
+			return err
+		}
+		for _, v := range tagIds {
+			if err := tx.Create(&ItemIconTag{ItemIconId: itemIconId, TagId: v}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 包含标签的图标ID，matchAll为true时需要包含全部标签
+func (m *ItemIconTag) GetItemIconIds(db *gorm.DB, tagIds []uint, matchAll bool) ([]uint, error) {
+	ids := []uint{}
+	if len(tagIds) == 0 {
+		return ids, nil
+	}
+	query := db.Model(&ItemIconTag{}).Where("tag_id in ?", tagIds).Group("item_icon_id")
+	if matchAll {
+		query = query.Having("COUNT(DISTINCT tag_id)=?", len(tagIds))
+	}
+	err := query.Pluck("item_icon_id", &ids).Error
+	return ids, err
+}
+
+func (m *ItemIconTag) DeleteByItemIconIds(db *gorm.DB, itemIconIds []uint) error {
+	return db.Delete(&ItemIconTag{}, "item_icon_id in ?", itemIconIds).Error
+}
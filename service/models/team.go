@@ -0,0 +1,70 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// 团队成员角色
+const (
+	TEAM_MEMBER_ROLE_VIEWER = 1 // 查看者：只能查看团队分组
+	TEAM_MEMBER_ROLE_EDITOR = 2 // 编辑者：可以维护团队分组及其中的图标
+)
+
+// 团队，团队的分组会出现在每个成员的面板中
+type Team struct {
+	BaseModel
+	Name        string `gorm:"type:varchar(50)" json:"name"`
+	Description string `gorm:"type:varchar(255)" json:"description"`
+}
+
+// 团队成员
+type TeamMember struct {
+	BaseModel
+	TeamId uint `gorm:"index" json:"teamId"`
+	UserId uint `gorm:"index" json:"userId"`
+	Role   int  `gorm:"type:tinyint(1)" json:"role"` // 1.查看者 2.编辑者
+}
+
+func (m *Team) GetById(db *gorm.DB, id uint) (Team, error) {
+	info := Team{}
+	err := db.First(&info, "id=?", id).Error
+	return info, err
+}
+
+// 删除团队、成员及团队的分组和图标
+func (m *Team) DeleteByIds(db *gorm.DB, ids []uint) error {
+	groupIds := db.Model(&ItemIconGroup{}).Select("id").Where("team_id in ?", ids)
+	if err := db.Delete(&ItemIcon{}, "item_icon_group_id in (?)", groupIds).Error; err != nil {
+		return err
+	}
+	if err := db.Delete(&ItemIconGroup{}, "team_id in ?", ids).Error; err != nil {
+		return err
+	}
+	if err := db.Unscoped().Delete(&TeamMember{}, "team_id in ?", ids).Error; err != nil {
+		return err
+	}
+	return db.Delete(&Team{}, "id in ?", ids).Error
+}
+
+// 用户加入的团队，返回团队ID和成员角色
+func (m *TeamMember) GetRolesByUserId(db *gorm.DB, userId uint) (map[uint]int, error) {
+	list := []TeamMember{}
+	if err := db.Find(&list, "user_id=?", userId).Error; err != nil {
+		return nil, err
+	}
+	roles := map[uint]int{}
+	for _, v := range list {
+		roles[v.TeamId] = v.Role
+	}
+	return roles, nil
+}
+
+func (m *TeamMember) GetListByTeamId(db *gorm.DB, teamId uint) ([]TeamMember, error) {
+	list := []TeamMember{}
+	err := db.Order("id").Find(&list, "team_id=?", teamId).Error
+	return list, err
+}
+
+func (m *TeamMember) DeleteByUserId(db *gorm.DB, userId uint) error {
+	return db.Unscoped().Delete(&TeamMember{}, "user_id=?", userId).Error
+}
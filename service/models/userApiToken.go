@@ -0,0 +1,138 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 个人访问令牌的权限范围
+const (
+	API_TOKEN_SCOPE_READ_PANEL   = "read_panel"   // 读取面板(分组、图标、配置)
+	API_TOKEN_SCOPE_WRITE_ITEMS  = "write_items"  // 添加、修改、删除图标和分组
+	API_TOKEN_SCOPE_UPLOAD_FILES = "upload_files" // 上传和管理文件
+	API_TOKEN_SCOPE_ADMIN        = "admin"        // 管理员接口(需要账号本身是管理员)
+)
+
+const API_TOKEN_PREFIX = "sp_" // 令牌前缀，便于识别和密钥扫描
+
+var ApiTokenScopes = []string{
+	API_TOKEN_SCOPE_READ_PANEL,
+	API_TOKEN_SCOPE_WRITE_ITEMS,
+	API_TOKEN_SCOPE_UPLOAD_FILES,
+	API_TOKEN_SCOPE_ADMIN,
+}
+
+var (
+	ErrApiTokenInvalid = errors.New("invalid api token")
+	ErrApiTokenExpired = errors.New("api token has expired")
+)
+
+// 个人访问令牌，用于脚本和CI调用接口，只保存令牌的哈希
+type UserApiToken struct {
+	BaseModel
+	UserId      uint       `gorm:"index" json:"userId"`
+	Name        string     `gorm:"type:varchar(50)" json:"name"`
+	TokenHash   string     `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	TokenPrefix string     `gorm:"type:varchar(20)" json:"tokenPrefix"` // 令牌开头部分，用于列表中识别
+	Scopes      string     `gorm:"type:varchar(255)" json:"-"`          // 权限范围，逗号分隔
+	ExpiresAt   *time.Time `json:"expiresAt"`                           // 过期时间，为空永不过期
+	LastUsedAt  *time.Time `json:"lastUsedAt"`
+	LastUsedIp  string     `gorm:"type:varchar(64)" json:"lastUsedIp"`
+}
+
+// 权限范围列表
+func (m *UserApiToken) ScopeList() []string {
+	if m.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(m.Scopes, ",")
+}
+
+// 是否拥有某个权限范围
+func (m *UserApiToken) HasScope(scope string) bool {
+	for _, v := range m.ScopeList() {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// 创建令牌，返回明文令牌(仅此一次)
+func (m *UserApiToken) Create(db *gorm.DB, userId uint, name string, scopes []string, expiresAt *time.Time) (UserApiToken, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return UserApiToken{}, "", err
+	}
+	token := API_TOKEN_PREFIX + base64.RawURLEncoding.EncodeToString(buf)
+
+	info := UserApiToken{
+		UserId:      userId,
+		Name:        name,
+		TokenHash:   HashToken(token),
+		TokenPrefix: token[:len(API_TOKEN_PREFIX)+6],
+		Scopes:      strings.Join(scopes, ","),
+		ExpiresAt:   expiresAt,
+	}
+	err := db.Create(&info).Error
+	return info, token, err
+}
+
+// 根据明文令牌获取令牌信息，不存在或已过期返回错误
+func (m *UserApiToken) GetByToken(db *gorm.DB, token string) (UserApiToken, error) {
+	info := UserApiToken{}
+	if !strings.HasPrefix(token, API_TOKEN_PREFIX) {
+		return info, ErrApiTokenInvalid
+	}
+	if err := db.First(&info, "token_hash=?", HashToken(token)).Error; err == gorm.ErrRecordNotFound {
+		return info, ErrApiTokenInvalid
+	} else if err != nil {
+		return info, err
+	}
+	if info.ExpiresAt != nil && info.ExpiresAt.Before(time.Now()) {
+		return info, ErrApiTokenExpired
+	}
+	return info, nil
+}
+
+// 记录最后使用时间，一分钟内只更新一次
+func (m *UserApiToken) UpdateLastUsed(db *gorm.DB, ip string) error {
+	now := time.Now()
+	if m.LastUsedAt != nil && now.Sub(*m.LastUsedAt) < time.Minute && m.LastUsedIp == ip {
+		return nil
+	}
+	return db.Model(&UserApiToken{}).Where("id=?", m.ID).Updates(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": ip,
+	}).Error
+}
+
+// 获取用户的令牌列表
+func (m *UserApiToken) GetListByUserId(db *gorm.DB, userId uint) ([]UserApiToken, error) {
+	list := []UserApiToken{}
+	err := db.Order("id desc").Find(&list, "user_id=?", userId).Error
+	return list, err
+}
+
+// 删除用户的令牌
+func (m *UserApiToken) DeleteByIds(db *gorm.DB, userId uint, ids []uint) error {
+	return db.Unscoped().Delete(&UserApiToken{}, "user_id=? AND id in ?", userId, ids).Error
+}
+
+// 删除用户的全部令牌
+func (m *UserApiToken) DeleteByUserIds(db *gorm.DB, userIds []uint) error {
+	return db.Unscoped().Delete(&UserApiToken{}, "user_id in ?", userIds).Error
+}
+
+// 令牌(访问令牌、会话token)的哈希，令牌随机性足够，无需慢哈希
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
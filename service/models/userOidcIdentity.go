@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 用户绑定的OIDC身份，按签发者和sub匹配用户(用户名等claim可以被修改，不能用于匹配)
+type UserOidcIdentity struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"createTime"`
+	UserId    uint      `gorm:"index" json:"userId"`
+	Issuer    string    `gorm:"type:varchar(255);uniqueIndex:idx_issuer_subject" json:"issuer"`
+	Subject   string    `gorm:"type:varchar(255);uniqueIndex:idx_issuer_subject" json:"subject"`
+}
+
+// 根据签发者和sub获取绑定，不存在返回 gorm.ErrRecordNotFound
+func (m *UserOidcIdentity) Get(db *gorm.DB, issuer, subject string) (UserOidcIdentity, error) {
+	info := UserOidcIdentity{}
+	err := db.First(&info, "issuer=? AND subject=?", issuer, subject).Error
+	return info, err
+}
+
+func (m *UserOidcIdentity) DeleteByUserId(db *gorm.DB, userId uint) error {
+	return db.Delete(&UserOidcIdentity{}, "user_id=?", userId).Error
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 通行密钥(WebAuthn凭据)，一个用户可以注册多个
+type UserPasskey struct {
+	BaseModel
+	UserId          uint       `gorm:"index" json:"userId"`
+	Name            string     `gorm:"type:varchar(50)" json:"name"`
+	CredentialId    string     `gorm:"type:varchar(255);uniqueIndex" json:"-"` // 凭据ID，base64url
+	PublicKey       string     `gorm:"type:text" json:"-"`                     // COSE格式的公钥，base64url
+	AttestationType string     `gorm:"type:varchar(50)" json:"-"`
+	Transports      string     `gorm:"type:varchar(100)" json:"-"` // 传输方式，逗号分隔
+	Aaguid          string     `gorm:"type:varchar(64)" json:"-"`  // 认证器型号
+	SignCount       uint32     `json:"-"`                          // 签名计数器，用于发现克隆的认证器
+	BackupEligible  bool       `json:"backupEligible"`             // 是否可以同步到其他设备
+	BackupState     bool       `json:"backupState"`                // 是否已同步
+	LastUsedAt      *time.Time `json:"lastUsedAt"`
+}
+
+// 获取用户的通行密钥列表
+func (m *UserPasskey) GetListByUserId(db *gorm.DB, userId uint) ([]UserPasskey, error) {
+	list := []UserPasskey{}
+	err := db.Order("id").Find(&list, "user_id=?", userId).Error
+	return list, err
+}
+
+// 根据凭据ID获取通行密钥，不存在返回 gorm.ErrRecordNotFound
+func (m *UserPasskey) GetByCredentialId(db *gorm.DB, credentialId string) (UserPasskey, error) {
+	info := UserPasskey{}
+	err := db.First(&info, "credential_id=?", credentialId).Error
+	return info, err
+}
+
+// 登录成功后更新签名计数器和最后使用时间
+func (m *UserPasskey) UpdateAfterLogin(db *gorm.DB, signCount uint32, backupState bool) error {
+	return db.Model(&UserPasskey{}).Where("id=?", m.ID).Updates(map[string]interface{}{
+		"sign_count":   signCount,
+		"backup_state": backupState,
+		"last_used_at": time.Now(),
+	}).Error
+}
+
+// 修改名称
+func (m *UserPasskey) Rename(db *gorm.DB, userId, id uint, name string) (int64, error) {
+	res := db.Model(&UserPasskey{}).Where("user_id=? AND id=?", userId, id).Update("name", name)
+	return res.RowsAffected, res.Error
+}
+
+// 删除用户的通行密钥
+func (m *UserPasskey) DeleteByIds(db *gorm.DB, userId uint, ids []uint) error {
+	return db.Unscoped().Delete(&UserPasskey{}, "user_id=? AND id in ?", userId, ids).Error
+}
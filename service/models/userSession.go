@@ -0,0 +1,163 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const SESSION_IDLE_TIMEOUT = 72 * time.Hour // 会话超过该时间未使用自动失效
+
+var ErrSessionInvalid = errors.New("session does not exist or has expired")
+
+// 登录会话，只保存token的哈希
+type UserSession struct {
+	BaseModel
+	UserId     uint      `gorm:"index" json:"userId"`
+	TokenHash  string    `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	Device     string    `gorm:"type:varchar(100)" json:"device"` // 设备，根据UA解析
+	Ip         string    `gorm:"type:varchar(64)" json:"ip"`      // 最后访问的IP
+	UserAgent  string    `gorm:"type:varchar(255)" json:"userAgent"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+
+	// 管理员以该用户身份查看(模拟登录)时的管理员ID，这类会话只读且有固定的过期时间
+	ImpersonatedBy uint       `gorm:"default:0" json:"impersonatedBy"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+}
+
+// 创建会话，返回明文token
+func (m *UserSession) Create(db *gorm.DB, userId uint, device, ip, userAgent string) (UserSession, string, error) {
+	return m.create(db, UserSession{
+		UserId:    userId,
+		Device:    device,
+		Ip:        ip,
+		UserAgent: userAgent,
+	})
+}
+
+// 创建模拟登录会话，返回明文token
+func (m *UserSession) CreateImpersonation(db *gorm.DB, userId, adminId uint, expiresAt time.Time, device, ip, userAgent string) (UserSession, string, error) {
+	return m.create(db, UserSession{
+		UserId:         userId,
+		Device:         device,
+		Ip:             ip,
+		UserAgent:      userAgent,
+		ImpersonatedBy: adminId,
+		ExpiresAt:      &expiresAt,
+	})
+}
+
+func (m *UserSession) create(db *gorm.DB, info UserSession) (UserSession, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return UserSession{}, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	if len(info.UserAgent) > 255 {
+		info.UserAgent = info.UserAgent[:255]
+	}
+	info.TokenHash = HashToken(token)
+	info.LastSeenAt = time.Now()
+	err := db.Create(&info).Error
+	return info, token, err
+}
+
+// 根据token的哈希获取会话
+func (m *UserSession) GetByTokenHash(db *gorm.DB, tokenHash string) (UserSession, error) {
+	info := UserSession{}
+	err := db.First(&info, "token_hash=?", tokenHash).Error
+	if err == gorm.ErrRecordNotFound || (err == nil && info.IsExpired()) {
+		return info, ErrSessionInvalid
+	}
+	return info, err
+}
+
+// 是否已过期
+func (m *UserSession) IsExpired() bool {
+	if m.ExpiresAt != nil && time.Now().After(*m.ExpiresAt) {
+		return true
+	}
+	return time.Since(m.LastSeenAt) > SESSION_IDLE_TIMEOUT
+}
+
+// 是否为模拟登录会话
+func (m *UserSession) IsImpersonation() bool {
+	return m.ImpersonatedBy != 0
+}
+
+// 更新最后访问时间和IP，一分钟内只更新一次，返回是否有更新
+// 会话已被删除(如在命令行中吊销)时返回 ErrSessionInvalid
+func (m *UserSession) Touch(db *gorm.DB, ip string) (bool, error) {
+	now := time.Now()
+	if now.Sub(m.LastSeenAt) < time.Minute && m.Ip == ip {
+		return false, nil
+	}
+	m.LastSeenAt = now
+	m.Ip = ip
+	res := db.Model(&UserSession{}).Where("id=?", m.ID).Updates(map[string]interface{}{
+		"last_seen_at": now,
+		"ip":           ip,
+	})
+	if res.Error == nil && res.RowsAffected == 0 {
+		return false, ErrSessionInvalid
+	}
+	return true, res.Error
+}
+
+// 获取用户未过期的会话列表
+func (m *UserSession) GetListByUserId(db *gorm.DB, userId uint) ([]UserSession, error) {
+	list := []UserSession{}
+	now := time.Now()
+	err := db.Order("last_seen_at desc").
+		Where("expires_at IS NULL OR expires_at>?", now).
+		Find(&list, "user_id=? AND last_seen_at>?", userId, now.Add(-SESSION_IDLE_TIMEOUT)).Error
+	return list, err
+}
+
+// 获取会话所属的用户ID
+func (m *UserSession) GetUserIdsByIds(db *gorm.DB, ids []uint) ([]uint, error) {
+	userIds := []uint{}
+	err := db.Model(&UserSession{}).Where("id in ?", ids).Distinct().Pluck("user_id", &userIds).Error
+	return userIds, err
+}
+
+// 删除会话，返回被删除会话的token哈希，用于清除缓存
+// userId为0时不限制用户
+func (m *UserSession) DeleteByIds(db *gorm.DB, userId uint, ids []uint) ([]string, error) {
+	query := db.Where("id in ?", ids)
+	if userId != 0 {
+		query = query.Where("user_id=?", userId)
+	}
+	return deleteSessions(db, query)
+}
+
+// 删除用户的全部会话，exceptId不为0时保留该会话
+func (m *UserSession) DeleteByUserIds(db *gorm.DB, userIds []uint, exceptId uint) ([]string, error) {
+	query := db.Where("user_id in ?", userIds)
+	if exceptId != 0 {
+		query = query.Where("id<>?", exceptId)
+	}
+	return deleteSessions(db, query)
+}
+
+// 清理过期的会话
+func (m *UserSession) DeleteExpired(db *gorm.DB) error {
+	now := time.Now()
+	return db.Unscoped().Delete(&UserSession{}, "last_seen_at<? OR expires_at<?", now.Add(-SESSION_IDLE_TIMEOUT), now).Error
+}
+
+func deleteSessions(db *gorm.DB, query *gorm.DB) ([]string, error) {
+	hashes := []string{}
+	if err := query.Model(&UserSession{}).Pluck("token_hash", &hashes).Error; err != nil {
+		return nil, err
+	}
+	if len(hashes) == 0 {
+		return hashes, nil
+	}
+	err := db.Unscoped().Delete(&UserSession{}, "token_hash in ?", hashes).Error
+	return hashes, err
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"sun-panel/lib/totp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const TWO_FACTOR_RECOVERY_CODE_COUNT = 10 // 恢复码数量
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorCodeInvalid    = errors.New("invalid two-factor authentication code")
+)
+
+// 用户二次验证(TOTP)
+type UserTwoFactor struct {
+	BaseModel
+	UserId            uint   `gorm:"uniqueIndex" json:"userId"`
+	Secret            string `gorm:"type:varchar(64)" json:"-"`
+	Enabled           int    `gorm:"type:tinyint(1)" json:"enabled"` // 是否启用 0.未启用(待绑定) 1.启用
+	LastUsedStep      int64  `json:"-"`                              // 最后一次使用的时间步，防止验证码重放
+	RecoveryCodesJson string `gorm:"type:text" json:"-"`             // 恢复码哈希列表
+}
+
+// 获取用户的二次验证信息，不存在返回 gorm.ErrRecordNotFound
+func (m *UserTwoFactor) GetByUserId(db *gorm.DB, userId uint) (UserTwoFactor, error) {
+	info := UserTwoFactor{}
+	err := db.First(&info, "user_id=?", userId).Error
+	return info, err
+}
+
+// 用户是否已启用二次验证
+func (m *UserTwoFactor) IsEnabled(db *gorm.DB, userId uint) (bool, error) {
+	info, err := m.GetByUserId(db, userId)
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return info.Enabled == INT_TURE, nil
+}
+
+// 生成新的待绑定密钥，已启用的需要先关闭
+func (m *UserTwoFactor) GenerateSecret(db *gorm.DB, userId uint) (UserTwoFactor, error) {
+	info, err := m.GetByUserId(db, userId)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return info, err
+	}
+	if info.Enabled == INT_TURE {
+		return info, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return info, err
+	}
+
+	info.UserId = userId
+	info.Secret = secret
+	info.Enabled = INT_FALSE
+	info.LastUsedStep = 0
+	info.RecoveryCodesJson = "[]"
+	if err := db.Save(&info).Error; err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// 使用验证码确认绑定并启用，返回明文恢复码(仅此一次)
+func (m *UserTwoFactor) Enable(db *gorm.DB, userId uint, code string) ([]string, error) {
+	info, err := m.GetByUserId(db, userId)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrTwoFactorNotEnabled
+	} else if err != nil {
+		return nil, err
+	}
+	if info.Enabled == INT_TURE {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(info.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	codes, hashJson, err := buildRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Model(&UserTwoFactor{}).Where("id=?", info.ID).Updates(map[string]interface{}{
+		"enabled":             INT_TURE,
+		"last_used_step":      step,
+		"recovery_codes_json": hashJson,
+	}).Error
+	return codes, err
+}
+
+// 验证二次验证码，支持TOTP验证码或一次性恢复码
+func (m *UserTwoFactor) Verify(db *gorm.DB, userId uint, code string) error {
+	info, err := m.GetByUserId(db, userId)
+	if err == gorm.ErrRecordNotFound || (err == nil && info.Enabled != INT_TURE) {
+		return ErrTwoFactorNotEnabled
+	} else if err != nil {
+		return err
+	}
+
+	// TOTP验证码
+	if step, ok := totp.Validate(info.Secret, code, time.Now()); ok {
+		if step <= info.LastUsedStep {
+			return ErrTwoFactorCodeInvalid
+		}
+		// 条件更新，避免并发请求重复使用同一个验证码
+		res := db.Model(&UserTwoFactor{}).Where("id=? AND last_used_step<?", info.ID, step).Update("last_used_step", step)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrTwoFactorCodeInvalid
+		}
+		return nil
+	}
+
+	// 恢复码，使用后作废
+	hashes := []string{}
+	json.Unmarshal([]byte(info.RecoveryCodesJson), &hashes)
+	codeHash := totp.HashRecoveryCode(code)
+	for i, v := range hashes {
+		if v != codeHash {
+			continue
+		}
+		remain := append(hashes[:i:i], hashes[i+1:]...)
+		remainJson, _ := json.Marshal(remain)
+		res := db.Model(&UserTwoFactor{}).Where("id=? AND recovery_codes_json=?", info.ID, info.RecoveryCodesJson).Update("recovery_codes_json", string(remainJson))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrTwoFactorCodeInvalid
+		}
+		return nil
+	}
+
+	return ErrTwoFactorCodeInvalid
+}
+
+// 重新生成恢复码
+func (m *UserTwoFactor) RegenerateRecoveryCodes(db *gorm.DB, userId uint) ([]string, error) {
+	codes, hashJson, err := buildRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	res := db.Model(&UserTwoFactor{}).Where("user_id=? AND enabled=?", userId, INT_TURE).Update("recovery_codes_json", hashJson)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return codes, nil
+}
+
+// 剩余可用的恢复码数量
+func (m *UserTwoFactor) RecoveryCodesCount() int {
+	hashes := []string{}
+	json.Unmarshal([]byte(m.RecoveryCodesJson), &hashes)
+	return len(hashes)
+}
+
+// 删除(关闭/重置)二次验证
+func (m *UserTwoFactor) DeleteByUserIds(db *gorm.DB, userIds []uint) error {
+	return db.Unscoped().Delete(&UserTwoFactor{}, "user_id in ?", userIds).Error
+}
+
+func buildRecoveryCodes() (codes []string, hashJson string, err error) {
+	codes, err = totp.GenerateRecoveryCodes(TWO_FACTOR_RECOVERY_CODE_COUNT)
+	if err != nil {
+		return
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, v := range codes {
+		hashes = append(hashes, totp.HashRecoveryCode(v))
+	}
+	b, err := json.Marshal(hashes)
+	hashJson = string(b)
+	return
+}
@@ -1,7 +1,9 @@
 package router
 
 import (
+	"sun-panel/api/api_v1/middleware"
 	"sun-panel/global"
+	"sun-panel/lib/cmn"
 	// "sun-panel/router/admin"
 	"sun-panel/router/openness"
 	"sun-panel/router/panel"
@@ -13,8 +15,19 @@ import (
 // 初始化总路由
 func InitRouters(addr string) error {
 	router := gin.Default()
+
+	// 可信代理，只有来自这些地址的 X-Forwarded-For 才用于获取客户端IP(登录限制等依赖真实IP)
+	// 未配置时不信任任何代理，客户端IP为连接地址，防止伪造
+	trustedProxies := cmn.SplitAndTrim(global.Config.GetValueString("base", "trusted_proxies"), ",")
+	if len(trustedProxies) == 0 {
+		trustedProxies = nil
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return err
+	}
+
 	rootRouter := router.Group("/")
-	routerGroup := rootRouter.Group("api")
+	routerGroup := rootRouter.Group("api", middleware.SetupInterceptor)
 
 	// 接口
 	system.Init(routerGroup)
@@ -7,4 +7,13 @@ func Init(routerGroup *gin.RouterGroup) {
 	InitUserConfig(routerGroup)
 	InitUsersRouter(routerGroup)
 	InitItemIconGroup(routerGroup)
+	InitLoginLimitRouter(routerGroup)
+	InitSystemSettingRouter(routerGroup)
+	InitRoleRouter(routerGroup)
+	InitTeamRouter(routerGroup)
+	InitAuditLogRouter(routerGroup)
+	InitNetworkZoneRouter(routerGroup)
+	InitSearchRouter(routerGroup)
+	InitTagRouter(routerGroup)
+	InitTagViewRouter(routerGroup)
 }
@@ -0,0 +1,19 @@
+package panel
+
+import (
+	"sun-panel/api/api_v1"
+	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitAuditLogRouter(router *gin.RouterGroup) {
+	api := api_v1.ApiGroupApp.ApiPanel.AuditLogApi
+	r := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_MANAGE_SYSTEM))
+	{
+		r.POST("panel/auditLog/getList", api.GetList)
+		r.POST("panel/auditLog/getSetting", api.GetSetting)
+		r.POST("panel/auditLog/setSetting", api.SetSetting)
+	}
+}
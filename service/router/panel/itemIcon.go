@@ -3,13 +3,14 @@ package panel
 import (
 	"sun-panel/api/api_v1"
 	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
 
 	"github.com/gin-gonic/gin"
 )
 
 func InitItemIcon(router *gin.RouterGroup) {
 	itemIcon := api_v1.ApiGroupApp.ApiPanel.ItemIcon
-	r := router.Group("", middleware.LoginInterceptor)
+	r := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_EDIT_PANEL))
 	{
 		r.POST("/panel/itemIcon/edit", itemIcon.Edit)
 		r.POST("/panel/itemIcon/deletes", itemIcon.Deletes)
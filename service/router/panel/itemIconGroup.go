@@ -3,13 +3,14 @@ package panel
 import (
 	"sun-panel/api/api_v1"
 	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
 
 	"github.com/gin-gonic/gin"
 )
 
 func InitItemIconGroup(router *gin.RouterGroup) {
 	itemIconGroup := api_v1.ApiGroupApp.ApiPanel.ItemIconGroup
-	r := router.Group("", middleware.LoginInterceptor)
+	r := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_EDIT_PANEL))
 	{
 		r.POST("/panel/itemIconGroup/edit", itemIconGroup.Edit)
 		r.POST("/panel/itemIconGroup/deletes", itemIconGroup.Deletes)
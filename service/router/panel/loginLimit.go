@@ -0,0 +1,20 @@
+package panel
+
+import (
+	"sun-panel/api/api_v1"
+	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitLoginLimitRouter(router *gin.RouterGroup) {
+	api := api_v1.ApiGroupApp.ApiPanel.LoginLimitApi
+	rAdmin := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_MANAGE_SYSTEM))
+	{
+		rAdmin.POST("panel/loginLimit/getSetting", api.GetSetting)
+		rAdmin.POST("panel/loginLimit/setSetting", api.SetSetting)
+		rAdmin.POST("panel/loginLimit/getLockedList", api.GetLockedList)
+		rAdmin.POST("panel/loginLimit/unlock", api.Unlock)
+	}
+}
@@ -0,0 +1,31 @@
+package panel
+
+import (
+	"sun-panel/api/api_v1"
+	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitNetworkZoneRouter(router *gin.RouterGroup) {
+	api := api_v1.ApiGroupApp.ApiPanel.NetworkZoneApi
+
+	// 编辑图标时需要选择区域
+	r := router.Group("", middleware.LoginInterceptor)
+	{
+		r.POST("panel/networkZone/getList", api.GetList)
+	}
+
+	rAdmin := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_MANAGE_SYSTEM))
+	{
+		rAdmin.POST("panel/networkZone/edit", api.Edit)
+		rAdmin.POST("panel/networkZone/deletes", api.Deletes)
+	}
+
+	// 公开模式
+	rPublic := router.Group("", middleware.PublicModeInterceptor)
+	{
+		rPublic.POST("panel/networkZone/getCurrent", api.GetCurrent)
+	}
+}
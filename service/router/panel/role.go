@@ -0,0 +1,20 @@
+package panel
+
+import (
+	"sun-panel/api/api_v1"
+	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitRoleRouter(router *gin.RouterGroup) {
+	api := api_v1.ApiGroupApp.ApiPanel.RoleApi
+	r := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_MANAGE_USERS))
+	{
+		r.POST("panel/role/getPermissionList", api.GetPermissionList)
+		r.POST("panel/role/getList", api.GetList)
+		r.POST("panel/role/edit", api.Edit)
+		r.POST("panel/role/deletes", api.Deletes)
+	}
+}
@@ -0,0 +1,18 @@
+package panel
+
+import (
+	"sun-panel/api/api_v1"
+	"sun-panel/api/api_v1/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitSearchRouter(router *gin.RouterGroup) {
+	api := api_v1.ApiGroupApp.ApiPanel.SearchApi
+
+	// 公开模式
+	rPublic := router.Group("", middleware.PublicModeInterceptor)
+	{
+		rPublic.POST("panel/search/query", api.Query)
+	}
+}
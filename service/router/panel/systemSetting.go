@@ -0,0 +1,20 @@
+package panel
+
+import (
+	"sun-panel/api/api_v1"
+	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitSystemSettingRouter(router *gin.RouterGroup) {
+	api := api_v1.ApiGroupApp.ApiPanel.SystemSettingApi
+	rAdmin := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_MANAGE_SYSTEM))
+	{
+		rAdmin.POST("panel/systemSetting/getEmail", api.GetEmail)
+		rAdmin.POST("panel/systemSetting/setEmail", api.SetEmail)
+		rAdmin.POST("panel/systemSetting/getApplicationSetting", api.GetApplicationSetting)
+		rAdmin.POST("panel/systemSetting/setApplicationSetting", api.SetApplicationSetting)
+	}
+}
@@ -0,0 +1,24 @@
+package panel
+
+import (
+	"sun-panel/api/api_v1"
+	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitTagRouter(router *gin.RouterGroup) {
+	api := api_v1.ApiGroupApp.ApiPanel.TagApi
+	r := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_EDIT_PANEL))
+	{
+		r.POST("panel/tag/edit", api.Edit)
+		r.POST("panel/tag/deletes", api.Deletes)
+	}
+
+	// 公开模式
+	rPublic := router.Group("", middleware.PublicModeInterceptor)
+	{
+		rPublic.POST("panel/tag/getList", api.GetList)
+	}
+}
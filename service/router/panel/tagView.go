@@ -0,0 +1,25 @@
+package panel
+
+import (
+	"sun-panel/api/api_v1"
+	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitTagViewRouter(router *gin.RouterGroup) {
+	api := api_v1.ApiGroupApp.ApiPanel.TagViewApi
+	r := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_EDIT_PANEL))
+	{
+		r.POST("panel/tagView/edit", api.Edit)
+		r.POST("panel/tagView/deletes", api.Deletes)
+	}
+
+	// 公开模式
+	rPublic := router.Group("", middleware.PublicModeInterceptor)
+	{
+		rPublic.POST("panel/tagView/getList", api.GetList)
+		rPublic.POST("panel/tagView/getItems", api.GetItems)
+	}
+}
@@ -0,0 +1,27 @@
+package panel
+
+import (
+	"sun-panel/api/api_v1"
+	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitTeamRouter(router *gin.RouterGroup) {
+	api := api_v1.ApiGroupApp.ApiPanel.TeamApi
+	r := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_MANAGE_USERS))
+	{
+		r.POST("panel/team/getList", api.GetList)
+		r.POST("panel/team/edit", api.Edit)
+		r.POST("panel/team/deletes", api.Deletes)
+		r.POST("panel/team/getMemberList", api.GetMemberList)
+		r.POST("panel/team/setMember", api.SetMember)
+		r.POST("panel/team/removeMembers", api.RemoveMembers)
+	}
+
+	rLogin := router.Group("", middleware.LoginInterceptor)
+	{
+		rLogin.POST("panel/team/getMyList", api.GetMyList)
+	}
+}
@@ -3,6 +3,7 @@ package panel
 import (
 	"sun-panel/api/api_v1"
 	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,7 +11,7 @@ import (
 func InitUsersRouter(router *gin.RouterGroup) {
 	userApi := api_v1.ApiGroupApp.ApiPanel.UsersApi
 
-	rAdmin := router.Group("", middleware.LoginInterceptor, middleware.AdminInterceptor)
+	rAdmin := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_MANAGE_USERS))
 	{
 		rAdmin.POST("panel/users/create", userApi.Create)
 		rAdmin.POST("panel/users/update", userApi.Update)
@@ -18,5 +19,15 @@ func InitUsersRouter(router *gin.RouterGroup) {
 		rAdmin.POST("panel/users/deletes", userApi.Deletes)
 		rAdmin.POST("panel/users/getPublicVisitUser", userApi.GetPublicVisitUser)
 		rAdmin.POST("panel/users/setPublicVisitUser", userApi.SetPublicVisitUser)
+		rAdmin.POST("panel/users/resetTwoFactor", userApi.ResetTwoFactor)
+		rAdmin.POST("panel/users/approve", userApi.Approve)
+		rAdmin.POST("panel/users/setStatus", userApi.SetStatus)
+		rAdmin.POST("panel/users/setExpireAt", userApi.SetExpireAt)
+		rAdmin.POST("panel/users/setMustChangePassword", userApi.SetMustChangePassword)
+		rAdmin.POST("panel/users/impersonate", userApi.Impersonate)
+
+		sessionApi := api_v1.ApiGroupApp.ApiSystem.SessionApi
+		rAdmin.POST("panel/users/getSessionList", sessionApi.AdminGetList)
+		rAdmin.POST("panel/users/revokeSessions", sessionApi.AdminRevoke)
 	}
 }
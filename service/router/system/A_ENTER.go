@@ -10,4 +10,8 @@ func Init(routerGroup *gin.RouterGroup) {
 	InitNoticeRouter(routerGroup)
 	InitModuleConfigRouter(routerGroup)
 	InitMonitorRouter(routerGroup)
+	InitOidcRouter(routerGroup)
+	InitRegisterRouter(routerGroup)
+	InitCaptchaRouter(routerGroup)
+	InitSetupRouter(routerGroup)
 }
@@ -0,0 +1,15 @@
+package system
+
+import (
+	"sun-panel/api/api_v1"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitCaptchaRouter(router *gin.RouterGroup) {
+	captchaApi := api_v1.ApiGroupApp.ApiSystem.CaptchaApi
+
+	router.GET("/captcha/getImage", captchaApi.GetImage)
+	router.GET("/captcha/getImage/:width/:height", captchaApi.GetImage)
+	router.GET("/captcha/getImageByCaptchaId/:captchaId/:width/:height", captchaApi.GetImageByCaptchaId)
+}
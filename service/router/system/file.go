@@ -3,6 +3,7 @@ package system
 import (
 	"sun-panel/api/api_v1"
 	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,7 +12,7 @@ func InitFileRouter(router *gin.RouterGroup) {
 	FileApi := api_v1.ApiGroupApp.ApiSystem.FileApi
 
 	// 验证项目的权限(有访问密码的需要验证访问token)
-	private := router.Group("", middleware.LoginInterceptor)
+	private := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_UPLOAD_FILES))
 	{
 		private.POST("/file/uploadImg", FileApi.UploadImg)
 		private.POST("/file/uploadFiles", FileApi.UploadFiles)
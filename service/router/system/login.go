@@ -11,6 +11,15 @@ func InitLogin(router *gin.RouterGroup) {
 	loginApi := api_v1.ApiGroupApp.ApiSystem.LoginApi
 
 	router.POST("/login", loginApi.Login)
+	router.POST("/login/twoFactor/verify", loginApi.TwoFactorVerify)
+	router.POST("/login/twoFactor/generate", loginApi.TwoFactorGenerate)
+	router.POST("/login/twoFactor/enable", loginApi.TwoFactorEnable)
+	router.POST("/login/passkey/begin", loginApi.PasskeyBegin)
+	router.POST("/login/passkey/finish", loginApi.PasskeyFinish)
+	router.POST("/login/sendResetPasswordVCode", loginApi.SendResetPasswordVCode)
+	router.POST("/login/resetPasswordByVCode", loginApi.ResetPasswordByVCode)
+	router.POST("/login/sendMagicLink", loginApi.SendMagicLink)
+	router.POST("/login/magicLink", loginApi.MagicLinkLogin)
 	router.POST("/logout", middleware.LoginInterceptor, loginApi.Logout)
 
 }
@@ -2,6 +2,8 @@ package system
 
 import (
 	"sun-panel/api/api_v1"
+	"sun-panel/api/api_v1/middleware"
+	"sun-panel/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,4 +12,11 @@ func InitNoticeRouter(router *gin.RouterGroup) {
 	api := api_v1.ApiGroupApp.ApiSystem.NoticeApi
 
 	router.POST("/notice/getListByDisplayType", api.GetListByDisplayType)
+
+	r := router.Group("", middleware.LoginInterceptor, middleware.PermissionInterceptor(models.PERMISSION_MANAGE_NOTICES))
+	{
+		r.POST("/notice/getList", api.GetList)
+		r.POST("/notice/edit", api.Edit)
+		r.POST("/notice/deletes", api.Deletes)
+	}
 }
@@ -0,0 +1,17 @@
+package system
+
+import (
+	"sun-panel/api/api_v1"
+	"sun-panel/api/api_v1/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitOidcRouter(router *gin.RouterGroup) {
+	api := api_v1.ApiGroupApp.ApiSystem.OidcApi
+
+	router.GET("/oidc/login", api.Login)
+	router.GET("/oidc/callback", api.Callback)
+	router.POST("/oidc/exchange", api.Exchange)
+	router.POST("/oidc/bindStart", middleware.LoginInterceptor, api.BindStart)
+}
@@ -0,0 +1,14 @@
+package system
+
+import (
+	"sun-panel/api/api_v1"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitRegisterRouter(router *gin.RouterGroup) {
+	registerApi := api_v1.ApiGroupApp.ApiSystem.RegisterApi
+
+	router.POST("/register/sendRegisterVcode", registerApi.SendRegisterVcode)
+	router.POST("/register/commit", registerApi.Commit)
+}
@@ -0,0 +1,15 @@
+package system
+
+import (
+	"sun-panel/api/api_v1"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 首次运行设置，完成后提交接口返回错误
+func InitSetupRouter(router *gin.RouterGroup) {
+	setupApi := api_v1.ApiGroupApp.ApiSystem.SetupApi
+
+	router.POST("/setup/getStatus", setupApi.GetStatus)
+	router.POST("/setup/commit", setupApi.Commit)
+}
@@ -15,6 +15,30 @@ func InitUserRouter(router *gin.RouterGroup) {
 	r.POST("/user/updateInfo", api.UpdateInfo)
 	r.POST("/user/getReferralCode", api.GetReferralCode)
 
+	twoFactorApi := api_v1.ApiGroupApp.ApiSystem.TwoFactorApi
+	r.POST("/user/twoFactor/getStatus", twoFactorApi.GetStatus)
+	r.POST("/user/twoFactor/generate", twoFactorApi.Generate)
+	r.POST("/user/twoFactor/enable", twoFactorApi.Enable)
+	r.POST("/user/twoFactor/disable", twoFactorApi.Disable)
+	r.POST("/user/twoFactor/regenerateRecoveryCodes", twoFactorApi.RegenerateRecoveryCodes)
+
+	apiTokenApi := api_v1.ApiGroupApp.ApiSystem.ApiTokenApi
+	r.POST("/user/apiToken/getList", apiTokenApi.GetList)
+	r.POST("/user/apiToken/create", apiTokenApi.Create)
+	r.POST("/user/apiToken/deletes", apiTokenApi.Deletes)
+
+	passkeyApi := api_v1.ApiGroupApp.ApiSystem.PasskeyApi
+	r.POST("/user/passkey/getList", passkeyApi.GetList)
+	r.POST("/user/passkey/registerBegin", passkeyApi.RegisterBegin)
+	r.POST("/user/passkey/registerFinish", passkeyApi.RegisterFinish)
+	r.POST("/user/passkey/rename", passkeyApi.Rename)
+	r.POST("/user/passkey/deletes", passkeyApi.Deletes)
+
+	sessionApi := api_v1.ApiGroupApp.ApiSystem.SessionApi
+	r.POST("/user/session/getList", sessionApi.GetList)
+	r.POST("/user/session/revoke", sessionApi.Revoke)
+	r.POST("/user/session/revokeOthers", sessionApi.RevokeOthers)
+
 	// 公开模式
 	rPublic := router.Group("", middleware.PublicModeInterceptor)
 	{
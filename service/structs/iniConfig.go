@@ -7,3 +7,56 @@ type IniConfigRedis struct {
 	Prefix   string `ini:"prefix"`   // key前缀
 	Db       int    `ini:"db"`       // 数据库，默认0
 }
+
+// 配置文件OpenID Connect单点登录
+type IniConfigOidc struct {
+	Enable        bool   `ini:"enable"`         // 是否启用
+	ButtonName    string `ini:"button_name"`    // 登录页按钮名称
+	Issuer        string `ini:"issuer"`         // 签发者地址
+	ClientId      string `ini:"client_id"`      // 客户端ID
+	ClientSecret  string `ini:"client_secret"`  // 客户端密钥
+	RedirectUrl   string `ini:"redirect_url"`   // 回调地址，为空时根据请求自动生成：<站点地址>/api/oidc/callback
+	Scopes        string `ini:"scopes"`         // 请求的scope，逗号分隔
+	UsernameClaim string `ini:"username_claim"` // 用于匹配用户名的claim，默认：preferred_username
+	AutoCreate    bool   `ini:"auto_create"`    // 用户不存在时自动创建
+	GroupsClaim   string `ini:"groups_claim"`   // 用户组claim，为空不同步角色
+	AdminGroups   string `ini:"admin_groups"`   // 管理员用户组，逗号分隔
+	UserGroups    string `ini:"user_groups"`    // 允许登录的用户组，逗号分隔，为空时不限制
+}
+
+// 配置文件LDAP认证
+type IniConfigLdap struct {
+	Enable             bool   `ini:"enable"`               // 是否启用
+	Url                string `ini:"url"`                  // 服务地址：ldap://host:389 或 ldaps://host:636
+	StartTls           bool   `ini:"start_tls"`            // 使用StartTLS
+	InsecureSkipVerify bool   `ini:"insecure_skip_verify"` // 跳过证书验证
+	BindDn             string `ini:"bind_dn"`              // 查询用户使用的账号，为空时匿名绑定
+	BindPassword       string `ini:"bind_password"`        // 查询用户使用的密码
+	BaseDn             string `ini:"base_dn"`              // 用户查询的根节点
+	UserFilter         string `ini:"user_filter"`          // 用户查询条件，{username}会被替换为登录账号
+	UsernameAttribute  string `ini:"username_attribute"`   // 用户名属性
+	NameAttribute      string `ini:"name_attribute"`       // 昵称属性
+	MailAttribute      string `ini:"mail_attribute"`       // 邮箱属性
+	MemberOfAttribute  string `ini:"member_of_attribute"`  // 用户所属组的属性，未配置group_filter时使用
+	GroupBaseDn        string `ini:"group_base_dn"`        // 用户组查询的根节点，为空时使用base_dn
+	GroupFilter        string `ini:"group_filter"`         // 用户组查询条件，{dn}为用户DN，{username}为登录账号
+	GroupNameAttribute string `ini:"group_name_attribute"` // 用户组名称属性
+	AdminGroups        string `ini:"admin_groups"`         // 管理员用户组(名称或DN)，逗号分隔
+	UserGroups         string `ini:"user_groups"`          // 允许登录的用户组，逗号分隔，为空时不限制
+	AutoCreate         bool   `ini:"auto_create"`          // 首次登录自动创建用户
+	LocalFallback      bool   `ini:"local_fallback"`       // LDAP验证失败时尝试本地账号密码
+	LinkExisting       bool   `ini:"link_existing"`        // 按用户名关联未关联LDAP的已有账号(升级前由LDAP创建的账号)
+}
+
+// 配置文件反向代理认证(Authelia、oauth2-proxy、Traefik forward-auth等)
+type IniConfigProxyAuth struct {
+	Enable         bool   `ini:"enable"`          // 是否启用
+	TrustedProxies string `ini:"trusted_proxies"` // 可信代理的IP或CIDR，逗号分隔，只接受来自这些地址的请求头
+	UserHeader     string `ini:"user_header"`     // 用户名请求头，默认：Remote-User
+	NameHeader     string `ini:"name_header"`     // 昵称请求头
+	MailHeader     string `ini:"mail_header"`     // 邮箱请求头
+	GroupsHeader   string `ini:"groups_header"`   // 用户组请求头(逗号分隔)，为空不同步角色
+	AdminGroups    string `ini:"admin_groups"`    // 管理员用户组，逗号分隔
+	UserGroups     string `ini:"user_groups"`     // 允许登录的用户组，逗号分隔，为空时不限制
+	AutoCreate     bool   `ini:"auto_create"`     // 用户不存在时自动创建
+}